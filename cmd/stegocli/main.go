@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/robustness"
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/steganalysis"
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/stego"
+)
+
+const usage = `Usage: stegocli <command> [arguments]
+
+Commands:
+  analyze <file.mp3>    Report ID3 tags, frames, bitrate, duration and structure
+  headercheck <file.mp3>...
+                        Audit frame-header flags for header-level steganography
+  robustness [flags] <carrier.mp3>
+                        Embed with every method, apply common manipulations
+                        and report how often the secret is recovered
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "analyze":
+		err = runAnalyze(os.Args[2:])
+	case "headercheck":
+		err = runHeaderCheck(os.Args[2:])
+	case "robustness":
+		err = runRobustness(os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func printJSON(value any) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+func runAnalyze(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: stegocli analyze <file.mp3>")
+	}
+
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+
+	analysis, err := stego.AnalyzeMP3(data)
+	if err != nil {
+		return err
+	}
+
+	return printJSON(analysis)
+}
+
+type headerCheckResult struct {
+	File  string `json:"file"`
+	Error string `json:"error,omitempty"`
+	*steganalysis.HeaderAnomalyReport
+}
+
+func runHeaderCheck(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: stegocli headercheck <file.mp3>...")
+	}
+
+	results := make([]headerCheckResult, 0, len(args))
+	for _, path := range args {
+		result := headerCheckResult{File: path}
+
+		data, err := os.ReadFile(path)
+		if err == nil {
+			result.HeaderAnomalyReport, err = steganalysis.DetectHeaderAnomalies(data)
+		}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+
+	return printJSON(results)
+}
+
+func runRobustness(args []string) error {
+	flags := flag.NewFlagSet("robustness", flag.ContinueOnError)
+	key := flags.String("key", robustness.DefaultKey, "embedding key")
+	secretPath := flags.String("secret", "", "secret file to embed (default: random bytes)")
+	secretSize := flags.Int("secret-size", robustness.DefaultSecretSize, "size of the random secret in bytes")
+	trials := flags.Int("trials", robustness.DefaultTrials, "trials per random transformation")
+	trim := flags.Int("trim", robustness.DefaultTrimFrames, "frames removed by the trim transformations")
+	bers := flags.String("ber", formatBERs(robustness.DefaultBERs), "comma-separated bit error rates")
+	seed := flags.Int64("seed", 1, "seed for the random transformations")
+	stealth := flags.Bool("stealth", false, "embed in stealth mode")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: stegocli robustness [flags] <carrier.mp3>")
+	}
+
+	carrier, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	rates, err := parseBERs(*bers)
+	if err != nil {
+		return err
+	}
+
+	cfg := robustness.Config{
+		Options:    stego.EmbedOptions{Key: *key, Stealth: *stealth},
+		SecretSize: *secretSize,
+		Trials:     *trials,
+		Seed:       *seed,
+		Transforms: robustness.DefaultTransforms(carrier, *trim, rates),
+	}
+	if *secretPath != "" {
+		cfg.Secret, err = os.ReadFile(*secretPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	report, err := robustness.Run(carrier, cfg)
+	if err != nil {
+		return err
+	}
+
+	return printJSON(report)
+}
+
+func parseBERs(value string) ([]float64, error) {
+	var rates []float64
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		rate, err := strconv.ParseFloat(field, 64)
+		if err != nil || rate <= 0 || rate > 0.5 {
+			return nil, fmt.Errorf("invalid bit error rate %q: use a value in (0, 0.5]", field)
+		}
+		rates = append(rates, rate)
+	}
+	return rates, nil
+}
+
+func formatBERs(rates []float64) string {
+	fields := make([]string, len(rates))
+	for i, rate := range rates {
+		fields[i] = strconv.FormatFloat(rate, 'g', -1, 64)
+	}
+	return strings.Join(fields, ",")
+}
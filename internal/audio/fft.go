@@ -0,0 +1,82 @@
+package audio
+
+import (
+	"math"
+	"math/bits"
+)
+
+// FFT computes an in-place radix-2 transform. len(x) must be a power of two.
+func FFT(x []complex128) {
+	n := len(x)
+	if n < 2 {
+		return
+	}
+	shift := 64 - uint(bits.TrailingZeros(uint(n)))
+	for i := 0; i < n; i++ {
+		j := int(bits.Reverse64(uint64(i)) >> shift)
+		if j > i {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+
+	for size := 2; size <= n; size <<= 1 {
+		step := -2 * math.Pi / float64(size)
+		for start := 0; start < n; start += size {
+			for k := 0; k < size/2; k++ {
+				w := complex(math.Cos(step*float64(k)), math.Sin(step*float64(k)))
+				a, b := x[start+k], x[start+k+size/2]*w
+				x[start+k], x[start+k+size/2] = a+b, a-b
+			}
+		}
+	}
+}
+
+func HannWindow(n int) []float64 {
+	window := make([]float64, n)
+	for i := range window {
+		window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(n))
+	}
+	return window
+}
+
+// PowerSpectrum returns |X(k)|² for k = 0..n/2 of a windowed frame. frame
+// must have the window's length, which must be a power of two.
+func PowerSpectrum(frame, window []float64) []float64 {
+	buf := make([]complex128, len(frame))
+	for i, v := range frame {
+		buf[i] = complex(v*window[i], 0)
+	}
+	FFT(buf)
+
+	power := make([]float64, len(frame)/2+1)
+	for k := range power {
+		re, im := real(buf[k]), imag(buf[k])
+		power[k] = re*re + im*im
+	}
+	return power
+}
+
+// RealCepstrum returns the real cepstrum of a windowed frame, the inverse
+// transform of its log magnitude spectrum. An echo delayed by d samples shows
+// up as a peak at quefrency d. frame must have the window's length, which
+// must be a power of two.
+func RealCepstrum(frame, window []float64) []float64 {
+	buf := make([]complex128, len(frame))
+	for i, v := range frame {
+		buf[i] = complex(v*window[i], 0)
+	}
+	FFT(buf)
+
+	// The log magnitude is real and even, so the forward transform equals
+	// the inverse up to the 1/n scale.
+	for k, v := range buf {
+		buf[k] = complex(math.Log(math.Hypot(real(v), imag(v))+1e-12), 0)
+	}
+	FFT(buf)
+
+	cepstrum := make([]float64, len(frame))
+	for i, v := range buf {
+		cepstrum[i] = real(v) / float64(len(frame))
+	}
+	return cepstrum
+}
@@ -0,0 +1,781 @@
+package audio
+
+import (
+	"errors"
+	"math"
+)
+
+var ErrNotMP3 = errors.New("no decodable MPEG Layer III frames found")
+
+const (
+	modeJointStereo = 1
+	modeMono        = 3
+
+	maxReservoir = 4096
+)
+
+type mp3Header struct {
+	lsf         bool
+	protected   bool
+	bandIndex   int
+	sampleRate  int
+	mode        int
+	modeExt     int
+	channels    int
+	frameSize   int
+	granules    int
+	sideInfoLen int
+}
+
+// MP3Stream is the result of decoding an MP3 file. Frame offsets are kept so
+// a modified copy of the same file can be decoded frame-for-frame with
+// DecodeMP3Aligned.
+type MP3Stream struct {
+	PCM          *PCM  `json:"pcm"`
+	FrameOffsets []int `json:"-"`
+	FrameSamples int   `json:"frame_samples"`
+	LostFrames   int   `json:"lost_frames"`
+
+	headers []mp3Header
+}
+
+func parseMP3Header(data []byte) (mp3Header, bool) {
+	f, ok := ParseMP3FrameHeader(data)
+	if !ok {
+		return mp3Header{}, false
+	}
+	return newMP3Header(f), true
+}
+
+func newMP3Header(f *MP3FrameHeader) mp3Header {
+	h := mp3Header{
+		lsf:        f.Version != mpegVersion1,
+		protected:  f.Protection == 0,
+		bandIndex:  f.bandIndex(),
+		sampleRate: f.SampleRateHz(),
+		mode:       int(f.Channel),
+		modeExt:    int(f.ModeExt),
+		channels:   2,
+		frameSize:  f.Size,
+	}
+	if h.mode == modeMono {
+		h.channels = 1
+	}
+
+	if h.lsf {
+		h.granules = 1
+		h.sideInfoLen = 9
+		if h.channels == 2 {
+			h.sideInfoLen = 17
+		}
+	} else {
+		h.granules = 2
+		h.sideInfoLen = 17
+		if h.channels == 2 {
+			h.sideInfoLen = 32
+		}
+	}
+
+	return h
+}
+
+func (h mp3Header) dataStart() int {
+	if h.protected {
+		return 6
+	}
+	return 4
+}
+
+// findMP3Frames locates the frames the decoder can play in sequence: those
+// FindMP3Frames finds that share the first frame's version and sample rate.
+func findMP3Frames(data []byte) ([]int, []mp3Header) {
+	start := ID3v2Size(data)
+	frames, positions := FindMP3Frames(data[start:])
+
+	var offsets []int
+	var headers []mp3Header
+	for i, f := range frames {
+		h := newMP3Header(f)
+		if len(headers) > 0 && (h.lsf != headers[0].lsf || h.sampleRate != headers[0].sampleRate) {
+			continue
+		}
+		offsets = append(offsets, start+positions[i])
+		headers = append(headers, h)
+	}
+
+	return offsets, headers
+}
+
+// DecodeMP3 decodes every MPEG-1, MPEG-2 and MPEG-2.5 Layer III frame in data.
+func DecodeMP3(data []byte) (*MP3Stream, error) {
+	offsets, headers := findMP3Frames(data)
+	if len(offsets) == 0 {
+		return nil, ErrNotMP3
+	}
+
+	d := newMP3Decoder(headers[0])
+	for i, off := range offsets {
+		d.decodeFrame(headers[i], data[off:off+headers[i].frameSize], false)
+	}
+	return d.stream(offsets, headers), nil
+}
+
+// DecodeMP3Aligned decodes data using the frame layout of reference, which
+// must come from the cover file data was derived from. Frames whose header no
+// longer parses the same way are counted as lost and decoded as silence,
+// which is what a player that skips them would produce, while keeping the
+// output aligned sample-for-sample with the reference.
+func DecodeMP3Aligned(data []byte, reference *MP3Stream) (*MP3Stream, error) {
+	if reference == nil || len(reference.headers) == 0 {
+		return nil, ErrNotMP3
+	}
+
+	d := newMP3Decoder(reference.headers[0])
+	for i, off := range reference.FrameOffsets {
+		ref := reference.headers[i]
+		if off+ref.frameSize > len(data) {
+			d.decodeFrame(ref, nil, true)
+			continue
+		}
+
+		frame := data[off : off+ref.frameSize]
+		h, ok := parseMP3Header(frame)
+		lost := !ok || h.frameSize != ref.frameSize || h.channels != ref.channels ||
+			h.lsf != ref.lsf || h.protected != ref.protected
+		if lost {
+			h = ref
+		}
+		d.decodeFrame(h, frame, lost)
+	}
+	return d.stream(reference.FrameOffsets, reference.headers), nil
+}
+
+type granuleChannel struct {
+	part23Length     int
+	bigValues        int
+	globalGain       int
+	scalefacCompress int
+	windowSwitching  bool
+	blockType        int
+	mixed            bool
+	tableSelect      [3]int
+	subblockGain     [3]int
+	region0Count     int
+	region1Count     int
+	preflag          bool
+	scalefacScale    int
+	count1Table      int
+}
+
+type sideInfo struct {
+	mainDataBegin int
+	scfsi         [2][4]bool
+	gr            [2][2]granuleChannel
+}
+
+type scalefactors struct {
+	long     [22]int
+	short    [13][3]int
+	longMax  [22]int
+	shortMax [13][3]int
+}
+
+type band struct {
+	start, width int
+	sfb, window  int
+	freq         int
+	short        bool
+}
+
+type mp3Decoder struct {
+	channels   int
+	sampleRate int
+	reservoir  []byte
+	overlap    [2][32][18]float64
+	synth      [2]synthesisState
+	samples    []float64
+	lost       int
+	frameLen   int
+}
+
+func newMP3Decoder(first mp3Header) *mp3Decoder {
+	return &mp3Decoder{
+		channels:   first.channels,
+		sampleRate: first.sampleRate,
+		frameLen:   576 * first.granules,
+	}
+}
+
+func (d *mp3Decoder) stream(offsets []int, headers []mp3Header) *MP3Stream {
+	return &MP3Stream{
+		PCM:          &PCM{Channels: d.channels, SampleRate: d.sampleRate, Samples: d.samples},
+		FrameOffsets: offsets,
+		FrameSamples: d.frameLen,
+		LostFrames:   d.lost,
+		headers:      headers,
+	}
+}
+
+func readSideInfo(r *bitReader, h mp3Header) *sideInfo {
+	si := &sideInfo{}
+	if h.lsf {
+		si.mainDataBegin = r.bits(8)
+		r.bits(h.channels)
+	} else {
+		si.mainDataBegin = r.bits(9)
+		if h.channels == 1 {
+			r.bits(5)
+		} else {
+			r.bits(3)
+		}
+		for ch := 0; ch < h.channels; ch++ {
+			for band := 0; band < 4; band++ {
+				si.scfsi[ch][band] = r.bit() == 1
+			}
+		}
+	}
+
+	for gr := 0; gr < h.granules; gr++ {
+		for ch := 0; ch < h.channels; ch++ {
+			gc := &si.gr[gr][ch]
+			gc.part23Length = r.bits(12)
+			gc.bigValues = r.bits(9)
+			if gc.bigValues > 288 {
+				gc.bigValues = 288
+			}
+			gc.globalGain = r.bits(8)
+			if h.lsf {
+				gc.scalefacCompress = r.bits(9)
+			} else {
+				gc.scalefacCompress = r.bits(4)
+			}
+			gc.windowSwitching = r.bit() == 1
+			if gc.windowSwitching {
+				gc.blockType = r.bits(2)
+				gc.mixed = r.bit() == 1
+				gc.tableSelect[0] = r.bits(5)
+				gc.tableSelect[1] = r.bits(5)
+				for w := 0; w < 3; w++ {
+					gc.subblockGain[w] = r.bits(3)
+				}
+				if gc.blockType == 0 {
+					gc.windowSwitching = false
+				}
+			} else {
+				for i := 0; i < 3; i++ {
+					gc.tableSelect[i] = r.bits(5)
+				}
+				gc.region0Count = r.bits(4)
+				gc.region1Count = r.bits(3)
+			}
+			if !h.lsf {
+				gc.preflag = r.bit() == 1
+			}
+			gc.scalefacScale = r.bit()
+			gc.count1Table = r.bit()
+		}
+	}
+
+	return si
+}
+
+func (gc *granuleChannel) shortBlocks() bool {
+	return gc.windowSwitching && gc.blockType == 2
+}
+
+func granuleBands(gc *granuleChannel, sfb *scalefactorBands) []band {
+	var bands []band
+	addShort := func(from int) {
+		for s := from; s < 13; s++ {
+			width := sfb.short[s+1] - sfb.short[s]
+			for w := 0; w < 3; w++ {
+				bands = append(bands, band{
+					start: 3*sfb.short[s] + w*width, width: width,
+					sfb: s, window: w, freq: sfb.short[s], short: true,
+				})
+			}
+		}
+	}
+
+	switch {
+	case !gc.shortBlocks():
+		for s := 0; s < 22; s++ {
+			bands = append(bands, band{start: sfb.long[s], width: sfb.long[s+1] - sfb.long[s], sfb: s})
+		}
+	case gc.mixed:
+		for s := 0; sfb.long[s+1] <= 36; s++ {
+			bands = append(bands, band{start: sfb.long[s], width: sfb.long[s+1] - sfb.long[s], sfb: s})
+		}
+		addShort(3)
+	default:
+		addShort(0)
+	}
+	return bands
+}
+
+func readScalefactorsMPEG1(r *bitReader, gc *granuleChannel, scfsi [4]bool, gr int, prev, sf *scalefactors) {
+	slen1, slen2 := mpeg1Slen[gc.scalefacCompress][0], mpeg1Slen[gc.scalefacCompress][1]
+	if gc.shortBlocks() {
+		first := 0
+		if gc.mixed {
+			for s := 0; s < 8; s++ {
+				sf.long[s] = r.bits(slen1)
+			}
+			first = 3
+		}
+		for s := first; s < 12; s++ {
+			n := slen1
+			if s >= 6 {
+				n = slen2
+			}
+			for w := 0; w < 3; w++ {
+				sf.short[s][w] = r.bits(n)
+			}
+		}
+		return
+	}
+
+	bounds := [5]int{0, 6, 11, 16, 21}
+	for group := 0; group < 4; group++ {
+		n := slen1
+		if group >= 2 {
+			n = slen2
+		}
+		for s := bounds[group]; s < bounds[group+1]; s++ {
+			if gr == 1 && scfsi[group] {
+				sf.long[s] = prev.long[s]
+			} else {
+				sf.long[s] = r.bits(n)
+			}
+		}
+	}
+}
+
+func readScalefactorsLSF(r *bitReader, gc *granuleChannel, intensityRight bool, sf *scalefactors) {
+	var slen [4]int
+	var block int
+	sfc := gc.scalefacCompress
+	if intensityRight {
+		sfc >>= 1
+		switch {
+		case sfc < 180:
+			slen = [4]int{sfc / 36, (sfc % 36) / 6, sfc % 6, 0}
+			block = 3
+		case sfc < 244:
+			sfc -= 180
+			slen = [4]int{(sfc % 64) >> 4, (sfc % 16) >> 2, sfc % 4, 0}
+			block = 4
+		default:
+			sfc -= 244
+			slen = [4]int{sfc / 3, sfc % 3, 0, 0}
+			block = 5
+		}
+	} else {
+		switch {
+		case sfc < 400:
+			slen = [4]int{(sfc >> 4) / 5, (sfc >> 4) % 5, (sfc & 15) >> 2, sfc & 3}
+		case sfc < 500:
+			sfc -= 400
+			slen = [4]int{(sfc >> 2) / 5, (sfc >> 2) % 5, sfc & 3, 0}
+			block = 1
+		default:
+			sfc -= 500
+			slen = [4]int{sfc / 3, sfc % 3, 0, 0}
+			block = 2
+			gc.preflag = true
+		}
+	}
+
+	kind := 0
+	if gc.shortBlocks() {
+		kind = 1
+		if gc.mixed {
+			kind = 2
+		}
+	}
+
+	k := 0
+	for i, count := range lsfScalefactorCounts[block][kind] {
+		for j := 0; j < count; j++ {
+			value, max := r.bits(slen[i]), 1<<uint(slen[i])-1
+			switch {
+			case kind == 0:
+				sf.long[k], sf.longMax[k] = value, max
+			case kind == 1:
+				sf.short[k/3][k%3], sf.shortMax[k/3][k%3] = value, max
+			case k < 6:
+				sf.long[k], sf.longMax[k] = value, max
+			default:
+				s, w := (k-6)/3+3, (k-6)%3
+				sf.short[s][w], sf.shortMax[s][w] = value, max
+			}
+			k++
+		}
+	}
+}
+
+func readSpectrum(r *bitReader, gc *granuleChannel, sfb *scalefactorBands, end int, is *[576]int) {
+	var region1, region2 int
+	if gc.windowSwitching {
+		region1 = 36
+		if gc.shortBlocks() && !gc.mixed {
+			region1 = 3 * sfb.short[3]
+		}
+		region2 = 576
+	} else {
+		region1 = sfb.long[gc.region0Count+1]
+		region2 = sfb.long[min(gc.region0Count+gc.region1Count+2, 22)]
+	}
+
+	bigEnd := gc.bigValues * 2
+	i := 0
+	for ; i < bigEnd; i += 2 {
+		table := gc.tableSelect[0]
+		if i >= region2 {
+			table = gc.tableSelect[2]
+		} else if i >= region1 {
+			table = gc.tableSelect[1]
+		}
+		info := bigValueTables[table]
+		if info.spec == 0 {
+			is[i], is[i+1] = 0, 0
+			continue
+		}
+
+		value := bigValueTrees[info.spec].decode(r)
+		x, y := value>>4, value&15
+		if info.linbits > 0 && x == 15 {
+			x += r.bits(int(info.linbits))
+		}
+		if x != 0 && r.bit() == 1 {
+			x = -x
+		}
+		if info.linbits > 0 && y == 15 {
+			y += r.bits(int(info.linbits))
+		}
+		if y != 0 && r.bit() == 1 {
+			y = -y
+		}
+		is[i], is[i+1] = x, y
+	}
+
+	for i+4 <= 576 && r.pos < end {
+		var value int
+		if gc.count1Table == 1 {
+			value = 15 - r.bits(4)
+		} else {
+			value = count1TreeA.decode(r)
+		}
+
+		quad := [4]int{value >> 3 & 1, value >> 2 & 1, value >> 1 & 1, value & 1}
+		for j := range quad {
+			if quad[j] != 0 && r.bit() == 1 {
+				quad[j] = -1
+			}
+		}
+		if r.pos > end {
+			break
+		}
+		copy(is[i:i+4], quad[:])
+		i += 4
+	}
+
+	for ; i < 576; i++ {
+		is[i] = 0
+	}
+}
+
+var pow43Table = func() []float64 {
+	table := make([]float64, 8207)
+	for i := range table {
+		table[i] = math.Pow(float64(i), 4.0/3.0)
+	}
+	return table
+}()
+
+func requantize(is *[576]int, gc *granuleChannel, sf *scalefactors, bands []band, xr *[576]float64) {
+	multiplier := 0.5 * float64(1+gc.scalefacScale)
+	for _, b := range bands {
+		var exponent float64
+		if b.short {
+			exponent = 0.25*float64(gc.globalGain-210-8*gc.subblockGain[b.window]) -
+				multiplier*float64(scalefactorAt(sf, b))
+		} else {
+			scale := scalefactorAt(sf, b)
+			if gc.preflag {
+				scale += mp3Pretab[b.sfb]
+			}
+			exponent = 0.25*float64(gc.globalGain-210) - multiplier*float64(scale)
+		}
+		gain := math.Exp2(exponent)
+
+		for i := b.start; i < b.start+b.width; i++ {
+			v := is[i]
+			switch {
+			case v == 0:
+				xr[i] = 0
+			case v > 0:
+				xr[i] = pow43Table[min(v, len(pow43Table)-1)] * gain
+			default:
+				xr[i] = -pow43Table[min(-v, len(pow43Table)-1)] * gain
+			}
+		}
+	}
+}
+
+func scalefactorAt(sf *scalefactors, b band) int {
+	if b.short {
+		if b.sfb >= 12 {
+			return 0
+		}
+		return sf.short[b.sfb][b.window]
+	}
+	if b.sfb >= 21 {
+		return 0
+	}
+	return sf.long[b.sfb]
+}
+
+// intensityPosition returns the right channel's intensity position for a band
+// and whether it is legal. The top band has no scalefactor of its own and
+// reuses the one below it.
+func intensityPosition(sf *scalefactors, b band, lsf bool) (int, bool) {
+	var pos, max int
+	if b.short {
+		s := min(b.sfb, 11)
+		pos, max = sf.short[s][b.window], sf.shortMax[s][b.window]
+	} else {
+		s := min(b.sfb, 20)
+		pos, max = sf.long[s], sf.longMax[s]
+	}
+	if !lsf {
+		max = 7
+	}
+	return pos, pos != max
+}
+
+func intensityRatios(pos int, lsf bool, scalefacCompress int) (float64, float64) {
+	if !lsf {
+		if pos == 6 {
+			return 1, 0
+		}
+		ratio := math.Tan(float64(pos) * math.Pi / 12)
+		return ratio / (1 + ratio), 1 / (1 + ratio)
+	}
+
+	base := math.Exp2(-0.25)
+	if scalefacCompress&1 == 1 {
+		base = math.Exp2(-0.5)
+	}
+	switch {
+	case pos == 0:
+		return 1, 1
+	case pos%2 == 1:
+		return math.Pow(base, float64((pos+1)/2)), 1
+	default:
+		return 1, math.Pow(base, float64(pos/2))
+	}
+}
+
+func processStereo(h mp3Header, gcRight *granuleChannel, sfRight *scalefactors, bands []band, xr *[2][576]float64) {
+	ms := h.modeExt&2 != 0
+	intensity := h.modeExt&1 != 0
+
+	applyMS := func(start, end int) {
+		for i := start; i < end; i++ {
+			m, s := xr[0][i], xr[1][i]
+			xr[0][i] = (m + s) * math.Sqrt2 / 2
+			xr[1][i] = (m - s) * math.Sqrt2 / 2
+		}
+	}
+
+	if !intensity {
+		if ms {
+			applyMS(0, 576)
+		}
+		return
+	}
+
+	lastLong := -1
+	lastShort := [3]int{-1, -1, -1}
+	for _, b := range bands {
+		for i := b.start; i < b.start+b.width; i++ {
+			if xr[1][i] != 0 {
+				if b.short {
+					lastShort[b.window] = b.sfb
+				} else {
+					lastLong = b.sfb
+				}
+				break
+			}
+		}
+	}
+	shortNonzero := lastShort != [3]int{-1, -1, -1}
+
+	for _, b := range bands {
+		inIntensity := b.sfb > lastLong && !shortNonzero
+		if b.short {
+			inIntensity = b.sfb > lastShort[b.window]
+		}
+
+		pos, legal := intensityPosition(sfRight, b, h.lsf)
+		if !inIntensity || !legal {
+			if ms {
+				applyMS(b.start, b.start+b.width)
+			}
+			continue
+		}
+
+		kl, kr := intensityRatios(pos, h.lsf, gcRight.scalefacCompress)
+		for i := b.start; i < b.start+b.width; i++ {
+			v := xr[0][i]
+			xr[0][i] = v * kl
+			xr[1][i] = v * kr
+		}
+	}
+}
+
+// decodeFrame decodes one frame and appends its samples. Lost frames and
+// frames whose bit reservoir is unavailable produce silence but still feed
+// the reservoir and flush the filterbank state.
+func (d *mp3Decoder) decodeFrame(h mp3Header, frame []byte, lost bool) {
+	sideStart := h.dataStart()
+	mainStart := sideStart + h.sideInfoLen
+	if len(frame) < mainStart {
+		lost = true
+	}
+
+	var si *sideInfo
+	var main bitReader
+	if !lost {
+		si = readSideInfo(&bitReader{data: frame[sideStart:mainStart]}, h)
+		if si.mainDataBegin > len(d.reservoir) {
+			lost = true
+		} else {
+			buf := make([]byte, 0, si.mainDataBegin+len(frame)-mainStart)
+			buf = append(buf, d.reservoir[len(d.reservoir)-si.mainDataBegin:]...)
+			buf = append(buf, frame[mainStart:]...)
+			main.data = buf
+		}
+	}
+	if len(frame) > mainStart {
+		d.reservoir = append(d.reservoir, frame[mainStart:]...)
+		if len(d.reservoir) > maxReservoir {
+			d.reservoir = append([]byte(nil), d.reservoir[len(d.reservoir)-maxReservoir:]...)
+		}
+	}
+	if lost {
+		d.lost++
+	}
+
+	sfb := &mp3ScalefactorBands[h.bandIndex]
+	var sf [2][2]scalefactors
+	out := make([]float64, 576*h.granules*d.channels)
+
+	for gr := 0; gr < h.granules; gr++ {
+		var xr [2][576]float64
+		var bands [2][]band
+
+		for ch := 0; ch < h.channels && !lost; ch++ {
+			gc := &si.gr[gr][ch]
+			bands[ch] = granuleBands(gc, sfb)
+			part2Start := main.pos
+
+			if h.lsf {
+				intensityRight := ch == 1 && h.mode == modeJointStereo && h.modeExt&1 != 0
+				readScalefactorsLSF(&main, gc, intensityRight, &sf[gr][ch])
+			} else {
+				readScalefactorsMPEG1(&main, gc, si.scfsi[ch], gr, &sf[0][ch], &sf[gr][ch])
+			}
+
+			end := part2Start + gc.part23Length
+			var is [576]int
+			readSpectrum(&main, gc, sfb, end, &is)
+			main.pos = end
+
+			requantize(&is, gc, &sf[gr][ch], bands[ch], &xr[ch])
+		}
+
+		if !lost && h.channels == 2 && h.mode == modeJointStereo {
+			processStereo(h, &si.gr[gr][1], &sf[gr][1], bands[1], &xr)
+		}
+
+		var pcm [2][576]float64
+		for ch := 0; ch < h.channels; ch++ {
+			var gc *granuleChannel
+			if !lost {
+				gc = &si.gr[gr][ch]
+			}
+			d.synthesizeGranule(ch, gc, bands[ch], &xr[ch], &pcm[ch])
+		}
+
+		for i := 0; i < 576; i++ {
+			for ch := 0; ch < d.channels; ch++ {
+				var v float64
+				switch {
+				case h.channels == d.channels:
+					v = pcm[ch][i]
+				case h.channels == 1:
+					v = pcm[0][i]
+				default:
+					v = (pcm[0][i] + pcm[1][i]) / 2
+				}
+				out[(gr*576+i)*d.channels+ch] = math.Max(-1, math.Min(1, v))
+			}
+		}
+	}
+
+	d.samples = append(d.samples, out...)
+}
+
+func (d *mp3Decoder) synthesizeGranule(ch int, gc *granuleChannel, bands []band, xr *[576]float64, pcm *[576]float64) {
+	var lines [576]float64
+	for _, b := range bands {
+		if !b.short {
+			copy(lines[b.start:b.start+b.width], xr[b.start:b.start+b.width])
+			continue
+		}
+		for j := 0; j < b.width; j++ {
+			f := b.freq + j
+			lines[(f/6)*18+b.window*6+f%6] = xr[b.start+j]
+		}
+	}
+
+	aliasLimit := 31
+	if gc != nil && gc.shortBlocks() {
+		aliasLimit = 0
+		if gc.mixed {
+			aliasLimit = 1
+		}
+	}
+	for sb := 0; sb < aliasLimit; sb++ {
+		for i := 0; i < 8; i++ {
+			lo, hi := sb*18+17-i, (sb+1)*18+i
+			a, b := lines[lo], lines[hi]
+			lines[lo] = a*aliasCS[i] - b*aliasCA[i]
+			lines[hi] = b*aliasCS[i] + a*aliasCA[i]
+		}
+	}
+
+	var subbands [32][18]float64
+	for sb := 0; sb < 32; sb++ {
+		blockType := 0
+		if gc != nil && gc.windowSwitching && !(gc.mixed && sb < 2) {
+			blockType = gc.blockType
+		}
+		imdct(lines[sb*18:sb*18+18], blockType, &d.overlap[ch][sb], subbands[sb][:])
+		if sb%2 == 1 {
+			for i := 1; i < 18; i += 2 {
+				subbands[sb][i] = -subbands[sb][i]
+			}
+		}
+	}
+
+	var input [32]float64
+	for t := 0; t < 18; t++ {
+		for sb := 0; sb < 32; sb++ {
+			input[sb] = subbands[sb][t]
+		}
+		d.synth[ch].synthesize(&input, pcm[t*32:t*32+32])
+	}
+}
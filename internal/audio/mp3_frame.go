@@ -0,0 +1,195 @@
+package audio
+
+// MP3FrameHeader holds the raw fields of an MPEG Layer III frame header and
+// the frame's size in bytes.
+type MP3FrameHeader struct {
+	Sync       uint16
+	Version    uint8
+	Layer      uint8
+	Protection uint8
+	Bitrate    uint8
+	SampleRate uint8
+	Padding    uint8
+	Private    uint8
+	Channel    uint8
+	ModeExt    uint8
+	Copyright  uint8
+	Original   uint8
+	Emphasis   uint8
+	Size       int
+}
+
+const (
+	mpegVersion25 = 0
+	mpegVersion2  = 2
+	mpegVersion1  = 3
+	layerIII      = 1
+)
+
+func (f *MP3FrameHeader) BitrateKbps() int {
+	if f.Bitrate >= 15 {
+		return 0
+	}
+	if f.Version == mpegVersion1 {
+		return mp3Bitrates[0][f.Bitrate]
+	}
+	return mp3Bitrates[1][f.Bitrate]
+}
+
+// bandIndex selects the row of the sample rate and scalefactor band tables.
+func (f *MP3FrameHeader) bandIndex() int {
+	switch f.Version {
+	case mpegVersion1:
+		return int(f.SampleRate)
+	case mpegVersion2:
+		return 3 + int(f.SampleRate)
+	default:
+		return 6 + int(f.SampleRate)
+	}
+}
+
+func (f *MP3FrameHeader) SampleRateHz() int {
+	if f.SampleRate >= 3 {
+		return 0
+	}
+	return mp3SampleRates[f.bandIndex()]
+}
+
+func (f *MP3FrameHeader) SamplesPerFrame() int {
+	if f.Version == mpegVersion1 {
+		return 1152
+	}
+	return 576
+}
+
+// SideInfoSize is the length of the Layer III side information that follows
+// the header and the optional CRC.
+func (f *MP3FrameHeader) SideInfoSize() int {
+	mono := f.Channel == 3
+	switch {
+	case f.Version == mpegVersion1 && mono:
+		return 17
+	case f.Version == mpegVersion1:
+		return 32
+	case mono:
+		return 9
+	}
+	return 17
+}
+
+func (f *MP3FrameHeader) VersionName() string {
+	switch f.Version {
+	case mpegVersion1:
+		return "MPEG-1"
+	case mpegVersion2:
+		return "MPEG-2"
+	case mpegVersion25:
+		return "MPEG-2.5"
+	default:
+		return "reserved"
+	}
+}
+
+func (f *MP3FrameHeader) ChannelMode() string {
+	switch f.Channel {
+	case 0:
+		return "stereo"
+	case 1:
+		return "joint_stereo"
+	case 2:
+		return "dual_channel"
+	default:
+		return "mono"
+	}
+}
+
+// ParseMP3FrameHeader parses the Layer III frame header at the start of
+// data. Free-format and reserved bitrates, reserved sample rates and other
+// layers are rejected.
+func ParseMP3FrameHeader(data []byte) (*MP3FrameHeader, bool) {
+	if len(data) < 4 || data[0] != 0xFF || data[1]&0xE0 != 0xE0 {
+		return nil, false
+	}
+
+	b1, b2, b3, b4 := data[0], data[1], data[2], data[3]
+	f := &MP3FrameHeader{
+		Sync:       uint16(b1)<<3 | uint16(b2>>5),
+		Version:    (b2 >> 3) & 0x03,
+		Layer:      (b2 >> 1) & 0x03,
+		Protection: b2 & 0x01,
+		Bitrate:    (b3 >> 4) & 0x0F,
+		SampleRate: (b3 >> 2) & 0x03,
+		Padding:    (b3 >> 1) & 0x01,
+		Private:    b3 & 0x01,
+		Channel:    (b4 >> 6) & 0x03,
+		ModeExt:    (b4 >> 4) & 0x03,
+		Copyright:  (b4 >> 3) & 0x01,
+		Original:   (b4 >> 2) & 0x01,
+		Emphasis:   b4 & 0x03,
+	}
+
+	bitrate := f.BitrateKbps() * 1000
+	sampleRate := f.SampleRateHz()
+	if f.Version == 1 || f.Layer != layerIII || bitrate == 0 || sampleRate == 0 {
+		return nil, false
+	}
+
+	f.Size = f.SamplesPerFrame()/8*bitrate/sampleRate + int(f.Padding)
+	return f, true
+}
+
+// ID3v2Size returns the length of the ID3v2 tag at the start of data,
+// including the footer a version 2.4 tag may carry, or 0 when there is none.
+func ID3v2Size(data []byte) int {
+	if len(data) < 10 || string(data[:3]) != "ID3" {
+		return 0
+	}
+	size := 0
+	for _, c := range data[6:10] {
+		size = size<<7 | int(c&0x7F)
+	}
+	size += 10
+	if data[3] == 4 && data[5]&0x10 != 0 {
+		size += 10
+	}
+	if size > len(data) {
+		return len(data)
+	}
+	return size
+}
+
+// FindMP3Frames locates the Layer III frames in data, which should start
+// after any ID3v2 tag. Whenever the scanner has to (re)acquire sync it
+// requires the next header to agree on version and sample rate, so stray
+// sync patterns in tags or junk are not taken for frames.
+func FindMP3Frames(data []byte) ([]*MP3FrameHeader, []int) {
+	var frames []*MP3FrameHeader
+	var offsets []int
+
+	locked := false
+	for pos := 0; pos+4 <= len(data); {
+		f, ok := ParseMP3FrameHeader(data[pos:])
+		if ok && pos+f.Size > len(data) {
+			ok = false
+		}
+		if ok && !locked {
+			next := pos + f.Size
+			if next+4 <= len(data) {
+				nf, nok := ParseMP3FrameHeader(data[next:])
+				ok = nok && nf.Version == f.Version && nf.SampleRate == f.SampleRate
+			}
+		}
+		if !ok {
+			locked = false
+			pos++
+			continue
+		}
+
+		locked = true
+		frames = append(frames, f)
+		offsets = append(offsets, pos)
+		pos += f.Size
+	}
+
+	return frames, offsets
+}
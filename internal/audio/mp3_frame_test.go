@@ -0,0 +1,133 @@
+package audio
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseMP3FrameHeader(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     []byte
+		ok         bool
+		version    string
+		bitrate    int
+		sampleRate int
+		samples    int
+		channel    string
+		size       int
+		sideInfo   int
+	}{
+		{"mpeg1 128k", []byte{0xFF, 0xFB, 0x90, 0x64}, true, "MPEG-1", 128, 44100, 1152, "joint_stereo", 417, 32},
+		{"mpeg1 padded", []byte{0xFF, 0xFB, 0x92, 0x64}, true, "MPEG-1", 128, 44100, 1152, "joint_stereo", 418, 32},
+		{"mpeg1 48k mono", []byte{0xFF, 0xFA, 0xE4, 0xC0}, true, "MPEG-1", 320, 48000, 1152, "mono", 960, 17},
+		{"mpeg2 64k", []byte{0xFF, 0xF3, 0x80, 0x00}, true, "MPEG-2", 64, 22050, 576, "stereo", 208, 17},
+		{"mpeg2 mono", []byte{0xFF, 0xF3, 0x80, 0xC0}, true, "MPEG-2", 64, 22050, 576, "mono", 208, 9},
+		{"mpeg2.5 8k", []byte{0xFF, 0xE3, 0x18, 0x80}, true, "MPEG-2.5", 8, 8000, 576, "dual_channel", 72, 17},
+		{"no sync", []byte{0xFF, 0x7B, 0x90, 0x64}, false, "", 0, 0, 0, "", 0, 0},
+		{"reserved version", []byte{0xFF, 0xEB, 0x90, 0x64}, false, "", 0, 0, 0, "", 0, 0},
+		{"layer ii", []byte{0xFF, 0xFD, 0x90, 0x64}, false, "", 0, 0, 0, "", 0, 0},
+		{"free format", []byte{0xFF, 0xFB, 0x00, 0x64}, false, "", 0, 0, 0, "", 0, 0},
+		{"bad bitrate", []byte{0xFF, 0xFB, 0xF0, 0x64}, false, "", 0, 0, 0, "", 0, 0},
+		{"reserved sample rate", []byte{0xFF, 0xFB, 0x9C, 0x64}, false, "", 0, 0, 0, "", 0, 0},
+		{"truncated", []byte{0xFF, 0xFB, 0x90}, false, "", 0, 0, 0, "", 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, ok := ParseMP3FrameHeader(tt.header)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if f.VersionName() != tt.version || f.BitrateKbps() != tt.bitrate || f.SampleRateHz() != tt.sampleRate {
+				t.Errorf("got %s %d kbps %d Hz, want %s %d kbps %d Hz",
+					f.VersionName(), f.BitrateKbps(), f.SampleRateHz(), tt.version, tt.bitrate, tt.sampleRate)
+			}
+			if f.SamplesPerFrame() != tt.samples || f.ChannelMode() != tt.channel || f.Size != tt.size {
+				t.Errorf("got %d samples, %s, %d bytes, want %d samples, %s, %d bytes",
+					f.SamplesPerFrame(), f.ChannelMode(), f.Size, tt.samples, tt.channel, tt.size)
+			}
+			if f.SideInfoSize() != tt.sideInfo {
+				t.Errorf("side information %d bytes, want %d", f.SideInfoSize(), tt.sideInfo)
+			}
+		})
+	}
+}
+
+func TestID3v2Size(t *testing.T) {
+	padded := func(tag []byte, n int) []byte {
+		return append(tag, make([]byte, n)...)
+	}
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"no tag", padded([]byte{0xFF, 0xFB, 0x90, 0x64}, 20), 0},
+		{"empty tag", padded([]byte("ID3\x03\x00\x00\x00\x00\x00\x00"), 4), 10},
+		{"synchsafe size", padded([]byte("ID3\x04\x00\x00\x00\x00\x01\x7F"), 300), 265},
+		{"footer", padded([]byte("ID3\x04\x00\x10\x00\x00\x00\x10"), 40), 36},
+		{"footer flag before v2.4", padded([]byte("ID3\x03\x00\x10\x00\x00\x00\x10"), 40), 26},
+		{"clamped", padded([]byte("ID3\x03\x00\x00\x00\x00\x10\x00"), 100), 110},
+		{"too short", []byte("ID3\x03"), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ID3v2Size(tt.data); got != tt.want {
+				t.Errorf("ID3v2Size = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// testFrames returns n MPEG-1 128 kbps frames with zero bodies, padding every
+// third one as an encoder would at 44.1 kHz.
+func testFrames(n int) []byte {
+	var data []byte
+	for i := 0; i < n; i++ {
+		padding := byte(0)
+		if i%3 == 2 {
+			padding = 1
+		}
+		frame := make([]byte, 417+int(padding))
+		copy(frame, []byte{0xFF, 0xFB, 0x90 | padding<<1, 0x64})
+		data = append(data, frame...)
+	}
+	return data
+}
+
+func TestFindMP3Frames(t *testing.T) {
+	frames := testFrames(6)
+	// A lone sync pattern whose successor is not a frame header.
+	stray := append([]byte{0xFF, 0xFB, 0x90, 0x64}, bytes.Repeat([]byte{0x55}, 500)...)
+	// An MPEG-2 header that would be followed by an MPEG-1 one.
+	mismatched := append([]byte{0xFF, 0xF3, 0x80, 0x00}, make([]byte, 204)...)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		offsets []int
+	}{
+		{"clean", frames, []int{0, 417, 834, 1252, 1669, 2086}},
+		{"leading junk", append([]byte{0x00, 0xFF, 0x12}, frames...), []int{3, 420, 837, 1255, 1672, 2089}},
+		{"stray sync", append(stray, frames[:834]...), []int{504, 921}},
+		{"version change", append(mismatched, frames[:834]...), []int{208, 625}},
+		{"truncated last frame", frames[:1000], []int{0, 417}},
+		{"no frames", bytes.Repeat([]byte{0xFF}, 64), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, offsets := FindMP3Frames(tt.data)
+			if len(offsets) != len(tt.offsets) {
+				t.Fatalf("offsets = %v, want %v", offsets, tt.offsets)
+			}
+			for i := range offsets {
+				if offsets[i] != tt.offsets[i] {
+					t.Fatalf("offsets = %v, want %v", offsets, tt.offsets)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package audio
+
+// bitReader reads big-endian bit fields. Reads past the end return zero bits
+// so corrupt streams decode to garbage instead of panicking.
+type bitReader struct {
+	data []byte
+	pos  int
+}
+
+func (b *bitReader) bit() int {
+	i := b.pos >> 3
+	b.pos++
+	if i >= len(b.data) {
+		return 0
+	}
+	return int(b.data[i]>>(7-uint((b.pos-1)&7))) & 1
+}
+
+func (b *bitReader) bits(n int) int {
+	value := 0
+	for i := 0; i < n; i++ {
+		value = value<<1 | b.bit()
+	}
+	return value
+}
+
+// huffmanTree stores a binary decoding tree. Non-negative entries point to
+// the next node; negative entries are leaves holding -(value+1).
+type huffmanTree [][2]int32
+
+func newHuffmanTree(spec huffmanSpec, symbol func(index int) int) huffmanTree {
+	tree := huffmanTree{{0, 0}}
+	for index, code := range spec.codes {
+		length := int(spec.lens[index])
+		node := 0
+		for i := length - 1; i >= 0; i-- {
+			bit := (code >> uint(i)) & 1
+			if i == 0 {
+				tree[node][bit] = -int32(symbol(index) + 1)
+				break
+			}
+			if tree[node][bit] == 0 {
+				tree = append(tree, [2]int32{0, 0})
+				tree[node][bit] = int32(len(tree) - 1)
+			}
+			node = int(tree[node][bit])
+		}
+	}
+	return tree
+}
+
+func (t huffmanTree) decode(r *bitReader) int {
+	node := int32(0)
+	for {
+		next := t[node][r.bit()]
+		if next < 0 {
+			return int(-next - 1)
+		}
+		if next == 0 {
+			return 0
+		}
+		node = next
+	}
+}
+
+var (
+	bigValueTrees = map[int]huffmanTree{}
+	count1TreeA   huffmanTree
+)
+
+func init() {
+	for id, spec := range huffmanSpecs {
+		size := spec.size
+		bigValueTrees[id] = newHuffmanTree(spec, func(index int) int {
+			return (index/size)<<4 | index%size
+		})
+	}
+	count1TreeA = newHuffmanTree(count1SpecA, func(index int) int { return index })
+}
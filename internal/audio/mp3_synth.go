@@ -0,0 +1,117 @@
+package audio
+
+import "math"
+
+var (
+	synthesisMatrix [64][32]float64
+
+	imdctLongCos  [18][36]float64
+	imdctShortCos [6][12]float64
+	imdctWindows  [4][36]float64
+
+	aliasCS, aliasCA [8]float64
+)
+
+func init() {
+	for i := 0; i < 64; i++ {
+		for k := 0; k < 32; k++ {
+			synthesisMatrix[i][k] = math.Cos(float64((16+i)*(2*k+1)) * math.Pi / 64)
+		}
+	}
+
+	for k := 0; k < 18; k++ {
+		for i := 0; i < 36; i++ {
+			imdctLongCos[k][i] = math.Cos(math.Pi / 72 * float64(2*i+1+18) * float64(2*k+1))
+		}
+	}
+	for k := 0; k < 6; k++ {
+		for i := 0; i < 12; i++ {
+			imdctShortCos[k][i] = math.Cos(math.Pi / 24 * float64(2*i+1+6) * float64(2*k+1))
+		}
+	}
+
+	for i := 0; i < 36; i++ {
+		imdctWindows[0][i] = math.Sin(math.Pi / 36 * (float64(i) + 0.5))
+	}
+	for i := 0; i < 18; i++ {
+		imdctWindows[1][i] = imdctWindows[0][i]
+		imdctWindows[3][i+18] = imdctWindows[0][i+18]
+	}
+	for i := 18; i < 24; i++ {
+		imdctWindows[1][i] = 1
+		imdctWindows[3][i-12] = 1
+	}
+	for i := 24; i < 30; i++ {
+		imdctWindows[1][i] = math.Sin(math.Pi / 12 * (float64(i-18) + 0.5))
+		imdctWindows[3][i-18] = math.Sin(math.Pi / 12 * (float64(i-24) + 0.5))
+	}
+	for i := 0; i < 12; i++ {
+		imdctWindows[2][i] = math.Sin(math.Pi / 12 * (float64(i) + 0.5))
+	}
+
+	for i, c := range aliasCoefficients {
+		d := math.Sqrt(1 + c*c)
+		aliasCS[i] = 1 / d
+		aliasCA[i] = c / d
+	}
+}
+
+// imdct transforms the 18 lines of one subband and overlap-adds the result
+// with the previous granule, writing 18 time samples to out.
+func imdct(in []float64, blockType int, overlap *[18]float64, out []float64) {
+	var raw [36]float64
+	if blockType == 2 {
+		win := &imdctWindows[2]
+		for w := 0; w < 3; w++ {
+			for i := 0; i < 12; i++ {
+				var sum float64
+				for k := 0; k < 6; k++ {
+					sum += in[w*6+k] * imdctShortCos[k][i]
+				}
+				raw[6+6*w+i] += sum * win[i]
+			}
+		}
+	} else {
+		win := &imdctWindows[blockType]
+		for i := 0; i < 36; i++ {
+			var sum float64
+			for k := 0; k < 18; k++ {
+				sum += in[k] * imdctLongCos[k][i]
+			}
+			raw[i] = sum * win[i]
+		}
+	}
+
+	for i := 0; i < 18; i++ {
+		out[i] = raw[i] + overlap[i]
+		overlap[i] = raw[i+18]
+	}
+}
+
+type synthesisState struct {
+	v   [1024]float64
+	pos int
+}
+
+// synthesize runs the polyphase filterbank on one set of 32 subband samples
+// and writes 32 PCM samples to out.
+func (s *synthesisState) synthesize(subbands *[32]float64, out []float64) {
+	s.pos = (s.pos - 64) & 1023
+	for i := 0; i < 64; i++ {
+		var sum float64
+		row := &synthesisMatrix[i]
+		for k := 0; k < 32; k++ {
+			sum += row[k] * subbands[k]
+		}
+		s.v[(s.pos+i)&1023] = sum
+	}
+
+	for j := 0; j < 32; j++ {
+		var sum float64
+		for i := 0; i < 8; i++ {
+			sum += s.v[(s.pos+128*i+j)&1023] * synthesisWindow[64*i+j]
+			sum += s.v[(s.pos+128*i+96+j)&1023] * synthesisWindow[64*i+32+j]
+		}
+		out[j] = sum
+	}
+}
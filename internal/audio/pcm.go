@@ -0,0 +1,68 @@
+package audio
+
+import "errors"
+
+var ErrUnsupportedAudio = errors.New("audio format is not supported for decoding")
+
+// PCM holds interleaved samples scaled to [-1, 1].
+type PCM struct {
+	Channels   int       `json:"channels"`
+	SampleRate int       `json:"sample_rate"`
+	Samples    []float64 `json:"-"`
+}
+
+func (p *PCM) Frames() int {
+	if p.Channels == 0 {
+		return 0
+	}
+	return len(p.Samples) / p.Channels
+}
+
+func (p *PCM) DurationSeconds() float64 {
+	if p.SampleRate == 0 {
+		return 0
+	}
+	return float64(p.Frames()) / float64(p.SampleRate)
+}
+
+// Mono returns the average of all channels for every sample frame.
+func (p *PCM) Mono() []float64 {
+	mono := make([]float64, p.Frames())
+	for i := range mono {
+		var sum float64
+		for c := 0; c < p.Channels; c++ {
+			sum += p.Samples[i*p.Channels+c]
+		}
+		mono[i] = sum / float64(p.Channels)
+	}
+	return mono
+}
+
+func (w *WAV) PCM() *PCM {
+	scale := float64(w.MaxSample() + 1)
+	samples := make([]float64, len(w.Samples))
+	for i, s := range w.Samples {
+		samples[i] = float64(s) / scale
+	}
+	return &PCM{Channels: w.Channels, SampleRate: w.SampleRate, Samples: samples}
+}
+
+// DecodePCM decodes a WAV or MP3 file to PCM.
+func DecodePCM(data []byte) (*PCM, error) {
+	if IsWAV(data) {
+		wav, err := ParseWAV(data)
+		if err != nil {
+			return nil, err
+		}
+		return wav.PCM(), nil
+	}
+
+	stream, err := DecodeMP3(data)
+	if err != nil {
+		if err == ErrNotMP3 {
+			return nil, ErrUnsupportedAudio
+		}
+		return nil, err
+	}
+	return stream.PCM, nil
+}
@@ -0,0 +1,185 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+var (
+	ErrNotWAV         = errors.New("data is not a RIFF/WAVE file")
+	ErrInvalidWAV     = errors.New("invalid or truncated WAV file")
+	ErrUnsupportedWAV = errors.New("only integer PCM WAV files are supported")
+)
+
+const (
+	formatPCM        = 1
+	formatExtensible = 0xFFFE
+)
+
+type WAV struct {
+	Channels      int   `json:"channels"`
+	SampleRate    int   `json:"sample_rate"`
+	BitsPerSample int   `json:"bits_per_sample"`
+	DataOffset    int   `json:"data_offset"`
+	DataSize      int   `json:"data_size"`
+	Samples       []int `json:"-"`
+}
+
+func IsWAV(data []byte) bool {
+	return len(data) >= 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "WAVE"
+}
+
+func ParseWAV(data []byte) (*WAV, error) {
+	if !IsWAV(data) {
+		return nil, ErrNotWAV
+	}
+
+	wav := &WAV{}
+	haveFormat := false
+
+	for pos := 12; pos+8 <= len(data); {
+		chunkID := string(data[pos : pos+4])
+		chunkSize := int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
+		body := pos + 8
+		if chunkSize < 0 || body+chunkSize > len(data) {
+			if chunkID != "data" {
+				return nil, ErrInvalidWAV
+			}
+			chunkSize = len(data) - body
+		}
+
+		switch chunkID {
+		case "fmt ":
+			if chunkSize < 16 {
+				return nil, ErrInvalidWAV
+			}
+			format := binary.LittleEndian.Uint16(data[body:])
+			if format == formatExtensible && chunkSize >= 26 {
+				format = binary.LittleEndian.Uint16(data[body+24:])
+			}
+			if format != formatPCM {
+				return nil, ErrUnsupportedWAV
+			}
+
+			wav.Channels = int(binary.LittleEndian.Uint16(data[body+2:]))
+			wav.SampleRate = int(binary.LittleEndian.Uint32(data[body+4:]))
+			wav.BitsPerSample = int(binary.LittleEndian.Uint16(data[body+14:]))
+			switch wav.BitsPerSample {
+			case 8, 16, 24, 32:
+			default:
+				return nil, ErrUnsupportedWAV
+			}
+			if wav.Channels == 0 {
+				return nil, ErrInvalidWAV
+			}
+			haveFormat = true
+		case "data":
+			if !haveFormat {
+				return nil, ErrInvalidWAV
+			}
+			wav.DataOffset = body
+			wav.DataSize = chunkSize - chunkSize%wav.BytesPerSample()
+			wav.Samples = decodeSamples(data[body:body+wav.DataSize], wav.BitsPerSample)
+			return wav, nil
+		}
+
+		pos = body + chunkSize + chunkSize%2
+	}
+
+	return nil, ErrInvalidWAV
+}
+
+func (w *WAV) BytesPerSample() int {
+	return w.BitsPerSample / 8
+}
+
+func (w *WAV) Frames() int {
+	return len(w.Samples) / w.Channels
+}
+
+func (w *WAV) DurationSeconds() float64 {
+	if w.SampleRate == 0 {
+		return 0
+	}
+	return float64(w.Frames()) / float64(w.SampleRate)
+}
+
+func (w *WAV) Channel(channel int) []int {
+	samples := make([]int, w.Frames())
+	for i := range samples {
+		samples[i] = w.Samples[i*w.Channels+channel]
+	}
+	return samples
+}
+
+// Mono returns the average of all channels for every sample frame.
+func (w *WAV) Mono() []float64 {
+	mono := make([]float64, w.Frames())
+	for i := range mono {
+		var sum int
+		for c := 0; c < w.Channels; c++ {
+			sum += w.Samples[i*w.Channels+c]
+		}
+		mono[i] = float64(sum) / float64(w.Channels)
+	}
+	return mono
+}
+
+func (w *WAV) MaxSample() int {
+	return 1<<(w.BitsPerSample-1) - 1
+}
+
+func decodeSamples(data []byte, bitsPerSample int) []int {
+	size := bitsPerSample / 8
+	samples := make([]int, len(data)/size)
+
+	for i := range samples {
+		b := data[i*size : (i+1)*size]
+		switch bitsPerSample {
+		case 8:
+			samples[i] = int(b[0]) - 128
+		case 16:
+			samples[i] = int(int16(binary.LittleEndian.Uint16(b)))
+		case 24:
+			v := int(b[0]) | int(b[1])<<8 | int(b[2])<<16
+			if v&0x800000 != 0 {
+				v -= 1 << 24
+			}
+			samples[i] = v
+		case 32:
+			samples[i] = int(int32(binary.LittleEndian.Uint32(b)))
+		}
+	}
+
+	return samples
+}
+
+// Encode returns a copy of data, the file w was parsed from, with the data
+// chunk rewritten from w.Samples. Every other chunk is kept byte for byte.
+// Samples outside the range of the sample format are clipped.
+func (w *WAV) Encode(data []byte) []byte {
+	result := make([]byte, len(data))
+	copy(result, data)
+	encodeSamples(result[w.DataOffset:w.DataOffset+w.DataSize], w.Samples, w.BitsPerSample)
+	return result
+}
+
+func encodeSamples(data []byte, samples []int, bitsPerSample int) {
+	size := bitsPerSample / 8
+	low, high := -(1 << (bitsPerSample - 1)), 1<<(bitsPerSample-1)-1
+
+	for i, sample := range samples {
+		v := min(max(sample, low), high)
+		b := data[i*size : (i+1)*size]
+		switch bitsPerSample {
+		case 8:
+			b[0] = byte(v + 128)
+		case 16:
+			binary.LittleEndian.PutUint16(b, uint16(int16(v)))
+		case 24:
+			b[0], b[1], b[2] = byte(v), byte(v>>8), byte(v>>16)
+		case 32:
+			binary.LittleEndian.PutUint32(b, uint32(int32(v)))
+		}
+	}
+}
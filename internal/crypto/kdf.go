@@ -0,0 +1,40 @@
+package crypto
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// PBKDF2 derives keyLen bytes from password and salt with PBKDF2-HMAC-SHA256
+// (RFC 8018).
+func PBKDF2(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	size := prf.Size()
+	blocks := (keyLen + size - 1) / size
+
+	derived := make([]byte, 0, blocks*size)
+	block := make([]byte, 4)
+	u := make([]byte, size)
+	t := make([]byte, size)
+	for i := 1; i <= blocks; i++ {
+		binary.BigEndian.PutUint32(block, uint32(i))
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write(block)
+		u = prf.Sum(u[:0])
+		copy(t, u)
+
+		for n := 1; n < iterations; n++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		derived = append(derived, t...)
+	}
+
+	return derived[:keyLen]
+}
@@ -0,0 +1,27 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestPBKDF2(t *testing.T) {
+	tests := []struct {
+		password, salt string
+		iterations     int
+		keyLen         int
+		want           string
+	}{
+		{"password", "salt", 1, 32, "120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b"},
+		{"password", "salt", 4096, 32, "c5e478d59288c841aa530db6845c4c8d962893a001ce4e11a4963873aa98134a"},
+		{"passwordPASSWORDpassword", "saltSALTsaltSALTsaltSALTsaltSALTsalt", 4096, 40,
+			"348c89dbcbd32b2f32d814b8116e84cf2b17347ebc1800181c4e2a1fb8dd53e1c635518c7dac47e9"},
+		{"password", "salt", 1, 4, "120fb6cf"},
+	}
+	for _, tt := range tests {
+		got := hex.EncodeToString(PBKDF2([]byte(tt.password), []byte(tt.salt), tt.iterations, tt.keyLen))
+		if got != tt.want {
+			t.Errorf("PBKDF2(%q, %q, %d, %d) = %s, want %s", tt.password, tt.salt, tt.iterations, tt.keyLen, got, tt.want)
+		}
+	}
+}
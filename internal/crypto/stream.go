@@ -0,0 +1,25 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+)
+
+func NewKeystream(key, label string, nonce []byte) cipher.Stream {
+	derived := sha256.Sum256([]byte(label + ":" + key))
+	block, err := aes.NewCipher(derived[:])
+	if err != nil {
+		panic(err)
+	}
+
+	iv := make([]byte, aes.BlockSize)
+	copy(iv, nonce)
+	return cipher.NewCTR(block, iv)
+}
+
+func KeystreamXOR(data []byte, key, label string, nonce []byte) []byte {
+	result := make([]byte, len(data))
+	NewKeystream(key, label, nonce).XORKeyStream(result, data)
+	return result
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/stego"
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/utils"
+)
+
+type AnalyzeResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	*stego.MP3Analysis
+}
+
+func AnalyzeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		utils.SendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	err := r.ParseMultipartForm(100 << 20)
+	if err != nil {
+		utils.SendError(w, "Failed to parse form data", http.StatusBadRequest)
+		return
+	}
+
+	mp3Data, mp3Header, err := readUploadedFile(r, "mp3_file")
+	if err != nil {
+		utils.SendError(w, "MP3 file is required", http.StatusBadRequest)
+		return
+	}
+
+	analysis, err := stego.AnalyzeMP3(mp3Data)
+	if err != nil {
+		utils.SendError(w, "Failed to analyze MP3 file: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response := AnalyzeResponse{
+		Success:     true,
+		Message:     "MP3 analyzed successfully",
+		MP3Analysis: analysis,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+
+	log.Printf("Analyze operation: mp3=%s, frames=%d, mode=%s, duration=%.2fs",
+		mp3Header.Filename, analysis.FrameCount, analysis.BitrateMode, analysis.DurationSeconds)
+}
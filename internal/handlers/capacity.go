@@ -3,23 +3,36 @@ package handlers
 import (
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
-	"os"
-	"path/filepath"
-	"strconv"
 
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/audio"
 	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/stego"
 	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/utils"
 )
 
 type CapacityResponse struct {
-	Success          bool   `json:"success"`
-	Message          string `json:"message"`
-	CapacityBytes    int    `json:"capacity_bytes"`
-	CapacityReadable string `json:"capacity_readable"`
-	FrameCount       int    `json:"frame_count"`
-	Method           string `json:"method"`
+	Success          bool             `json:"success"`
+	Message          string           `json:"message"`
+	CapacityBytes    int              `json:"capacity_bytes"`
+	CapacityReadable string           `json:"capacity_readable"`
+	FrameCount       int              `json:"frame_count"`
+	Method           string           `json:"method"`
+	Filename         string           `json:"filename"`
+	FileType         string           `json:"file_type"`
+	OverheadBytes    int              `json:"overhead_bytes"`
+	BitsPerSecond    float64          `json:"bits_per_second,omitempty"`
+	DurationSeconds  float64          `json:"duration_seconds,omitempty"`
+	MinDuration      float64          `json:"min_duration_seconds,omitempty"`
+	Capacities       []MethodCapacity `json:"capacities"`
+}
+
+type MethodCapacity struct {
+	Method           string  `json:"method"`
+	LSBBits          int     `json:"lsb_bits,omitempty"`
+	CapacityBytes    int     `json:"capacity_bytes"`
+	CapacityReadable string  `json:"capacity_readable"`
+	BitsPerSecond    float64 `json:"bits_per_second,omitempty"`
+	Error            string  `json:"error,omitempty"`
 }
 
 func CapacityHandler(w http.ResponseWriter, r *http.Request) {
@@ -38,85 +51,171 @@ func CapacityHandler(w http.ResponseWriter, r *http.Request) {
 	if method == "" {
 		method = "lsb"
 	}
-
-	var lsbBits int
-	if method == "lsb" {
-		lsbBitsStr := r.FormValue("lsb_bits")
-		if lsbBitsStr != "" {
-			lsbBits, err = strconv.Atoi(lsbBitsStr)
-			if err != nil || lsbBits < 1 || lsbBits > 4 {
-				lsbBits = 1
-			}
-		} else {
-			lsbBits = 1
-		}
+	stegoMethod, ok := stego.LookupMethod(method)
+	if !ok {
+		utils.SendError(w, "Unknown steganography method: "+method, http.StatusBadRequest)
+		return
 	}
 
-	mp3File, mp3Header, err := r.FormFile("mp3_file")
+	mp3Data, _, err := readUploadedFile(r, "mp3_file")
 	if err != nil {
 		utils.SendError(w, "MP3 file is required", http.StatusBadRequest)
 		return
 	}
-	defer mp3File.Close()
 
-	tempDir := "./temp"
-	os.MkdirAll(tempDir, 0755)
-
-	mp3Path := filepath.Join(tempDir, mp3Header.Filename)
-	mp3Dst, err := os.Create(mp3Path)
+	opts, err := readCapacityOptions(r, stegoMethod)
 	if err != nil {
-		utils.SendError(w, "Failed to save MP3 file", http.StatusInternalServerError)
+		utils.SendError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	defer mp3Dst.Close()
-	defer os.Remove(mp3Path)
 
-	_, err = io.Copy(mp3Dst, mp3File)
-	if err != nil {
-		utils.SendError(w, "Failed to save MP3 file", http.StatusInternalServerError)
+	if opts.Stealth && opts.Key == "" {
+		utils.SendError(w, "Key is required for stealth mode", http.StatusBadRequest)
 		return
 	}
-	mp3Dst.Close()
 
-	mp3Data, err := os.ReadFile(mp3Path)
+	overhead, err := stego.MethodOverhead(stegoMethod, 0, opts)
 	if err != nil {
-		utils.SendError(w, "Failed to read MP3 file", http.StatusInternalServerError)
+		utils.SendError(w, "Invalid embedding options: "+err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	var capacity, frameCount int
-	var methodName string
+	var capacities []MethodCapacity
+	for _, m := range stego.Methods() {
+		methodOpts, _, optsErr := readEmbedOptions(r, m)
+		methodOpts.OriginalFilename, methodOpts.FileType = opts.OriginalFilename, opts.FileType
+		for _, bits := range stego.BitDepths(m) {
+			entry := MethodCapacity{Method: m.Name(), LSBBits: bits}
+			if optsErr != nil {
+				entry.Error = optsErr.Error()
+				capacities = append(capacities, entry)
+				continue
+			}
+			methodOpts.Bits = bits
 
-	if method == "header" {
-		headerStego := stego.NewHeaderSteganography()
-		capacity, frameCount, err = headerStego.CalculateCapacity(mp3Data)
-		if err != nil {
-			utils.SendError(w, "Failed to calculate header capacity: "+err.Error(), http.StatusInternalServerError)
-			return
+			capacity, err := m.Capacity(mp3Data, methodOpts)
+			if err != nil {
+				entry.Error = err.Error()
+			}
+			entry.CapacityBytes = capacity
+			entry.CapacityReadable = formatBytes(capacity)
+			if rater, ok := m.(stego.Rater); ok {
+				entry.BitsPerSecond, _ = rater.BitsPerSecond(mp3Data, methodOpts)
+			}
+			capacities = append(capacities, entry)
 		}
-		methodName = "MP3 Header Steganography"
-	} else {
-		capacity = stego.CalculateCapacity(len(mp3Data), lsbBits)
-		capacity -= 4
-		frameCount = 0
-		methodName = fmt.Sprintf("LSB Steganography (%d bits)", lsbBits)
 	}
 
-	capacityReadable := formatBytes(capacity)
+	capacity, err := stegoMethod.Capacity(mp3Data, opts)
+	if err != nil {
+		utils.SendError(w, "Failed to calculate "+method+" capacity: "+err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	response := CapacityResponse{
 		Success:          true,
 		Message:          "Capacity calculated successfully",
 		CapacityBytes:    capacity,
-		CapacityReadable: capacityReadable,
-		FrameCount:       frameCount,
-		Method:           methodName,
+		CapacityReadable: formatBytes(capacity),
+		Method:           capacityMethodName(stegoMethod, opts),
+		Filename:         opts.OriginalFilename,
+		FileType:         opts.FileType,
+		OverheadBytes:    overhead,
+		Capacities:       capacities,
+	}
+
+	if method == "header" || method == "chunked" {
+		_, response.FrameCount, _ = stego.NewHeaderSteganography().CalculateCapacity(mp3Data)
+	}
+
+	if rater, ok := stegoMethod.(stego.Rater); ok {
+		response.BitsPerSecond, err = rater.BitsPerSecond(mp3Data, opts)
+		if err == nil && response.BitsPerSecond == 0 {
+			utils.SendError(w, "The carrier has no sample rate, so "+method+" cannot hold any data", http.StatusBadRequest)
+			return
+		}
+		if err == nil {
+			wav, _ := audio.ParseWAV(mp3Data)
+			response.DurationSeconds = wav.DurationSeconds()
+			response.MinDuration = float64(overhead*8) / response.BitsPerSecond
+			if capacity == 0 {
+				response.Message = tooShortMessage(method, response.BitsPerSecond, response.MinDuration)
+			}
+		}
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+// readCapacityOptions reads the embedding options of method together with
+// the secret file's name and type, taken from secret_file when one is
+// uploaded and from the filename and file_type fields otherwise.
+func readCapacityOptions(r *http.Request, method stego.Method) (stego.EmbedOptions, error) {
+	opts, _, err := readEmbedOptions(r, method)
+	if err != nil {
+		return opts, err
+	}
+
+	opts.OriginalFilename = r.FormValue("filename")
+	opts.FileType = r.FormValue("file_type")
+	if secretData, secretHeader, err := readUploadedFile(r, "secret_file"); err == nil {
+		if opts.OriginalFilename == "" {
+			opts.OriginalFilename = secretHeader.Filename
+		}
+		opts.FileType = stego.DetectFileType(secretData, secretHeader.Filename)
+	} else if opts.FileType == "" {
+		opts.FileType = stego.FileTypeFromName(opts.OriginalFilename)
+		if opts.FileType == "" {
+			opts.FileType = "application/octet-stream"
+		}
+	}
+
+	return opts, nil
+}
+
+func capacityMethodName(method stego.Method, opts stego.EmbedOptions) string {
+	switch method.Name() {
+	case "lsb":
+		return fmt.Sprintf("LSB Steganography (%d bits)", opts.Bits)
+	case "header":
+		return "MP3 Header Steganography"
+	case "chunked":
+		return fmt.Sprintf("Chunked LSB Steganography (%d bits)", opts.Bits)
+	case "dsss":
+		chipRate := opts.ChipRate
+		if chipRate == 0 {
+			chipRate = stego.DefaultChipRate
+		}
+		return fmt.Sprintf("Spread Spectrum Steganography (%d samples per bit)", chipRate)
+	case "echo":
+		return "Echo Hiding Steganography"
+	}
+	return method.Name()
+}
+
+// tooShortMessage explains why a method whose capacity grows with duration
+// cannot hold even the container of a carrier.
+func tooShortMessage(method string, bitsPerSecond, minDuration float64) string {
+	return fmt.Sprintf("The carrier is too short for %s: it holds about %.1f bits per second, so at least %.1f seconds of audio are needed for the container alone",
+		method, bitsPerSecond, minDuration)
+}
+
+// rateHint tells how much audio a payload of payloadBytes needs when method's
+// capacity grows with duration, or returns "" for other methods.
+func rateHint(method stego.Method, carrier []byte, opts stego.EmbedOptions, payloadBytes int) string {
+	rater, ok := method.(stego.Rater)
+	if !ok {
+		return ""
+	}
+	bitsPerSecond, err := rater.BitsPerSecond(carrier, opts)
+	if err != nil || bitsPerSecond == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (%s holds about %.1f bits per second; this payload needs %.1f seconds of audio)",
+		method.Name(), bitsPerSecond, float64(payloadBytes*8)/bitsPerSecond)
+}
+
 func formatBytes(bytes int) string {
 	if bytes < 1024 {
 		return fmt.Sprintf("%d bytes", bytes)
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"encoding/binary"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCapacityHandler(t *testing.T) {
+	mp3 := testMP3(200, 10)
+	wav := testWAV(10, 8000, 11)
+	silentRate := append([]byte(nil), wav...)
+	binary.LittleEndian.PutUint32(silentRate[24:], 0)
+	binary.LittleEndian.PutUint32(silentRate[28:], 0)
+
+	tests := []struct {
+		name    string
+		fields  map[string]string
+		carrier []byte
+		status  int
+		message string
+	}{
+		{"lsb", map[string]string{"method": "lsb", "lsb_bits": "2"}, mp3, http.StatusOK, "Capacity calculated successfully"},
+		{"header", map[string]string{"method": "header"}, mp3, http.StatusOK, "Capacity calculated successfully"},
+		{"dsss", map[string]string{"method": "dsss", "chip_rate": "64"}, wav, http.StatusOK, "Capacity calculated successfully"},
+		{"dsss too short", map[string]string{"method": "dsss"}, wav, http.StatusOK, "The carrier is too short for dsss"},
+		{"dsss without sample rate", map[string]string{"method": "dsss"}, silentRate, http.StatusBadRequest, "The carrier has no sample rate"},
+		{"echo without sample rate", map[string]string{"method": "echo"}, silentRate, http.StatusBadRequest, "The carrier has no sample rate"},
+		{"dsss on mp3", map[string]string{"method": "dsss"}, mp3, http.StatusBadRequest, "Failed to calculate dsss capacity"},
+		{"unknown method", map[string]string{"method": "morse"}, mp3, http.StatusBadRequest, "Unknown steganography method"},
+		{"stealth without key", map[string]string{"method": "lsb", "stealth": "true"}, mp3, http.StatusBadRequest, "Key is required"},
+		{"invalid chip rate", map[string]string{"method": "dsss", "chip_rate": "fast"}, wav, http.StatusBadRequest, errInvalidSpread.Error()},
+		{"no carrier", map[string]string{"method": "lsb"}, nil, http.StatusBadRequest, "MP3 file is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := map[string][]byte{}
+			if tt.carrier != nil {
+				files["mp3_file"] = tt.carrier
+			}
+			w := serveForm(t, CapacityHandler, tt.fields, files)
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			var response CapacityResponse
+			decodeResponse(t, w, &response)
+			if !strings.HasPrefix(response.Message, tt.message) {
+				t.Errorf("message %q, want prefix %q", response.Message, tt.message)
+			}
+			if tt.status == http.StatusOK && len(response.Capacities) == 0 {
+				t.Errorf("no per-method capacities")
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/audio"
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/crypto"
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/metrics"
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/steganalysis"
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/stego"
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/utils"
+)
+
+const compareChiSquareWindow = 4096
+
+type CompareEntry struct {
+	Method               string  `json:"method"`
+	LSBBits              int     `json:"lsb_bits,omitempty"`
+	CapacityBytes        int     `json:"capacity_bytes"`
+	Fits                 bool    `json:"fits"`
+	Success              bool    `json:"success"`
+	EmbedMillis          float64 `json:"embed_ms"`
+	ExtractMillis        float64 `json:"extract_ms"`
+	BytesChanged         int     `json:"bytes_changed"`
+	BitsFlipped          int     `json:"bits_flipped"`
+	PSNR                 float64 `json:"psnr"`
+	SNR                  float64 `json:"snr"`
+	LostFrames           int     `json:"lost_frames,omitempty"`
+	ChiSquareProbability float64 `json:"chi_square_probability"`
+	Error                string  `json:"error,omitempty"`
+}
+
+type CompareResponse struct {
+	Success            bool           `json:"success"`
+	Message            string         `json:"message"`
+	SecretSize         int            `json:"secret_size"`
+	Domain             string         `json:"domain"`
+	DecodeError        string         `json:"decode_error,omitempty"`
+	CoverChiSquare     float64        `json:"cover_chi_square_probability"`
+	Results            []CompareEntry `json:"results"`
+	SuccessfulConfigs  int            `json:"successful_configs"`
+	TotalRuntimeMillis float64        `json:"total_runtime_ms"`
+}
+
+type compareJob struct {
+	carrier  []byte
+	secret   []byte
+	original []byte
+
+	cover   *audio.PCM
+	stream  *audio.MP3Stream
+	decoded bool
+}
+
+func CompareHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		utils.SendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	err := r.ParseMultipartForm(100 << 20)
+	if err != nil {
+		utils.SendError(w, "Failed to parse form data", http.StatusBadRequest)
+		return
+	}
+
+	mp3Data, mp3Header, err := readUploadedFile(r, "mp3_file")
+	if err != nil {
+		utils.SendError(w, "MP3 file is required", http.StatusBadRequest)
+		return
+	}
+
+	secretData, secretHeader, err := readUploadedFile(r, "secret_file")
+	if err != nil {
+		utils.SendError(w, "Secret file is required", http.StatusBadRequest)
+		return
+	}
+
+	fileType := stego.DetectFileType(secretData, secretHeader.Filename)
+	var configs []CompareEntry
+	var methods []stego.Method
+	var configOpts []stego.EmbedOptions
+	for _, method := range stego.Methods() {
+		opts, _, err := readEmbedOptions(r, method)
+		if err != nil {
+			utils.SendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if (opts.Stealth || opts.Fill == stego.FillKeyed) && opts.Key == "" {
+			utils.SendError(w, keyRequiredMessage(method, opts), http.StatusBadRequest)
+			return
+		}
+		opts.OriginalFilename = secretHeader.Filename
+		opts.FileType = fileType
+		for _, bits := range stego.BitDepths(method) {
+			opts.Bits = bits
+			configs = append(configs, CompareEntry{Method: method.Name(), LSBBits: bits})
+			methods = append(methods, method)
+			configOpts = append(configOpts, opts)
+		}
+	}
+
+	// Compression and encryption do not depend on the method.
+	opts := configOpts[0]
+	job := compareJob{carrier: mp3Data, original: secretData, secret: secretData}
+	if opts.Compressed {
+		job.secret, err = stego.CompressMessage(job.secret)
+		if err != nil {
+			utils.SendError(w, "Failed to compress secret file", http.StatusInternalServerError)
+			return
+		}
+	}
+	if opts.UseEncryption && opts.Key != "" {
+		job.secret = crypto.VigenereEncrypt(job.secret, opts.Key)
+	}
+
+	started := time.Now()
+
+	response := CompareResponse{
+		Success:    true,
+		Message:    "Comparison completed",
+		SecretSize: len(secretData),
+		Domain:     domainPCM,
+	}
+
+	job.cover, job.stream, err = decodeCover(mp3Data)
+	job.decoded = err == nil
+	if err != nil {
+		response.Domain = domainBytes
+		response.DecodeError = err.Error()
+		job.cover = metrics.BytesAsPCM(mp3Data)
+	}
+
+	coverValues, _ := carrierValues(mp3Data)
+	response.CoverChiSquare = chiSquareScore(coverValues, 1)
+
+	var wg sync.WaitGroup
+	for i := range configs {
+		wg.Add(1)
+		go func(entry *CompareEntry, method stego.Method, opts stego.EmbedOptions) {
+			defer wg.Done()
+			job.run(entry, method, opts)
+		}(&configs[i], methods[i], configOpts[i])
+	}
+	wg.Wait()
+
+	for _, entry := range configs {
+		if entry.Success {
+			response.SuccessfulConfigs++
+		}
+	}
+	response.Results = configs
+	response.TotalRuntimeMillis = milliseconds(time.Since(started))
+	if response.SuccessfulConfigs == 0 {
+		response.Message = "No configuration could embed and recover the secret file"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+
+	log.Printf("Compare operation: mp3=%s, secret=%s, configs=%d, successful=%d, runtime=%.0fms",
+		mp3Header.Filename, secretHeader.Filename, len(configs), response.SuccessfulConfigs, response.TotalRuntimeMillis)
+}
+
+// run embeds, extracts and measures one configuration. Each configuration
+// runs in its own goroutine, so run only writes to entry.
+func (job compareJob) run(entry *CompareEntry, method stego.Method, opts stego.EmbedOptions) {
+	if requiresKey(method, opts) && opts.Key == "" {
+		entry.Error = keyRequiredMessage(method, opts)
+		return
+	}
+
+	capacity, err := method.Capacity(job.carrier, opts)
+	if err != nil {
+		entry.Error = err.Error()
+		return
+	}
+	entry.CapacityBytes = capacity
+	entry.Fits = len(job.secret) <= capacity
+	if !entry.Fits {
+		entry.Error = stego.ErrInsufficientCapacity.Error()
+		return
+	}
+
+	started := time.Now()
+	embedded, err := method.Embed(job.carrier, job.secret, opts)
+	entry.EmbedMillis = milliseconds(time.Since(started))
+	if err != nil {
+		entry.Error = err.Error()
+		return
+	}
+
+	started = time.Now()
+	check := verifyPayload(embedded, method, verifyTarget{role: "secret", key: opts.Key, expected: job.original})
+	entry.ExtractMillis = milliseconds(time.Since(started))
+	entry.Success = check.HashMatch
+	if check.Error != "" {
+		entry.Error = check.Error
+	} else if !check.HashMatch {
+		entry.Error = "extracted payload does not match the secret file"
+	}
+
+	counts := metrics.CompareBytes(job.carrier, embedded)
+	entry.BytesChanged = counts.ByteErrors
+	entry.BitsFlipped = counts.BitErrors
+
+	cover, modified := job.cover, metrics.BytesAsPCM(embedded)
+	if job.decoded {
+		decoded, lost, err := decodeStego(embedded, job.cover, job.stream)
+		if err == nil {
+			modified = decoded
+			entry.LostFrames = lost
+		} else {
+			cover = metrics.BytesAsPCM(job.carrier)
+		}
+	}
+	entry.PSNR = metrics.PSNR(cover.Samples, modified.Samples, 1)
+	entry.SNR = metrics.SNR(cover.Samples, modified.Samples)
+
+	values, _ := carrierValues(embedded)
+	entry.ChiSquareProbability = chiSquareScore(values, max(entry.LSBBits, 1))
+}
+
+// chiSquareScore is the mean window probability of the chi-square attack.
+func chiSquareScore(values []int, bits int) float64 {
+	result, err := steganalysis.ChiSquareWindows(values, steganalysis.ChiSquareOptions{
+		Bits:       bits,
+		WindowSize: compareChiSquareWindow,
+		Step:       compareChiSquareWindow,
+	})
+	if err != nil {
+		return 0
+	}
+	return result.MeanProbability
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
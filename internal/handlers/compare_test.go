@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestCompareHandler(t *testing.T) {
+	secret := []byte("compare me")
+	compare := func(t *testing.T, carrier []byte, fields map[string]string) map[string]CompareEntry {
+		t.Helper()
+		w := serveForm(t, CompareHandler, fields, map[string][]byte{"mp3_file": carrier, "secret_file": secret})
+		if w.Code != http.StatusOK {
+			t.Fatalf("status %d: %s", w.Code, w.Body)
+		}
+		var response CompareResponse
+		decodeResponse(t, w, &response)
+		entries := make(map[string]CompareEntry)
+		for _, entry := range response.Results {
+			if entry.LSBBits <= 1 {
+				entries[entry.Method] = entry
+			}
+		}
+		return entries
+	}
+
+	t.Run("without key", func(t *testing.T) {
+		entries := compare(t, testMP3(300, 8), nil)
+		for method, want := range map[string]string{
+			"lsb":     "Key is required for lsb steganography",
+			"chunked": "Key is required for chunked steganography",
+			"dsss":    "Key is required for dsss steganography",
+		} {
+			if entries[method].Error != want {
+				t.Errorf("%s: error %q, want %q", method, entries[method].Error, want)
+			}
+		}
+		if !entries["header"].Success {
+			t.Errorf("header: %+v, want success without a key", entries["header"])
+		}
+	})
+
+	t.Run("method options", func(t *testing.T) {
+		wav := testWAV(6, 8000, 9)
+		if entry := compare(t, wav, map[string]string{"key": "k"})["dsss"]; entry.Fits {
+			t.Errorf("dsss at the default chip rate fits: %+v", entry)
+		}
+		if entry := compare(t, wav, map[string]string{"key": "k", "chip_rate": "64", "strength": "0.02"})["dsss"]; !entry.Success {
+			t.Errorf("dsss at chip_rate=64, strength=0.02: %+v, want success", entry)
+		}
+	})
+
+	errorTests := []struct {
+		name   string
+		fields map[string]string
+		files  map[string][]byte
+	}{
+		{"no carrier", map[string]string{"key": "k"}, map[string][]byte{"secret_file": secret}},
+		{"no secret", map[string]string{"key": "k"}, map[string][]byte{"mp3_file": testMP3(20, 8)}},
+		{"invalid fill", map[string]string{"key": "k", "fill": "zeros"}, map[string][]byte{"mp3_file": testMP3(20, 8), "secret_file": secret}},
+		{"stealth without key", map[string]string{"stealth": "true"}, map[string][]byte{"mp3_file": testMP3(20, 8), "secret_file": secret}},
+		{"invalid chip rate", map[string]string{"key": "k", "chip_rate": "fast"}, map[string][]byte{"mp3_file": testMP3(20, 8), "secret_file": secret}},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := serveForm(t, CompareHandler, tt.fields, tt.files); w.Code != http.StatusBadRequest {
+				t.Errorf("status %d, want %d: %s", w.Code, http.StatusBadRequest, w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/stego"
+)
+
+// dryRunRegionGap merges changed bytes closer than this into one region, so
+// that a method touching every frame reports its span rather than one region
+// per frame.
+const dryRunRegionGap = 1024
+
+type DryRunResponse struct {
+	Success  bool                  `json:"success"`
+	Message  string                `json:"message"`
+	Method   string                `json:"method"`
+	LSBBits  int                   `json:"lsb_bits,omitempty"`
+	Payloads []DryRunPayload       `json:"payloads"`
+	Regions  []stego.RegionChanges `json:"regions"`
+	Changes  *DryRunChanges        `json:"changes,omitempty"`
+}
+
+type DryRunPayload struct {
+	Role           string `json:"role"`
+	SecretSize     int    `json:"secret_size"`
+	PayloadBytes   int    `json:"payload_bytes"`
+	CapacityBytes  int    `json:"capacity_bytes"`
+	CapacityMargin int    `json:"capacity_margin"`
+}
+
+// DryRunChanges counts what the embed changed in the carrier. It is omitted
+// when the payloads do not fit.
+type DryRunChanges struct {
+	CarrierBytes     int     `json:"carrier_bytes"`
+	BytesChanged     int     `json:"bytes_changed"`
+	BitsFlipped      int     `json:"bits_flipped"`
+	ModifiedFraction float64 `json:"modified_fraction"`
+}
+
+type dryRunJob struct {
+	method  stego.Method
+	carrier []byte
+	message []byte
+	opts    stego.EmbedOptions
+	dual    []stego.DualPayload
+}
+
+// sendDryRun embeds the job into a buffer that is thrown away and reports
+// the payload sizes and the regions the embed changed. It reports whether
+// the payloads fit; an error means nothing was written and the caller should
+// report it like a failed embed.
+func sendDryRun(w http.ResponseWriter, job dryRunJob) (bool, error) {
+	response := DryRunResponse{
+		Method:  job.method.Name(),
+		LSBBits: job.opts.Bits,
+	}
+
+	var embedded []byte
+	var embedErr error
+	if len(job.dual) == 2 {
+		lsbStego := stego.NewLSBSteganography()
+		for i, payload := range job.dual {
+			role := "decoy"
+			if i == 1 {
+				role = "secret"
+			}
+			capacity, err := lsbStego.DualCapacity(job.carrier, job.opts.Bits, payload.Key, payload.OriginalFilename, payload.FileType)
+			if err != nil {
+				return false, err
+			}
+			overhead, err := stego.PayloadOverhead(stego.EmbedOptions{
+				Key:              payload.Key,
+				Stealth:          true,
+				OriginalFilename: payload.OriginalFilename,
+				FileType:         payload.FileType,
+			})
+			if err != nil {
+				return false, err
+			}
+			response.Payloads = append(response.Payloads, dryRunPayload(role, len(payload.Message), overhead, capacity))
+		}
+		embedded, embedErr = lsbStego.EmbedDualPayload(job.carrier, job.dual[0], job.dual[1], job.opts.Bits)
+	} else {
+		capacity, err := job.method.Capacity(job.carrier, job.opts)
+		if err != nil {
+			return false, err
+		}
+		overhead, err := stego.MethodOverhead(job.method, len(job.message), job.opts)
+		if err != nil {
+			return false, err
+		}
+		response.Payloads = append(response.Payloads, dryRunPayload("secret", len(job.message), overhead, capacity))
+		embedded, embedErr = job.method.Embed(job.carrier, job.message, job.opts)
+	}
+	if embedErr != nil && embedErr != stego.ErrInsufficientCapacity {
+		return false, embedErr
+	}
+
+	fits := embedErr == nil
+	if fits {
+		regions, err := stego.ChangedRegions(job.carrier, embedded, dryRunRegionGap)
+		if err != nil {
+			return false, err
+		}
+		changes := &DryRunChanges{CarrierBytes: len(job.carrier)}
+		for _, region := range regions {
+			changes.BytesChanged += region.BytesChanged
+			changes.BitsFlipped += region.BitsFlipped
+		}
+		changes.ModifiedFraction = float64(changes.BytesChanged) / float64(changes.CarrierBytes)
+		response.Regions = regions
+		response.Changes = changes
+	}
+
+	response.Success = fits
+	response.Message = "Dry run completed: the secret file fits"
+	status := http.StatusOK
+	if !fits {
+		response.Message = "Dry run failed: " + stego.ErrInsufficientCapacity.Error()
+		status = http.StatusUnprocessableEntity
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+	return fits, nil
+}
+
+func dryRunPayload(role string, secretSize, overhead, capacity int) DryRunPayload {
+	return DryRunPayload{
+		Role:           role,
+		SecretSize:     secretSize,
+		PayloadBytes:   overhead + secretSize,
+		CapacityBytes:  capacity,
+		CapacityMargin: capacity - secretSize,
+	}
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/stego"
+)
+
+func TestEmbedDryRun(t *testing.T) {
+	mp3 := testMP3(1000, 2)
+	wav := testWAV(6, 8000, 3)
+	echoWAV := testWAV(50, 4000, 3)
+	secret := []byte("dry run secret")
+
+	tests := []struct {
+		name     string
+		fields   map[string]string
+		carrier  []byte
+		secret   []byte
+		decoy    []byte
+		status   int
+		payloads int
+	}{
+		{"lsb", map[string]string{"method": "lsb", "key": "k", "lsb_bits": "2"}, mp3, secret, nil, http.StatusOK, 1},
+		{"header", map[string]string{"method": "header"}, mp3, secret, nil, http.StatusOK, 1},
+		{"chunked", map[string]string{"method": "chunked", "key": "k"}, mp3, secret, nil, http.StatusOK, 1},
+		{"dsss", map[string]string{"method": "dsss", "key": "k", "chip_rate": "64", "strength": "0.02"}, wav, secret, nil, http.StatusOK, 1},
+		{"echo", map[string]string{"method": "echo"}, echoWAV, secret, nil, http.StatusOK, 1},
+		{"dual", map[string]string{"method": "lsb", "key": "k", "decoy_key": "d"}, mp3, secret, []byte("decoy"), http.StatusOK, 2},
+		{"too large", map[string]string{"method": "lsb", "key": "k"}, mp3, bytes.Repeat(secret, 10000), nil, http.StatusUnprocessableEntity, 1},
+		{"too large for any depth", map[string]string{"method": "lsb", "key": "k", "lsb_bits": "auto"}, mp3, bytes.Repeat(secret, 30000), nil,
+			http.StatusUnprocessableEntity, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fields["dry_run"] = "true"
+			files := map[string][]byte{"mp3_file": tt.carrier, "secret_file": tt.secret}
+			if tt.decoy != nil {
+				files["decoy_file"] = tt.decoy
+			}
+			w := serveForm(t, EmbedHandler, tt.fields, files)
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			var response DryRunResponse
+			decodeResponse(t, w, &response)
+			if len(response.Payloads) != tt.payloads {
+				t.Fatalf("%d payloads, want %d", len(response.Payloads), tt.payloads)
+			}
+
+			if tt.status != http.StatusOK {
+				if response.Success || response.Changes != nil || response.Payloads[0].CapacityMargin >= 0 {
+					t.Errorf("success = %v, changes = %+v, margin = %d; want a failure with a negative margin",
+						response.Success, response.Changes, response.Payloads[0].CapacityMargin)
+				}
+				return
+			}
+			if !response.Success || response.Changes == nil || response.Changes.BytesChanged == 0 || len(response.Regions) == 0 {
+				t.Fatalf("success = %v, changes = %+v, %d regions; want changes", response.Success, response.Changes, len(response.Regions))
+			}
+			changed, flipped := 0, 0
+			for _, region := range response.Regions {
+				if region.Start >= region.End || region.End > len(tt.carrier) {
+					t.Errorf("region %+v outside the carrier", region)
+				}
+				changed += region.BytesChanged
+				flipped += region.BitsFlipped
+			}
+			if changed != response.Changes.BytesChanged || flipped != response.Changes.BitsFlipped {
+				t.Errorf("regions count %d bytes and %d bits, changes %+v", changed, flipped, *response.Changes)
+			}
+		})
+	}
+}
+
+func TestEmbedDryRunChunkedOverhead(t *testing.T) {
+	secret := bytes.Repeat([]byte("chunk"), 100)
+	w := serveForm(t, EmbedHandler, map[string]string{"method": "chunked", "key": "k", "dry_run": "true"},
+		map[string][]byte{"mp3_file": testMP3(200, 4), "secret_file": secret})
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var response DryRunResponse
+	decodeResponse(t, w, &response)
+
+	opts := stego.EmbedOptions{
+		Bits:             1,
+		Key:              "k",
+		OriginalFilename: "secret_file.bin",
+		FileType:         stego.DetectFileType(secret, "secret_file.bin"),
+	}
+	want, err := stego.MethodOverhead(stego.NewChunkedSteganography(), len(secret), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lsbOverhead, _ := stego.PayloadOverhead(opts)
+	if got := response.Payloads[0].PayloadBytes - len(secret); got != want || got <= lsbOverhead {
+		t.Errorf("chunked overhead %d, want %d (more than the container's %d)", got, want, lsbOverhead)
+	}
+}
+
+func TestEmbedDryRunErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields map[string]string
+		files  map[string][]byte
+		status int
+	}{
+		{"no secret", map[string]string{"method": "lsb", "key": "k"}, map[string][]byte{"mp3_file": testMP3(20, 5)}, http.StatusBadRequest},
+		{"no key", map[string]string{"method": "lsb"}, map[string][]byte{"mp3_file": testMP3(20, 5), "secret_file": []byte("x")}, http.StatusBadRequest},
+		{"dsss on mp3", map[string]string{"method": "dsss", "key": "k"}, map[string][]byte{"mp3_file": testMP3(20, 5), "secret_file": []byte("x")},
+			http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fields["dry_run"] = "true"
+			if w := serveForm(t, EmbedHandler, tt.fields, tt.files); w.Code != tt.status {
+				t.Errorf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+		})
+	}
+}
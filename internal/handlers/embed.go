@@ -8,7 +8,9 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/audio"
 	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/crypto"
 	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/stego"
 	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/utils"
@@ -26,26 +28,40 @@ func EmbedHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	key := r.FormValue("key")
-	useEncryption := r.FormValue("use_encryption") == "true"
-	useKeyForPosition := r.FormValue("use_key_for_position") == "true"
 	method := r.FormValue("method")
 	if method == "" {
 		method = "lsb"
 	}
+	stegoMethod, ok := stego.LookupMethod(method)
+	if !ok {
+		utils.SendError(w, "Unknown steganography method: "+method, http.StatusBadRequest)
+		return
+	}
+	verifyMode := r.FormValue("verify")
+	switch verifyMode {
+	case "", "false":
+		verifyMode = ""
+	case "true", "report":
+	default:
+		utils.SendError(w, "Invalid verify mode: use true or report", http.StatusBadRequest)
+		return
+	}
+	dryRun := r.FormValue("dry_run") == "true" || r.URL.Query().Get("dry_run") == "true"
+	reportMode := r.FormValue("report")
+	if reportMode != "" && reportMode != "json" {
+		utils.SendError(w, "Invalid report format: use json", http.StatusBadRequest)
+		return
+	}
 
-	var lsbBits int
-	if method == "lsb" {
-		lsbBitsStr := r.FormValue("lsb_bits")
-		lsbBits, err = strconv.Atoi(lsbBitsStr)
-		if err != nil || lsbBits < 1 || lsbBits > 4 {
-			lsbBits = 1
-		}
-
-		if key == "" {
-			utils.SendError(w, "Key is required for LSB steganography", http.StatusBadRequest)
-			return
-		}
+	opts, autoBits, err := readEmbedOptions(r, stegoMethod)
+	if err != nil {
+		utils.SendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	key := opts.Key
+	if requiresKey(stegoMethod, opts) && key == "" {
+		utils.SendError(w, keyRequiredMessage(stegoMethod, opts), http.StatusBadRequest)
+		return
 	}
 
 	mp3File, mp3Header, err := r.FormFile("mp3_file")
@@ -109,41 +125,224 @@ func EmbedHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if useEncryption && key != "" {
+	decoyData, decoyHeader, decoyErr := readUploadedFile(r, "decoy_file")
+	decoyKey := r.FormValue("decoy_key")
+	useDecoy := decoyErr == nil
+	if useDecoy {
+		if method != "lsb" {
+			utils.SendError(w, "Decoy payloads are only supported by LSB steganography", http.StatusBadRequest)
+			return
+		}
+		if decoyKey == "" || decoyKey == key {
+			utils.SendError(w, "A decoy key different from the secret key is required", http.StatusBadRequest)
+			return
+		}
+	}
+
+	opts.OriginalFilename = secretHeader.Filename
+	opts.FileType = stego.DetectFileType(secretData, secretHeader.Filename)
+	originalSecret := secretData
+	originalDecoy := decoyData
+
+	if opts.Compressed {
+		secretData, err = stego.CompressMessage(secretData)
+		if err != nil {
+			utils.SendError(w, "Failed to compress secret file", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if opts.UseEncryption && key != "" {
 		log.Printf("Applying encryption to secret data")
 		secretData = crypto.VigenereEncrypt(secretData, key)
 	}
 
 	var embeddedData []byte
-	if method == "header" {
-		headerStego := stego.NewHeaderSteganography()
-		embeddedData, err = headerStego.EmbedMessage(mp3Data, secretData, secretHeader.Filename)
-	} else {
-		fileType := stego.DetectFileType(secretData, secretHeader.Filename)
-
-		lsbStego := stego.NewLSBSteganography()
-		embeddedData, err = lsbStego.EmbedMessageWithMetadata(
-			mp3Data,
-			secretData,
-			lsbBits,
-			key,
-			useKeyForPosition,
-			useEncryption,
-			secretHeader.Filename,
-			fileType,
-		)
+	var dualPayloads []stego.DualPayload
+	if useDecoy {
+		decoyFileType := stego.DetectFileType(decoyData, decoyHeader.Filename)
+		if opts.Compressed {
+			decoyData, err = stego.CompressMessage(decoyData)
+			if err != nil {
+				utils.SendError(w, "Failed to compress decoy file", http.StatusInternalServerError)
+				return
+			}
+		}
+		if opts.UseEncryption {
+			decoyData = crypto.VigenereEncrypt(decoyData, decoyKey)
+		}
+
+		decoy := stego.DualPayload{
+			Message:          decoyData,
+			Key:              decoyKey,
+			OriginalFilename: decoyHeader.Filename,
+			FileType:         decoyFileType,
+			UseEncryption:    opts.UseEncryption,
+			Compressed:       opts.Compressed,
+		}
+		secret := stego.DualPayload{
+			Message:          secretData,
+			Key:              key,
+			OriginalFilename: opts.OriginalFilename,
+			FileType:         opts.FileType,
+			UseEncryption:    opts.UseEncryption,
+			Compressed:       opts.Compressed,
+		}
+
+		dualPayloads = []stego.DualPayload{decoy, secret}
+
+		// Dual payloads are always sealed and surrounded by random fill.
+		opts.UseKeyForPosition = false
+		opts.Stealth = true
+		opts.Fill = stego.FillRandom
+
+		if autoBits {
+			var selected int
+			selected, err = stego.NewLSBSteganography().SelectDualBits(mp3Data, decoy, secret)
+			if err == nil {
+				opts.Bits = selected
+			}
+		}
+	} else if autoBits {
+		var selected int
+		selected, err = stego.SelectBits(stegoMethod, mp3Data, len(secretData), opts)
+		if err == nil {
+			opts.Bits = selected
+		}
+	}
+
+	// A dry run embeds into a discarded buffer: when no bit depth fits it
+	// still reports the margins at the default depth.
+	if dryRun && (err == nil || err == stego.ErrInsufficientCapacity) {
+		var fits bool
+		fits, err = sendDryRun(w, dryRunJob{
+			method:  stegoMethod,
+			carrier: mp3Data,
+			message: secretData,
+			opts:    opts,
+			dual:    dualPayloads,
+		})
+		if err == nil {
+			log.Printf("Embed dry run: method=%s, mp3=%s, secret=%s, fits=%t",
+				method, mp3Header.Filename, secretHeader.Filename, fits)
+			return
+		}
+	}
+
+	if err == nil && useDecoy {
+		embeddedData, err = stego.NewLSBSteganography().EmbedDualPayload(mp3Data, dualPayloads[0], dualPayloads[1], opts.Bits)
+	} else if err == nil {
+		embeddedData, err = stegoMethod.Embed(mp3Data, secretData, opts)
+	}
+
+	if err == stego.ErrWAVRequired || err == stego.ErrInvalidChipRate || err == stego.ErrInvalidStrength || err == stego.ErrInvalidEchoAmplitude ||
+		err == stego.ErrCarrierTooLoud {
+		utils.SendError(w, "Failed to embed secret data: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err == stego.ErrInsufficientCapacity {
+		overhead, _ := stego.PayloadOverhead(opts)
+		utils.SendError(w, "Failed to embed secret data: "+err.Error()+rateHint(stegoMethod, mp3Data, opts, overhead+len(secretData)), http.StatusBadRequest)
+		return
 	}
 	if err != nil {
 		utils.SendError(w, "Failed to embed secret data: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set("Content-Type", "audio/mpeg")
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"stego_%s\"", mp3Header.Filename))
-	w.Header().Set("Content-Length", strconv.Itoa(len(embeddedData)))
+	if opts.Bits > 0 {
+		w.Header().Set("X-LSB-Bits", strconv.Itoa(opts.Bits))
+	}
+	w.Header().Set("X-Used-Compression", strconv.FormatBool(opts.Compressed))
+	setDistributionHeaders(w, "Before", measureDistribution(stegoMethod, mp3Data, opts.Bits))
+	setDistributionHeaders(w, "After", measureDistribution(stegoMethod, embeddedData, opts.Bits))
+
+	stegoFilename := "stego_" + mp3Header.Filename
 
-	w.Write(embeddedData)
+	var verifyReport *VerifyReport
+	if verifyMode != "" {
+		targets := []verifyTarget{{role: "secret", key: key, expected: originalSecret}}
+		if useDecoy {
+			targets = append(targets, verifyTarget{role: "decoy", key: decoyKey, expected: originalDecoy})
+		}
+
+		verifyReport = verifyEmbedding(mp3Data, embeddedData, stegoMethod, opts.Bits, targets)
+		w.Header().Set("X-Verified", strconv.FormatBool(verifyReport.Verified))
+		framesIntact := "not-applicable"
+		if verifyReport.FramesIntact != nil {
+			framesIntact = strconv.FormatBool(*verifyReport.FramesIntact)
+		}
+		w.Header().Set("X-Frames-Intact", framesIntact)
+	}
+
+	var embedReport *stego.EmbedReport
+	if reportMode == "json" {
+		embedReport, err = stego.BuildEmbedReport(stegoMethod, mp3Data, embeddedData, len(secretData), opts)
+		if err != nil {
+			utils.SendError(w, "Failed to build embedding report: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if verifyReport != nil && (!verifyReport.Verified || verifyMode == "report") {
+		sendVerifyReport(w, verifyReport, embedReport)
+		return
+	}
+
+	if verifyReport != nil || embedReport != nil {
+		parts := []responsePart{
+			{Name: "stego_file", Filename: stegoFilename, ContentType: carrierContentType(embeddedData), Data: embeddedData},
+		}
+		if verifyReport != nil {
+			part, err := jsonPart("verify_report", verifyReport)
+			if err != nil {
+				utils.SendError(w, "Failed to encode verification report", http.StatusInternalServerError)
+				return
+			}
+			parts = append(parts, part)
+		}
+		if embedReport != nil {
+			part, err := jsonPart("embed_report", embedReport)
+			if err != nil {
+				utils.SendError(w, "Failed to encode embedding report", http.StatusInternalServerError)
+				return
+			}
+			parts = append(parts, part)
+		}
+
+		writeMultipartResponse(w, parts)
+	} else {
+		w.Header().Set("Content-Type", carrierContentType(embeddedData))
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", stegoFilename))
+		w.Header().Set("Content-Length", strconv.Itoa(len(embeddedData)))
+
+		w.Write(embeddedData)
+	}
+
+	log.Printf("Embed operation: method=%s, mp3=%s, secret=%s, stealth=%t, decoy=%t, verify=%s, report=%s",
+		method, mp3Header.Filename, secretHeader.Filename, opts.Stealth, useDecoy, verifyMode, reportMode)
+}
+
+// carrierContentType names the media type of a stego file, which has the
+// same format as its carrier.
+func carrierContentType(carrier []byte) string {
+	if audio.IsWAV(carrier) {
+		return "audio/wav"
+	}
+	return "audio/mpeg"
+}
+
+func setDistributionHeaders(w http.ResponseWriter, suffix string, dist *stego.LSBDistribution) {
+	if dist == nil {
+		return
+	}
+
+	histogram := make([]string, len(dist.Histogram))
+	for i, count := range dist.Histogram {
+		histogram[i] = strconv.Itoa(count)
+	}
 
-	log.Printf("Embed operation: method=%s, mp3=%s, secret=%s",
-		method, mp3Header.Filename, secretHeader.Filename)
+	w.Header().Set("X-LSB-Histogram-"+suffix, strings.Join(histogram, ","))
+	w.Header().Set("X-LSB-Ones-Ratio-"+suffix, strconv.FormatFloat(dist.OnesRatio, 'f', 6, 64))
+	w.Header().Set("X-LSB-Entropy-"+suffix, strconv.FormatFloat(dist.Entropy, 'f', 6, 64))
 }
@@ -29,7 +29,7 @@ func ExtractHandler(w http.ResponseWriter, r *http.Request) {
 	key := r.FormValue("key")
 	method := r.FormValue("method")
 	if method == "" {
-		method = "lsb"
+		method = "auto"
 	}
 
 	mp3File, mp3Header, err := r.FormFile("mp3_file")
@@ -64,65 +64,77 @@ func ExtractHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var extractedData []byte
-	var originalFilename string
-	var fileType string
-	var metadata *stego.EmbedMetadata
-
-	if method == "header" {
-		headerStego := stego.NewHeaderSteganography()
-		extractedData, err = headerStego.ExtractMessage(mp3Data)
-		if err != nil {
-			utils.SendError(w, "Failed to extract secret data: "+err.Error(), http.StatusInternalServerError)
+	var result *stego.ExtractResult
+	if method == "auto" {
+		result, err = stego.DetectAndExtract(mp3Data, key)
+	} else {
+		stegoMethod, ok := stego.LookupMethod(method)
+		if !ok {
+			utils.SendError(w, "Unknown steganography method: "+method, http.StatusBadRequest)
 			return
 		}
 
-		originalFilename = "extracted_secret"
-		fileType = http.DetectContentType(extractedData)
-	} else {
-		lsbStego := stego.NewLSBSteganography()
-		result, err := lsbStego.ExtractMessageWithMetadata(mp3Data, key)
-		if err != nil {
-			var errorMsg string
-			var statusCode int
-
-			switch err {
-			case stego.ErrWrongKey:
-				errorMsg = "Incorrect key provided. Please check your key and try again. If the file was embedded with encryption, you must provide the correct key used during embedding."
-				statusCode = http.StatusBadRequest
-			case stego.ErrNoSteganographicData:
-				errorMsg = "No steganographic data found in this MP3 file. Please make sure you uploaded the correct file that contains embedded data."
-				statusCode = http.StatusBadRequest
-			case stego.ErrInvalidMetadata:
-				errorMsg = "Invalid or corrupted steganographic data found. The file may be damaged or not properly embedded."
-				statusCode = http.StatusBadRequest
-			case stego.ErrInvalidMP3Format:
-				errorMsg = "Invalid MP3 file format. Please upload a valid MP3 file."
-				statusCode = http.StatusBadRequest
-			default:
-				errorMsg = "Failed to extract secret data: " + err.Error()
-				statusCode = http.StatusInternalServerError
-			}
-
-			utils.SendError(w, errorMsg, statusCode)
-			return
+		result, err = stegoMethod.Extract(mp3Data, key)
+		if err == nil {
+			result.Method = stegoMethod.Name()
+		}
+	}
+	if err == stego.ErrIncompletePayload {
+		sendPartialRecovery(w, mp3Data, key)
+		log.Printf("Extract operation: method=%s, mp3=%s, partial payload recovered", method, mp3Header.Filename)
+		return
+	}
+	if err != nil {
+		var errorMsg string
+		var statusCode int
+
+		switch err {
+		case stego.ErrWrongKey:
+			errorMsg = "Incorrect key provided. Please check your key and try again. If the file was embedded with encryption, you must provide the correct key used during embedding."
+			statusCode = http.StatusBadRequest
+		case stego.ErrNoSteganographicData:
+			errorMsg = "No steganographic data found in this MP3 file for the given key. Please make sure you uploaded the correct file and provided the key used during embedding."
+			statusCode = http.StatusBadRequest
+		case stego.ErrUnsupportedVersion:
+			errorMsg = "Steganographic data was written by a newer version of this tool and cannot be read."
+			statusCode = http.StatusBadRequest
+		case stego.ErrInvalidMetadata:
+			errorMsg = "Invalid or corrupted steganographic data found. The file may be damaged or not properly embedded."
+			statusCode = http.StatusBadRequest
+		case stego.ErrInvalidMP3Format, stego.ErrNoValidFrames:
+			errorMsg = "Invalid MP3 file format. Please upload a valid MP3 file."
+			statusCode = http.StatusBadRequest
+		case stego.ErrWAVRequired:
+			errorMsg = "The " + method + " method only works on PCM WAV files. Please upload the WAV file produced during embedding."
+			statusCode = http.StatusBadRequest
+		default:
+			errorMsg = "Failed to extract secret data: " + err.Error()
+			statusCode = http.StatusInternalServerError
 		}
 
-		extractedData = result.Message
-		metadata = result.Metadata
-		originalFilename = result.OriginalFilename
-		fileType = result.FileType
+		utils.SendError(w, errorMsg, statusCode)
+		return
+	}
 
-		if metadata.UseEncryption && key != "" {
-			log.Printf("Applying decryption based on metadata")
-			extractedData = crypto.VigenereDecrypt(extractedData, key)
-		}
+	var extractedData []byte
+	metadata := result.Metadata
+	originalFilename := result.OriginalFilename
+	fileType := result.FileType
 
-		log.Printf("Extracted with metadata: filename=%s, type=%s, size=%d, encryption=%t, keyPos=%t, lsbBits=%d",
-			originalFilename, fileType, metadata.SecretMessageSize, metadata.UseEncryption,
-			metadata.UseKeyForPosition, metadata.LSBBits)
+	if metadata.UseEncryption && key != "" {
+		log.Printf("Applying decryption based on metadata")
 	}
 
+	extractedData, err = decodeExtractedMessage(result, key)
+	if err != nil {
+		utils.SendError(w, "Failed to decompress extracted data (wrong key?)", http.StatusBadRequest)
+		return
+	}
+
+	log.Printf("Extracted with metadata: method=%s, filename=%s, type=%s, size=%d, encryption=%t, keyPos=%t, lsbBits=%d",
+		result.Method, originalFilename, fileType, metadata.SecretMessageSize, metadata.UseEncryption,
+		metadata.UseKeyForPosition, metadata.LSBBits)
+
 	if originalFilename == "" {
 		originalFilename = "extracted_secret"
 	}
@@ -136,16 +148,73 @@ func ExtractHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", originalFilename))
 	w.Header().Set("Content-Length", strconv.Itoa(len(extractedData)))
 
-	if metadata != nil {
-		w.Header().Set("X-Original-Filename", originalFilename)
-		w.Header().Set("X-File-Type", fileType)
-		w.Header().Set("X-Secret-Size", strconv.Itoa(metadata.SecretMessageSize))
-		w.Header().Set("X-Used-Encryption", strconv.FormatBool(metadata.UseEncryption))
-		w.Header().Set("X-Used-Key-Position", strconv.FormatBool(metadata.UseKeyForPosition))
-		w.Header().Set("X-LSB-Bits", strconv.Itoa(metadata.LSBBits))
-	}
+	w.Header().Set("X-Method", result.Method)
+	w.Header().Set("X-Method-Detected", strconv.FormatBool(method == "auto"))
+	w.Header().Set("X-Original-Filename", originalFilename)
+	w.Header().Set("X-File-Type", fileType)
+	w.Header().Set("X-Secret-Size", strconv.Itoa(metadata.SecretMessageSize))
+	w.Header().Set("X-Used-Encryption", strconv.FormatBool(metadata.UseEncryption))
+	w.Header().Set("X-Used-Key-Position", strconv.FormatBool(metadata.UseKeyForPosition))
+	w.Header().Set("X-LSB-Bits", strconv.Itoa(metadata.LSBBits))
+	w.Header().Set("X-Used-Stealth", strconv.FormatBool(metadata.Stealth))
+	w.Header().Set("X-Used-Compression", strconv.FormatBool(metadata.Compressed))
 
 	w.Write(extractedData)
 
-	log.Printf("Extract operation: method=%s, mp3=%s, extracted=%s", method, mp3Header.Filename, originalFilename)
+	log.Printf("Extract operation: method=%s, detected=%s, mp3=%s, extracted=%s", method, result.Method, mp3Header.Filename, originalFilename)
+}
+
+func decodeExtractedMessage(result *stego.ExtractResult, key string) ([]byte, error) {
+	data := result.Message
+	if result.Metadata.UseEncryption && key != "" {
+		data = crypto.VigenereDecrypt(data, key)
+	}
+
+	if result.Metadata.Compressed {
+		return stego.DecompressMessage(data)
+	}
+
+	return data, nil
+}
+
+// sendPartialRecovery answers with whatever a cropped chunked carrier still
+// holds: the surviving message bytes (zeros where chunks are missing) and a
+// JSON report mapping the missing ranges.
+func sendPartialRecovery(w http.ResponseWriter, mp3Data []byte, key string) {
+	recovery, err := stego.NewChunkedSteganography().Recover(mp3Data, key)
+	if err != nil {
+		utils.SendError(w, "Failed to recover chunks: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	data := recovery.Payload
+	name, filename := "partial_payload", "partial_payload.bin"
+	if metadata := recovery.Metadata; metadata != nil {
+		data = recovery.Message
+		if metadata.UseEncryption && !metadata.Compressed && key != "" {
+			data = crypto.VigenereDecrypt(data, key)
+			for _, missing := range recovery.MessageMissing {
+				clear(data[missing.Start:missing.End])
+			}
+		}
+		name, filename = "partial_file", "partial_extracted_secret"
+		if metadata.OriginalFilename != "" {
+			filename = "partial_" + metadata.OriginalFilename
+		}
+	}
+
+	report, err := jsonPart("chunk_report", recovery)
+	if err != nil {
+		utils.SendError(w, "Failed to encode chunk report", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("X-Method", "chunked")
+	w.Header().Set("X-Payload-Complete", "false")
+	w.Header().Set("X-Chunks-Recovered", strconv.Itoa(len(recovery.Chunks)))
+	w.Header().Set("X-Chunks-Total", strconv.Itoa(recovery.TotalChunks))
+	writeMultipartResponseStatus(w, http.StatusPartialContent, []responsePart{
+		{Name: name, Filename: filename, ContentType: "application/octet-stream", Data: data},
+		report,
+	})
 }
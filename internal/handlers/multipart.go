@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strconv"
+)
+
+type responsePart struct {
+	Name        string
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+func jsonPart(name string, value interface{}) (responsePart, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return responsePart{}, err
+	}
+
+	return responsePart{
+		Name:        name,
+		Filename:    name + ".json",
+		ContentType: "application/json",
+		Data:        data,
+	}, nil
+}
+
+func writeMultipartResponse(w http.ResponseWriter, parts []responsePart) error {
+	return writeMultipartResponseStatus(w, http.StatusOK, parts)
+}
+
+func writeMultipartResponseStatus(w http.ResponseWriter, status int, parts []responsePart) error {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	for _, part := range parts {
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Type", part.ContentType)
+		header.Set("Content-Disposition", fmt.Sprintf("attachment; name=%q; filename=%q", part.Name, part.Filename))
+
+		partWriter, err := writer.CreatePart(header)
+		if err != nil {
+			return err
+		}
+		if _, err := partWriter.Write(part.Data); err != nil {
+			return err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "multipart/mixed; boundary="+writer.Boundary())
+	w.Header().Set("Content-Length", strconv.Itoa(body.Len()))
+	w.WriteHeader(status)
+	_, err := w.Write(body.Bytes())
+	return err
+}
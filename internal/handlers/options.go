@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/stego"
+)
+
+var (
+	errInvalidFillMode      = errors.New("Invalid fill mode: use none, random or keyed")
+	errInvalidSpread        = errors.New("chip_rate must be an integer and strength a number")
+	errInvalidEchoAmplitude = errors.New("echo_amplitude must be a number")
+)
+
+// readEmbedOptions parses the embedding fields of a form for method. Fields a
+// method does not use are left at their zero value: lsb_bits is only read by
+// methods with bit depths, use_key_for_position by lsb and header, chip_rate
+// and strength by dsss and echo_amplitude by echo. The second result reports
+// lsb_bits=auto, in which case Bits is left for the caller to select.
+func readEmbedOptions(r *http.Request, method stego.Method) (stego.EmbedOptions, bool, error) {
+	fillMode, err := stego.ParseFillMode(r.FormValue("fill"))
+	if err != nil {
+		return stego.EmbedOptions{}, false, errInvalidFillMode
+	}
+
+	opts := stego.EmbedOptions{
+		Key:           r.FormValue("key"),
+		UseEncryption: r.FormValue("use_encryption") == "true",
+		Stealth:       r.FormValue("stealth") == "true",
+		Fill:          fillMode,
+		Compressed:    r.FormValue("compress") == "true",
+	}
+
+	switch method.Name() {
+	case "lsb", "header":
+		opts.UseKeyForPosition = r.FormValue("use_key_for_position") == "true"
+	case "dsss":
+		var ok bool
+		opts.ChipRate, opts.Strength, ok = readSpreadOptions(r)
+		if !ok {
+			return stego.EmbedOptions{}, false, errInvalidSpread
+		}
+	case "echo":
+		var ok bool
+		opts.Strength, ok = formFloat(r, "echo_amplitude", 0)
+		if !ok {
+			return stego.EmbedOptions{}, false, errInvalidEchoAmplitude
+		}
+	}
+
+	autoBits := false
+	if stego.BitDepths(method)[0] > 0 {
+		lsbBits := r.FormValue("lsb_bits")
+		autoBits = lsbBits == "auto"
+		opts.Bits, err = strconv.Atoi(lsbBits)
+		if err != nil || opts.Bits < 1 || opts.Bits > 4 {
+			opts.Bits = 1
+		}
+	}
+
+	return opts, autoBits, nil
+}
+
+// readSpreadOptions parses the spread-spectrum chip_rate and strength fields;
+// zero leaves the choice to the method's defaults.
+func readSpreadOptions(r *http.Request) (int, float64, bool) {
+	chipRate, ok := formInt(r, "chip_rate", 0)
+	if !ok {
+		return 0, 0, false
+	}
+	strength, ok := formFloat(r, "strength", 0)
+	return chipRate, strength, ok
+}
+
+// requiresKey reports whether embedding with method and opts needs a key:
+// lsb, chunked and dsss derive their positions or chips from it, and stealth
+// mode and keyed fill need one with every method. header and echo work
+// without one; echo seals its payload when a key is given.
+func requiresKey(method stego.Method, opts stego.EmbedOptions) bool {
+	if opts.Stealth || opts.Fill == stego.FillKeyed {
+		return true
+	}
+	switch method.(type) {
+	case *stego.LSBSteganography, *stego.ChunkedSteganography, *stego.SpreadSpectrumSteganography:
+		return true
+	}
+	return false
+}
+
+// keyRequiredMessage explains why requiresKey asked for a key.
+func keyRequiredMessage(method stego.Method, opts stego.EmbedOptions) string {
+	if opts.Stealth || opts.Fill == stego.FillKeyed {
+		return "Key is required for stealth mode and keyed fill"
+	}
+	return "Key is required for " + method.Name() + " steganography"
+}
+
+// measureDistribution returns the distribution of the bits method writes in
+// carrier, or nil for methods that do not write discrete bits.
+func measureDistribution(method stego.Method, carrier []byte, bits int) *stego.LSBDistribution {
+	switch m := method.(type) {
+	case *stego.LSBSteganography:
+		return m.MeasureDistribution(carrier, bits)
+	case *stego.ChunkedSteganography:
+		return m.MeasureDistribution(carrier, bits)
+	case *stego.HeaderSteganography:
+		return m.MeasureDistribution(carrier)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/stego"
+)
+
+func formRequest(fields map[string]string) *http.Request {
+	values := url.Values{}
+	for name, value := range fields {
+		values.Set(name, value)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(values.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return r
+}
+
+func TestReadEmbedOptions(t *testing.T) {
+	lsb, _ := stego.LookupMethod("lsb")
+	header, _ := stego.LookupMethod("header")
+	dsss, _ := stego.LookupMethod("dsss")
+	echo, _ := stego.LookupMethod("echo")
+
+	tests := []struct {
+		name   string
+		method stego.Method
+		fields map[string]string
+		want   stego.EmbedOptions
+		auto   bool
+		err    error
+	}{
+		{"lsb defaults", lsb, nil, stego.EmbedOptions{Bits: 1}, false, nil},
+		{"lsb", lsb, map[string]string{"key": "k", "lsb_bits": "3", "use_key_for_position": "true", "compress": "true", "fill": "keyed"},
+			stego.EmbedOptions{Bits: 3, Key: "k", UseKeyForPosition: true, Compressed: true, Fill: stego.FillKeyed}, false, nil},
+		{"lsb auto", lsb, map[string]string{"lsb_bits": "auto"}, stego.EmbedOptions{Bits: 1}, true, nil},
+		{"lsb out of range", lsb, map[string]string{"lsb_bits": "7"}, stego.EmbedOptions{Bits: 1}, false, nil},
+		{"header ignores depth and spread", header, map[string]string{"lsb_bits": "3", "chip_rate": "64", "use_key_for_position": "true"},
+			stego.EmbedOptions{UseKeyForPosition: true}, false, nil},
+		{"dsss", dsss, map[string]string{"chip_rate": "64", "strength": "0.01", "use_key_for_position": "true"},
+			stego.EmbedOptions{ChipRate: 64, Strength: 0.01}, false, nil},
+		{"echo", echo, map[string]string{"echo_amplitude": "0.3", "strength": "0.01"}, stego.EmbedOptions{Strength: 0.3}, false, nil},
+		{"invalid fill", lsb, map[string]string{"fill": "zeros"}, stego.EmbedOptions{}, false, errInvalidFillMode},
+		{"invalid chip rate", dsss, map[string]string{"chip_rate": "-1"}, stego.EmbedOptions{}, false, errInvalidSpread},
+		{"invalid strength", dsss, map[string]string{"strength": "strong"}, stego.EmbedOptions{}, false, errInvalidSpread},
+		{"invalid echo amplitude", echo, map[string]string{"echo_amplitude": "loud"}, stego.EmbedOptions{}, false, errInvalidEchoAmplitude},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, auto, err := readEmbedOptions(formRequest(tt.fields), tt.method)
+			if err != tt.err {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if opts != tt.want || auto != tt.auto {
+				t.Errorf("got %+v (auto %v), want %+v (auto %v)", opts, auto, tt.want, tt.auto)
+			}
+		})
+	}
+}
+
+func TestRequiresKey(t *testing.T) {
+	tests := []struct {
+		method string
+		opts   stego.EmbedOptions
+		want   bool
+	}{
+		{"lsb", stego.EmbedOptions{}, true},
+		{"header", stego.EmbedOptions{}, false},
+		{"chunked", stego.EmbedOptions{}, true},
+		{"dsss", stego.EmbedOptions{}, true},
+		{"echo", stego.EmbedOptions{}, false},
+		{"header", stego.EmbedOptions{Stealth: true}, true},
+		{"echo", stego.EmbedOptions{Fill: stego.FillKeyed}, true},
+		{"header", stego.EmbedOptions{Fill: stego.FillRandom}, false},
+	}
+	for _, tt := range tests {
+		method, _ := stego.LookupMethod(tt.method)
+		if got := requiresKey(method, tt.opts); got != tt.want {
+			t.Errorf("requiresKey(%s, %+v) = %v, want %v", tt.method, tt.opts, got, tt.want)
+		}
+	}
+}
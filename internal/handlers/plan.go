@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/stego"
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/utils"
+)
+
+type PlanResponse struct {
+	Success     bool              `json:"success"`
+	Message     string            `json:"message"`
+	SecretSize  int               `json:"secret_size"`
+	Options     []stego.PlanEntry `json:"options"`
+	Recommended *stego.PlanEntry  `json:"recommended"`
+}
+
+func PlanHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		utils.SendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	err := r.ParseMultipartForm(100 << 20)
+	if err != nil {
+		utils.SendError(w, "Failed to parse form data", http.StatusBadRequest)
+		return
+	}
+
+	mp3Data, mp3Header, err := readUploadedFile(r, "mp3_file")
+	if err != nil {
+		utils.SendError(w, "MP3 file is required", http.StatusBadRequest)
+		return
+	}
+
+	secretData, secretHeader, err := readUploadedFile(r, "secret_file")
+	if err != nil {
+		utils.SendError(w, "Secret file is required", http.StatusBadRequest)
+		return
+	}
+
+	fileType := stego.DetectFileType(secretData, secretHeader.Filename)
+	methodOpts := make(map[string]stego.EmbedOptions)
+	for _, method := range stego.Methods() {
+		opts, _, err := readEmbedOptions(r, method)
+		if err != nil {
+			utils.SendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if (opts.Stealth || opts.Fill == stego.FillKeyed) && opts.Key == "" {
+			utils.SendError(w, "Key is required for stealth mode and keyed fill", http.StatusBadRequest)
+			return
+		}
+		opts.OriginalFilename = secretHeader.Filename
+		opts.FileType = fileType
+		methodOpts[method.Name()] = opts
+	}
+
+	// Without a compress field both settings are planned.
+	compressions := []bool{false, true}
+	if r.FormValue("compress") != "" {
+		compressions = []bool{r.FormValue("compress") == "true"}
+	}
+
+	entries, err := stego.PlanEmbedding(mp3Data, secretData, methodOpts, compressions)
+	if err != nil {
+		utils.SendError(w, "Failed to plan embedding: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var recommended *stego.PlanEntry
+	for i := range entries {
+		if entries[i].Recommended {
+			recommended = &entries[i]
+		}
+	}
+
+	message := "Embedding plan calculated successfully"
+	if recommended == nil {
+		message = "No setting fits the secret file"
+	}
+
+	response := PlanResponse{
+		Success:     true,
+		Message:     message,
+		SecretSize:  len(secretData),
+		Options:     entries,
+		Recommended: recommended,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+
+	log.Printf("Plan operation: mp3=%s, secret=%s, options=%d, fits=%t",
+		mp3Header.Filename, secretHeader.Filename, len(entries), recommended != nil)
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestPlanHandler(t *testing.T) {
+	mp3 := testMP3(300, 6)
+	wav := testWAV(6, 8000, 7)
+	secret := []byte("plan this secret")
+
+	plan := func(t *testing.T, carrier []byte, fields map[string]string) PlanResponse {
+		t.Helper()
+		w := serveForm(t, PlanHandler, fields, map[string][]byte{"mp3_file": carrier, "secret_file": secret})
+		if w.Code != http.StatusOK {
+			t.Fatalf("status %d: %s", w.Code, w.Body)
+		}
+		var response PlanResponse
+		decodeResponse(t, w, &response)
+		return response
+	}
+
+	t.Run("all settings", func(t *testing.T) {
+		response := plan(t, mp3, map[string]string{"key": "k"})
+		if len(response.Options) != 22 || response.Recommended == nil {
+			t.Fatalf("%d options, recommended %+v; want 22 and a recommendation", len(response.Options), response.Recommended)
+		}
+		payloads := make(map[string]int)
+		for _, entry := range response.Options {
+			if entry.LSBBits <= 1 && !entry.Compressed {
+				payloads[entry.Method] = entry.PayloadBytes
+			}
+		}
+		if payloads["chunked"] <= payloads["lsb"] {
+			t.Errorf("chunked payload %d bytes, want more than the %d of lsb", payloads["chunked"], payloads["lsb"])
+		}
+	})
+
+	t.Run("compress", func(t *testing.T) {
+		for _, compress := range []string{"true", "false"} {
+			response := plan(t, mp3, map[string]string{"key": "k", "compress": compress})
+			if len(response.Options) != 11 {
+				t.Fatalf("compress=%s: %d options, want 11", compress, len(response.Options))
+			}
+			for _, entry := range response.Options {
+				if entry.Compressed != (compress == "true") {
+					t.Errorf("compress=%s: planned %+v", compress, entry)
+				}
+			}
+		}
+	})
+
+	t.Run("method options", func(t *testing.T) {
+		capacity := func(response PlanResponse, method string) int {
+			for _, entry := range response.Options {
+				if entry.Method == method {
+					return entry.CapacityBytes
+				}
+			}
+			t.Fatalf("no %s entry", method)
+			return 0
+		}
+		base := plan(t, wav, map[string]string{"key": "k"})
+		fast := plan(t, wav, map[string]string{"key": "k", "chip_rate": "64"})
+		if capacity(fast, "dsss") <= capacity(base, "dsss") {
+			t.Errorf("chip_rate=64 capacity %d, want more than the default %d", capacity(fast, "dsss"), capacity(base, "dsss"))
+		}
+		weak := plan(t, wav, map[string]string{"key": "k", "echo_amplitude": "5"})
+		for _, entry := range weak.Options {
+			if entry.Method == "echo" && entry.Error == "" {
+				t.Errorf("echo_amplitude=5 planned without an error: %+v", entry)
+			}
+		}
+	})
+
+	errorTests := []struct {
+		name   string
+		fields map[string]string
+		files  map[string][]byte
+	}{
+		{"no carrier", map[string]string{"key": "k"}, map[string][]byte{"secret_file": secret}},
+		{"no secret", map[string]string{"key": "k"}, map[string][]byte{"mp3_file": mp3}},
+		{"invalid fill", map[string]string{"key": "k", "fill": "zeros"}, map[string][]byte{"mp3_file": mp3, "secret_file": secret}},
+		{"stealth without key", map[string]string{"stealth": "true"}, map[string][]byte{"mp3_file": mp3, "secret_file": secret}},
+		{"invalid chip rate", map[string]string{"key": "k", "chip_rate": "fast"}, map[string][]byte{"mp3_file": mp3, "secret_file": secret}},
+		{"invalid echo amplitude", map[string]string{"key": "k", "echo_amplitude": "loud"}, map[string][]byte{"mp3_file": mp3, "secret_file": secret}},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := serveForm(t, PlanHandler, tt.fields, tt.files); w.Code != http.StatusBadRequest {
+				t.Errorf("status %d, want %d: %s", w.Code, http.StatusBadRequest, w.Body)
+			}
+		})
+	}
+}
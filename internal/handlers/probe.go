@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/stego"
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/utils"
+)
+
+type ProbeResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	*stego.ProbeResult
+}
+
+func ProbeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		utils.SendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	err := r.ParseMultipartForm(100 << 20)
+	if err != nil {
+		utils.SendError(w, "Failed to parse form data", http.StatusBadRequest)
+		return
+	}
+
+	key := r.FormValue("key")
+
+	mp3Data, mp3Header, err := readUploadedFile(r, "mp3_file")
+	if err != nil {
+		utils.SendError(w, "MP3 file is required", http.StatusBadRequest)
+		return
+	}
+
+	result := stego.ProbeCarrier(mp3Data, key)
+
+	response := ProbeResponse{
+		Success:     true,
+		Message:     probeMessage(result),
+		ProbeResult: result,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+
+	log.Printf("Probe operation: mp3=%s, status=%s, method=%s, basis=%s",
+		mp3Header.Filename, result.Status, result.Method, result.Basis)
+}
+
+func probeMessage(result *stego.ProbeResult) string {
+	switch result.Status {
+	case stego.ProbeFound:
+		return "Steganographic data detected"
+	case stego.ProbeWrongKey:
+		return "Steganographic data detected, but the key is missing or incorrect"
+	case stego.ProbePartial:
+		return "Steganographic data detected, but only part of the payload could be recovered"
+	}
+	if result.KeyRequired {
+		return "No steganographic data detected without a key; provide the key to check for keyed payloads"
+	}
+	return "No steganographic data detected"
+}
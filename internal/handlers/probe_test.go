@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/stego"
+)
+
+func TestProbeHandler(t *testing.T) {
+	cover := testMP3(200, 1)
+	message := []byte("probe me")
+	embed := func(method stego.Method, carrier []byte, opts stego.EmbedOptions) []byte {
+		data, err := method.Embed(carrier, message, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	plain := embed(stego.NewLSBSteganography(), cover, stego.EmbedOptions{Bits: 2, Key: "k"})
+	encrypted := embed(stego.NewLSBSteganography(), cover, stego.EmbedOptions{Bits: 2, Key: "k", UseEncryption: true})
+	sealed := embed(stego.NewLSBSteganography(), cover, stego.EmbedOptions{Bits: 1, Key: "k", Stealth: true})
+
+	chunked, err := stego.NewChunkedSteganography().Embed(cover, bytes.Repeat(message, 200), stego.EmbedOptions{Bits: 2, Key: "k"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, offsets, err := stego.ScanMP3Frames(chunked)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cropped := append(append([]byte(nil), chunked[:offsets[0]]...), chunked[offsets[60]:offsets[63]]...)
+
+	tests := []struct {
+		name    string
+		carrier []byte
+		key     string
+		status  string
+		basis   string
+		message string
+	}{
+		{"plain", plain, "", stego.ProbeFound, stego.BasisContainer, "Steganographic data detected"},
+		{"encrypted with key", encrypted, "k", stego.ProbeFound, stego.BasisContainer, "Steganographic data detected"},
+		{"encrypted with wrong key", encrypted, "x", stego.ProbeWrongKey, stego.BasisContainer,
+			"Steganographic data detected, but the key is missing or incorrect"},
+		{"stealth with key", sealed, "k", stego.ProbeFound, stego.BasisSealed, "Steganographic data detected"},
+		{"chunked", chunked, "k", stego.ProbeFound, stego.BasisChunkCRC, "Steganographic data detected"},
+		{"cropped chunked", cropped, "k", stego.ProbePartial, stego.BasisChunkCRC,
+			"Steganographic data detected, but only part of the payload could be recovered"},
+		{"cover without key", cover, "", stego.ProbeNotFound, "",
+			"No steganographic data detected without a key; provide the key to check for keyed payloads"},
+		{"cover with key", cover, "k", stego.ProbeNotFound, "", "No steganographic data detected"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveForm(t, ProbeHandler, map[string]string{"key": tt.key}, map[string][]byte{"mp3_file": tt.carrier})
+			if w.Code != http.StatusOK {
+				t.Fatalf("status %d: %s", w.Code, w.Body)
+			}
+			var response ProbeResponse
+			decodeResponse(t, w, &response)
+			if response.Status != tt.status || response.Basis != tt.basis || response.Message != tt.message {
+				t.Errorf("got %s/%s %q, want %s/%s %q",
+					response.Status, response.Basis, response.Message, tt.status, tt.basis, tt.message)
+			}
+		})
+	}
+
+	if w := serveForm(t, ProbeHandler, nil, nil); w.Code != http.StatusBadRequest {
+		t.Errorf("missing carrier: status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"log"
 	"math"
@@ -9,15 +10,37 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/audio"
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/metrics"
 	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/utils"
 )
 
+const (
+	domainPCM   = "pcm"
+	domainBytes = "bytes"
+)
+
 type PSNRResponse struct {
 	PSNR         float64 `json:"psnr"`
 	MSE          float64 `json:"mse"`
 	MaxSignal    float64 `json:"max_signal"`
 	OriginalSize int     `json:"original_size"`
 	ModifiedSize int     `json:"modified_size"`
+
+	Domain       string  `json:"domain"`
+	SNR          float64 `json:"snr"`
+	SegmentalSNR float64 `json:"segmental_snr"`
+	LSD          float64 `json:"lsd"`
+	SampleRate   int     `json:"sample_rate,omitempty"`
+	Channels     int     `json:"channels,omitempty"`
+	Samples      int     `json:"samples,omitempty"`
+	LostFrames   int     `json:"lost_frames,omitempty"`
+	BytePSNR     float64 `json:"byte_psnr"`
+	DecodeError  string  `json:"decode_error,omitempty"`
+	metrics.ErrorCounts
+
+	Segments     []metrics.Segment `json:"segments"`
+	ByteSegments []metrics.Segment `json:"byte_segments"`
 }
 
 func PSNRHandler(w http.ResponseWriter, r *http.Request) {
@@ -93,6 +116,12 @@ func PSNRHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	segments, ok := formInt(r, "segments", metrics.DefaultSegments)
+	if !ok || segments < 1 {
+		utils.SendError(w, "segments must be a positive integer", http.StatusBadRequest)
+		return
+	}
+
 	psnr, mse, maxVal := calculatePSNR(originalData, modifiedData)
 
 	response := PSNRResponse{
@@ -101,14 +130,40 @@ func PSNRHandler(w http.ResponseWriter, r *http.Request) {
 		MaxSignal:    maxVal,
 		OriginalSize: len(originalData),
 		ModifiedSize: len(modifiedData),
+		Domain:       domainBytes,
+		BytePSNR:     psnr,
+		ErrorCounts:  metrics.CompareBytes(originalData, modifiedData),
+		ByteSegments: metrics.ByteSegments(originalData, modifiedData, segments),
+	}
+
+	original, modified, lost, err := decodePair(originalData, modifiedData)
+	if err != nil {
+		response.DecodeError = err.Error()
+		original, modified = metrics.BytesAsPCM(originalData), metrics.BytesAsPCM(modifiedData)
+	}
+
+	report := metrics.ComparePCM(original, modified, segments)
+	response.SNR = report.SNR
+	response.SegmentalSNR = report.SegmentalSNR
+	response.LSD = report.LSD
+	response.Segments = report.Segments
+	if err == nil {
+		response.Domain = domainPCM
+		response.PSNR = report.PSNR
+		response.MSE = report.MSE
+		response.MaxSignal = 1
+		response.SampleRate = report.SampleRate
+		response.Channels = report.Channels
+		response.Samples = report.Samples
+		response.LostFrames = lost
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(response)
 
-	log.Printf("PSNR calculation: PSNR=%.2f dB, MSE=%.6f, original=%s, modified=%s",
-		psnr, mse, originalHeader.Filename, modifiedHeader.Filename)
+	log.Printf("PSNR calculation (%s): PSNR=%.2f dB, MSE=%.6f, original=%s, modified=%s",
+		response.Domain, response.PSNR, response.MSE, originalHeader.Filename, modifiedHeader.Filename)
 }
 
 func calculatePSNR(original, modified []byte) (float64, float64, float64) {
@@ -142,3 +197,52 @@ func calculatePSNR(original, modified []byte) (float64, float64, float64) {
 
 	return psnr, mse, float64(maxVal)
 }
+
+// decodePair decodes cover and stego to PCM. MP3 stego files are decoded
+// frame-for-frame against the cover layout so both signals stay aligned even
+// when embedding damaged some frame headers.
+func decodePair(originalData, modifiedData []byte) (*audio.PCM, *audio.PCM, int, error) {
+	original, stream, err := decodeCover(originalData)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	modified, lost, err := decodeStego(modifiedData, original, stream)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return original, modified, lost, nil
+}
+
+// decodeCover decodes the reference side of a comparison. The MP3 stream is
+// nil for WAV carriers.
+func decodeCover(data []byte) (*audio.PCM, *audio.MP3Stream, error) {
+	if audio.IsWAV(data) {
+		pcm, err := audio.DecodePCM(data)
+		return pcm, nil, err
+	}
+
+	stream, err := audio.DecodeMP3(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return stream.PCM, stream, nil
+}
+
+func decodeStego(data []byte, cover *audio.PCM, stream *audio.MP3Stream) (*audio.PCM, int, error) {
+	if stream == nil {
+		pcm, err := audio.DecodePCM(data)
+		if err != nil {
+			return nil, 0, err
+		}
+		if pcm.Channels != cover.Channels || pcm.SampleRate != cover.SampleRate {
+			return nil, 0, errors.New("original and modified audio formats differ")
+		}
+		return pcm, 0, nil
+	}
+
+	decoded, err := audio.DecodeMP3Aligned(data, stream)
+	if err != nil {
+		return nil, 0, err
+	}
+	return decoded.PCM, decoded.LostFrames, nil
+}
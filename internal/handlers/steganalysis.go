@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/audio"
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/steganalysis"
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/utils"
+)
+
+type ChiSquareResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	Carrier string `json:"carrier"`
+	*steganalysis.ChiSquareResult
+}
+
+func formInt(r *http.Request, field string, fallback int) (int, bool) {
+	value := r.FormValue(field)
+	if value == "" {
+		return fallback, true
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 0 {
+		return 0, false
+	}
+	return parsed, true
+}
+
+func readCarrierFile(r *http.Request) ([]byte, string, error) {
+	data, header, err := readUploadedFile(r, "file")
+	if err != nil {
+		data, header, err = readUploadedFile(r, "mp3_file")
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return data, header.Filename, nil
+}
+
+// carrierValues returns the units a steganalysis test should look at: PCM
+// samples for WAV carriers and raw bytes for everything else.
+func carrierValues(data []byte) ([]int, string) {
+	if wav, err := audio.ParseWAV(data); err == nil {
+		return wav.Samples, "wav"
+	}
+	return steganalysis.BytesToValues(data), "mp3"
+}
+
+func ChiSquareHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		utils.SendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	err := r.ParseMultipartForm(100 << 20)
+	if err != nil {
+		utils.SendError(w, "Failed to parse form data", http.StatusBadRequest)
+		return
+	}
+
+	bits, ok := formInt(r, "bits", 1)
+	if !ok || bits < 1 || bits > 4 {
+		utils.SendError(w, "bits must be between 1 and 4", http.StatusBadRequest)
+		return
+	}
+	windowSize, ok := formInt(r, "window", 4096)
+	if !ok || windowSize == 0 {
+		utils.SendError(w, "window must be a positive integer", http.StatusBadRequest)
+		return
+	}
+	step, ok := formInt(r, "step", windowSize)
+	if !ok || step == 0 {
+		utils.SendError(w, "step must be a positive integer", http.StatusBadRequest)
+		return
+	}
+	offset, ok := formInt(r, "offset", 0)
+	if !ok {
+		utils.SendError(w, "offset must be a non-negative integer", http.StatusBadRequest)
+		return
+	}
+
+	data, filename, err := readCarrierFile(r)
+	if err != nil {
+		utils.SendError(w, "Audio file is required", http.StatusBadRequest)
+		return
+	}
+
+	values, carrier := carrierValues(data)
+	if offset > len(values) {
+		offset = len(values)
+	}
+
+	result, err := steganalysis.ChiSquareWindows(values[offset:], steganalysis.ChiSquareOptions{
+		Bits:       bits,
+		WindowSize: windowSize,
+		Step:       step,
+		Cumulative: r.FormValue("cumulative") == "true",
+	})
+	if err != nil {
+		utils.SendError(w, "Chi-square analysis failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response := ChiSquareResponse{
+		Success:         true,
+		Message:         "Chi-square analysis completed",
+		Carrier:         carrier,
+		ChiSquareResult: result,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+
+	log.Printf("Chi-square analysis: file=%s, carrier=%s, bits=%d, windows=%d, mean_p=%.4f",
+		filename, carrier, bits, len(result.Windows), result.MeanProbability)
+}
+
+type PCMAnalysisResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	*steganalysis.PCMAnalysis
+}
+
+func PCMAnalysisHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		utils.SendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	err := r.ParseMultipartForm(100 << 20)
+	if err != nil {
+		utils.SendError(w, "Failed to parse form data", http.StatusBadRequest)
+		return
+	}
+
+	data, filename, err := readCarrierFile(r)
+	if err != nil {
+		utils.SendError(w, "Audio file is required", http.StatusBadRequest)
+		return
+	}
+
+	wav, err := audio.ParseWAV(data)
+	if err != nil {
+		utils.SendError(w, "RS and sample pair analysis require a PCM WAV file: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	analysis := steganalysis.AnalyzePCM(wav)
+
+	response := PCMAnalysisResponse{
+		Success:     true,
+		Message:     "RS and sample pair analysis completed",
+		PCMAnalysis: analysis,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+
+	log.Printf("PCM analysis: file=%s, channels=%d, rs=%.4f, spa=%.4f",
+		filename, wav.Channels, analysis.EstimatedRateRS, analysis.EstimatedRateSPA)
+}
+
+type HeaderAnomalyResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	*steganalysis.HeaderAnomalyReport
+}
+
+func HeaderAnomalyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		utils.SendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	err := r.ParseMultipartForm(100 << 20)
+	if err != nil {
+		utils.SendError(w, "Failed to parse form data", http.StatusBadRequest)
+		return
+	}
+
+	mp3Data, mp3Header, err := readUploadedFile(r, "mp3_file")
+	if err != nil {
+		utils.SendError(w, "MP3 file is required", http.StatusBadRequest)
+		return
+	}
+
+	report, err := steganalysis.DetectHeaderAnomalies(mp3Data)
+	if err != nil {
+		utils.SendError(w, "Invalid MP3 file format. Please upload a valid MP3 file.", http.StatusBadRequest)
+		return
+	}
+
+	response := HeaderAnomalyResponse{
+		Success:             true,
+		Message:             report.Verdict,
+		HeaderAnomalyReport: report,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+
+	log.Printf("Header anomaly check: mp3=%s, frames=%d, suspicious=%t, score=%.4f",
+		mp3Header.Filename, report.Frames, report.Suspicious, report.Score)
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"math/rand"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// testMP3 returns an ID3v2 tag followed by frames MPEG-1 Layer III frames at
+// 128 kbps and 44.1 kHz with random bodies.
+func testMP3(frames int, seed int64) []byte {
+	rng := rand.New(rand.NewSource(seed))
+	data := []byte("ID3\x03\x00\x00\x00\x00\x00\x10")
+	data = append(data, make([]byte, 16)...)
+	for i := 0; i < frames; i++ {
+		padding := byte(0)
+		if i%3 == 0 {
+			padding = 1
+		}
+		frame := make([]byte, 144*128000/44100+int(padding))
+		copy(frame, []byte{0xFF, 0xFB, 0x90 | padding<<1, 0x64})
+		rng.Read(frame[4:])
+		data = append(data, frame...)
+	}
+	return data
+}
+
+// testWAV returns a 16-bit mono PCM WAV file of seconds of a tone with a
+// little noise.
+func testWAV(seconds float64, sampleRate int, seed int64) []byte {
+	rng := rand.New(rand.NewSource(seed))
+	frames := int(seconds * float64(sampleRate))
+	data := make([]byte, 44+2*frames)
+	copy(data, "RIFF")
+	binary.LittleEndian.PutUint32(data[4:], uint32(len(data)-8))
+	copy(data[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(data[16:], 16)
+	binary.LittleEndian.PutUint16(data[20:], 1)
+	binary.LittleEndian.PutUint16(data[22:], 1)
+	binary.LittleEndian.PutUint32(data[24:], uint32(sampleRate))
+	binary.LittleEndian.PutUint32(data[28:], uint32(2*sampleRate))
+	binary.LittleEndian.PutUint16(data[32:], 2)
+	binary.LittleEndian.PutUint16(data[34:], 16)
+	copy(data[36:], "data")
+	binary.LittleEndian.PutUint32(data[40:], uint32(2*frames))
+	for i := 0; i < frames; i++ {
+		t := float64(i) / float64(sampleRate)
+		v := 0.4*math.Sin(2*math.Pi*440*t) + 0.05*rng.NormFloat64()
+		binary.LittleEndian.PutUint16(data[44+2*i:], uint16(int16(v*32767)))
+	}
+	return data
+}
+
+// serveForm posts fields and files as a multipart form to handler.
+func serveForm(t *testing.T, handler http.HandlerFunc, fields map[string]string, files map[string][]byte) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for name, value := range fields {
+		if err := writer.WriteField(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, data := range files {
+		part, err := writer.CreateFormFile(name, name+".bin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(data)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/", &body)
+	r.Header.Set("Content-Type", writer.FormDataContentType())
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
+
+// decodeResponse decodes a JSON response body into v.
+func decodeResponse(t *testing.T, w *httptest.ResponseRecorder, v any) {
+	t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("decoding %q: %v", w.Body.String(), err)
+	}
+}
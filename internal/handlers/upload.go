@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"io"
+	"mime/multipart"
+	"net/http"
+)
+
+func readUploadedFile(r *http.Request, field string) ([]byte, *multipart.FileHeader, error) {
+	file, header, err := r.FormFile(field)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return data, header, nil
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/stego"
+)
+
+// VerifyReport summarises a round-trip check. FramesIntact is nil when the
+// carrier has no MP3 frames to compare.
+type VerifyReport struct {
+	Verified     bool                   `json:"verified"`
+	Method       string                 `json:"method"`
+	LSBBits      int                    `json:"lsb_bits,omitempty"`
+	Payloads     []PayloadVerification  `json:"payloads"`
+	FramesIntact *bool                  `json:"frames_intact"`
+	Frames       *stego.FrameComparison `json:"frames"`
+}
+
+type PayloadVerification struct {
+	Role            string `json:"role"`
+	ExpectedSHA256  string `json:"expected_sha256"`
+	ExtractedSHA256 string `json:"extracted_sha256,omitempty"`
+	HashMatch       bool   `json:"hash_match"`
+	Error           string `json:"error,omitempty"`
+}
+
+type verifyTarget struct {
+	role     string
+	key      string
+	expected []byte
+}
+
+func verifyEmbedding(cover, embedded []byte, method stego.Method, lsbBits int, targets []verifyTarget) *VerifyReport {
+	report := &VerifyReport{
+		Verified: true,
+		Method:   method.Name(),
+		LSBBits:  lsbBits,
+		Frames:   stego.CompareFrames(cover, embedded),
+	}
+	if report.Frames.Applicable {
+		report.FramesIntact = &report.Frames.Match
+	}
+
+	for _, target := range targets {
+		check := verifyPayload(embedded, method, target)
+		if !check.HashMatch {
+			report.Verified = false
+		}
+		report.Payloads = append(report.Payloads, check)
+	}
+
+	return report
+}
+
+func verifyPayload(embedded []byte, method stego.Method, target verifyTarget) PayloadVerification {
+	expectedSum := sha256.Sum256(target.expected)
+	check := PayloadVerification{
+		Role:           target.role,
+		ExpectedSHA256: hex.EncodeToString(expectedSum[:]),
+	}
+
+	result, err := method.Extract(embedded, target.key)
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+
+	recovered, err := decodeExtractedMessage(result, target.key)
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+
+	recoveredSum := sha256.Sum256(recovered)
+	check.ExtractedSHA256 = hex.EncodeToString(recoveredSum[:])
+	check.HashMatch = bytes.Equal(expectedSum[:], recoveredSum[:])
+
+	return check
+}
+
+type VerifyResponse struct {
+	Success     bool               `json:"success"`
+	Message     string             `json:"message"`
+	Report      *VerifyReport      `json:"report"`
+	EmbedReport *stego.EmbedReport `json:"embed_report,omitempty"`
+}
+
+func sendVerifyReport(w http.ResponseWriter, report *VerifyReport, embedReport *stego.EmbedReport) {
+	response := VerifyResponse{
+		Success:     report.Verified,
+		Message:     "Round-trip verification succeeded",
+		Report:      report,
+		EmbedReport: embedReport,
+	}
+
+	status := http.StatusOK
+	if !report.Verified {
+		response.Message = "Round-trip verification failed: the embedded payload could not be recovered"
+		status = http.StatusInternalServerError
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/audio"
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/metrics"
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/stego"
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/utils"
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/visual"
+)
+
+const (
+	imageCover      = "cover"
+	imageStego      = "stego"
+	imageDifference = "difference"
+	imageHeatmap    = "heatmap"
+	imageAll        = "all"
+
+	defaultImageWidth  = 1024
+	defaultImageHeight = 256
+	maxImageWidth      = 4096
+	maxImageHeight     = 2048
+	minFFTSize         = 64
+	maxFFTSize         = 16384
+
+	wavBlockFrames   = 1152
+	byteBlockSize    = 4096
+	heatmapMP3Frames = "mp3_frames"
+	heatmapWAVBlocks = "wav_blocks"
+	heatmapBytes     = "bytes"
+)
+
+func formFloat(r *http.Request, field string, fallback float64) (float64, bool) {
+	value := r.FormValue(field)
+	if value == "" {
+		return fallback, true
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, false
+	}
+	return parsed, true
+}
+
+// changeRanges splits a carrier into the units a change heatmap shows: MP3
+// frames, blocks of 1152 WAV sample frames, or fixed byte blocks.
+func changeRanges(cover []byte) ([][2]int, string) {
+	var ranges [][2]int
+
+	if wav, err := audio.ParseWAV(cover); err == nil {
+		block := wavBlockFrames * wav.Channels * wav.BytesPerSample()
+		end := wav.DataOffset + wav.DataSize
+		for start := wav.DataOffset; start < end; start += block {
+			ranges = append(ranges, [2]int{start, min(start+block, end)})
+		}
+		return ranges, heatmapWAVBlocks
+	}
+
+	if frames, offsets, err := stego.ScanMP3Frames(cover); err == nil {
+		for i, offset := range offsets {
+			ranges = append(ranges, [2]int{offset, offset + frames[i].Size})
+		}
+		return ranges, heatmapMP3Frames
+	}
+
+	for start := 0; start < len(cover); start += byteBlockSize {
+		ranges = append(ranges, [2]int{start, min(start+byteBlockSize, len(cover))})
+	}
+	return ranges, heatmapBytes
+}
+
+func pngPart(name string, data []byte) responsePart {
+	return responsePart{Name: name, Filename: name + ".png", ContentType: "image/png", Data: data}
+}
+
+func SpectrogramHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		utils.SendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	err := r.ParseMultipartForm(100 << 20)
+	if err != nil {
+		utils.SendError(w, "Failed to parse form data", http.StatusBadRequest)
+		return
+	}
+
+	originalData, originalHeader, err := readUploadedFile(r, "original_file")
+	if err != nil {
+		utils.SendError(w, "Original audio file is required", http.StatusBadRequest)
+		return
+	}
+	modifiedData, _, err := readUploadedFile(r, "modified_file")
+	if err != nil {
+		utils.SendError(w, "Modified audio file is required", http.StatusBadRequest)
+		return
+	}
+
+	view := r.FormValue("image")
+	if view == "" {
+		view = imageAll
+	}
+	switch view {
+	case imageCover, imageStego, imageDifference, imageHeatmap, imageAll:
+	default:
+		utils.SendError(w, "image must be cover, stego, difference, heatmap or all", http.StatusBadRequest)
+		return
+	}
+
+	width, okWidth := formInt(r, "width", defaultImageWidth)
+	height, okHeight := formInt(r, "height", defaultImageHeight)
+	if !okWidth || !okHeight || width < 1 || height < 1 || width > maxImageWidth || height > maxImageHeight {
+		utils.SendError(w, "width must be 1-4096 and height 1-2048", http.StatusBadRequest)
+		return
+	}
+
+	fftSize, ok := formInt(r, "fft_size", visual.DefaultFrameSize)
+	if !ok || fftSize < minFFTSize || fftSize > maxFFTSize || fftSize&(fftSize-1) != 0 {
+		utils.SendError(w, "fft_size must be a power of two between 64 and 16384", http.StatusBadRequest)
+		return
+	}
+
+	minDB, okMin := formFloat(r, "min_db", visual.DefaultMinDB)
+	maxDB, okMax := formFloat(r, "max_db", visual.DefaultMaxDB)
+	if !okMin || !okMax || minDB >= maxDB {
+		utils.SendError(w, "min_db must be lower than max_db", http.StatusBadRequest)
+		return
+	}
+
+	columns, okColumns := formInt(r, "columns", visual.DefaultHeatmapColumns)
+	cell, okCell := formInt(r, "cell", visual.DefaultHeatmapCell)
+	if !okColumns || !okCell || columns < 1 || cell < 1 || columns*cell > maxImageWidth {
+		utils.SendError(w, "columns and cell must be positive and columns*cell at most 4096", http.StatusBadRequest)
+		return
+	}
+
+	var parts []responsePart
+
+	if view != imageHeatmap {
+		domain := domainPCM
+		original, modified, _, err := decodePair(originalData, modifiedData)
+		if err != nil {
+			domain = domainBytes
+			original, modified = metrics.BytesAsPCM(originalData), metrics.BytesAsPCM(modifiedData)
+		}
+		w.Header().Set("X-Domain", domain)
+
+		coverMono, stegoMono := original.Mono(), modified.Mono()
+		difference := make([]float64, min(len(coverMono), len(stegoMono)))
+		for i := range difference {
+			difference[i] = stegoMono[i] - coverMono[i]
+		}
+
+		signals := []struct {
+			name    string
+			samples []float64
+		}{
+			{imageCover, coverMono},
+			{imageStego, stegoMono},
+			{imageDifference, difference},
+		}
+		for _, signal := range signals {
+			if view != imageAll && view != signal.name {
+				continue
+			}
+			spec := visual.NewSpectrogram(signal.samples, original.SampleRate, fftSize, width)
+			data, err := visual.EncodePNG(spec.Image(width, height, minDB, maxDB))
+			if err != nil {
+				utils.SendError(w, "Failed to render spectrogram", http.StatusInternalServerError)
+				return
+			}
+			parts = append(parts, pngPart(signal.name, data))
+		}
+	}
+
+	if view == imageHeatmap || view == imageAll {
+		ranges, unit := changeRanges(originalData)
+		changes := visual.MeasureChanges(originalData, modifiedData, ranges)
+		data, err := visual.EncodePNG(visual.HeatmapImage(changes, columns, cell))
+		if err != nil {
+			utils.SendError(w, "Failed to render heatmap", http.StatusInternalServerError)
+			return
+		}
+		parts = append(parts, pngPart(imageHeatmap, data))
+		w.Header().Set("X-Heatmap-Unit", unit)
+		w.Header().Set("X-Heatmap-Ranges", strconv.Itoa(len(ranges)))
+	}
+
+	log.Printf("Rendered %s images for %s", view, originalHeader.Filename)
+
+	if len(parts) == 1 {
+		w.Header().Set("Content-Type", "image/png")
+		w.Header().Set("Content-Disposition", "attachment; filename="+parts[0].Filename)
+		w.Header().Set("Content-Length", strconv.Itoa(len(parts[0].Data)))
+		w.WriteHeader(http.StatusOK)
+		w.Write(parts[0].Data)
+		return
+	}
+
+	if err := writeMultipartResponse(w, parts); err != nil {
+		log.Printf("Failed to write images: %v", err)
+	}
+}
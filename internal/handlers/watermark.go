@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/stego"
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/utils"
+)
+
+type WatermarkResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	*stego.WatermarkDetection
+}
+
+func WatermarkEmbedHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		utils.SendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	err := r.ParseMultipartForm(100 << 20)
+	if err != nil {
+		utils.SendError(w, "Failed to parse form data", http.StatusBadRequest)
+		return
+	}
+
+	key := r.FormValue("key")
+	if key == "" {
+		utils.SendError(w, "Key is required for watermarking", http.StatusBadRequest)
+		return
+	}
+
+	id, err := parseWatermarkID(r.FormValue("watermark_id"))
+	if err != nil {
+		utils.SendError(w, "watermark_id must be a hexadecimal identifier of at most 16 digits", http.StatusBadRequest)
+		return
+	}
+
+	mp3Data, mp3Header, err := readUploadedFile(r, "mp3_file")
+	if err != nil {
+		utils.SendError(w, "MP3 file is required", http.StatusBadRequest)
+		return
+	}
+
+	watermarked, err := stego.EmbedWatermark(mp3Data, id, key)
+	if err != nil {
+		switch err {
+		case stego.ErrInvalidMP3Format, stego.ErrNoValidFrames:
+			utils.SendError(w, "Invalid MP3 file format. Please upload a valid MP3 file.", http.StatusBadRequest)
+		case stego.ErrInsufficientCapacity:
+			utils.SendError(w, "MP3 file is too small to hold a watermark", http.StatusBadRequest)
+		default:
+			utils.SendError(w, "Failed to embed watermark: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	filename := "watermarked_" + mp3Header.Filename
+	w.Header().Set("Content-Type", "audio/mpeg")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+	w.Header().Set("Content-Length", strconv.Itoa(len(watermarked)))
+	w.Header().Set("X-Watermark-ID", stego.FormatWatermarkID(id))
+	w.Write(watermarked)
+
+	log.Printf("Watermark embed: mp3=%s, id=%s", mp3Header.Filename, stego.FormatWatermarkID(id))
+}
+
+func WatermarkDetectHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		utils.SendError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	err := r.ParseMultipartForm(100 << 20)
+	if err != nil {
+		utils.SendError(w, "Failed to parse form data", http.StatusBadRequest)
+		return
+	}
+
+	key := r.FormValue("key")
+	if key == "" {
+		utils.SendError(w, "Key is required for watermark detection", http.StatusBadRequest)
+		return
+	}
+
+	mp3Data, mp3Header, err := readUploadedFile(r, "mp3_file")
+	if err != nil {
+		utils.SendError(w, "MP3 file is required", http.StatusBadRequest)
+		return
+	}
+
+	detection, err := stego.DetectWatermark(mp3Data, key)
+	if err != nil {
+		switch err {
+		case stego.ErrInvalidMP3Format, stego.ErrNoValidFrames:
+			utils.SendError(w, "Invalid MP3 file format. Please upload a valid MP3 file.", http.StatusBadRequest)
+		default:
+			utils.SendError(w, "Failed to detect watermark: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	message := "No watermark detected for this key"
+	if detection.Detected {
+		message = "Watermark detected"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(WatermarkResponse{
+		Success:            true,
+		Message:            message,
+		WatermarkDetection: detection,
+	})
+
+	log.Printf("Watermark detect: mp3=%s, detected=%t, id=%s, confidence=%.4f, frames=%d",
+		mp3Header.Filename, detection.Detected, detection.IDHex, detection.Confidence, detection.Frames)
+}
+
+// parseWatermarkID accepts the identifier in the same hexadecimal form the
+// detector reports, with an optional 0x prefix.
+func parseWatermarkID(value string) (uint64, error) {
+	value = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(value)), "0x")
+	if value == "" || len(value) > 16 {
+		return 0, strconv.ErrSyntax
+	}
+	return strconv.ParseUint(value, 16, 64)
+}
@@ -0,0 +1,40 @@
+package metrics
+
+import "math/bits"
+
+// ErrorCounts compares two byte streams position by position. Bytes past the
+// end of the shorter stream count as fully in error.
+type ErrorCounts struct {
+	Bytes           int     `json:"bytes"`
+	ByteErrors      int     `json:"byte_errors"`
+	ByteErrorRate   float64 `json:"byte_error_rate"`
+	BitErrors       int     `json:"bit_errors"`
+	BitErrorRate    float64 `json:"bit_error_rate"`
+	HammingDistance int     `json:"hamming_distance"`
+}
+
+func CompareBytes(a, b []byte) ErrorCounts {
+	counts := ErrorCounts{Bytes: max(len(a), len(b))}
+	common := min(len(a), len(b))
+	for i := 0; i < common; i++ {
+		if a[i] != b[i] {
+			counts.ByteErrors++
+			counts.BitErrors += bits.OnesCount8(a[i] ^ b[i])
+		}
+	}
+
+	extra := counts.Bytes - common
+	counts.ByteErrors += extra
+	counts.BitErrors += 8 * extra
+	counts.HammingDistance = counts.BitErrors
+
+	if counts.Bytes > 0 {
+		counts.ByteErrorRate = float64(counts.ByteErrors) / float64(counts.Bytes)
+		counts.BitErrorRate = float64(counts.BitErrors) / float64(8*counts.Bytes)
+	}
+	return counts
+}
+
+func HammingDistance(a, b []byte) int {
+	return CompareBytes(a, b).HammingDistance
+}
@@ -0,0 +1,54 @@
+package metrics
+
+import "testing"
+
+func TestCompareBytes(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []byte
+		want ErrorCounts
+	}{
+		{"identical", []byte{1, 2, 3}, []byte{1, 2, 3}, ErrorCounts{Bytes: 3}},
+		{"one bit", []byte{0x00, 0xFF}, []byte{0x01, 0xFF}, ErrorCounts{
+			Bytes: 2, ByteErrors: 1, ByteErrorRate: 0.5, BitErrors: 1, BitErrorRate: 1.0 / 16, HammingDistance: 1,
+		}},
+		{"inverted", []byte{0x0F}, []byte{0xF0}, ErrorCounts{
+			Bytes: 1, ByteErrors: 1, ByteErrorRate: 1, BitErrors: 8, BitErrorRate: 1, HammingDistance: 8,
+		}},
+		{"missing tail", []byte{7, 7, 7, 7}, []byte{7, 7}, ErrorCounts{
+			Bytes: 4, ByteErrors: 2, ByteErrorRate: 0.5, BitErrors: 16, BitErrorRate: 0.5, HammingDistance: 16,
+		}},
+		{"empty", nil, nil, ErrorCounts{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CompareBytes(tt.a, tt.b); got != tt.want {
+				t.Errorf("CompareBytes = %+v, want %+v", got, tt.want)
+			}
+			if got := HammingDistance(tt.b, tt.a); got != tt.want.HammingDistance {
+				t.Errorf("HammingDistance = %d, want %d", got, tt.want.HammingDistance)
+			}
+		})
+	}
+}
+
+func TestByteSegments(t *testing.T) {
+	a := []byte{0, 0, 0, 0, 0, 0}
+	b := []byte{0, 0, 1, 0, 3}
+	segments := ByteSegments(a, b, 3)
+	want := []struct{ start, end, byteErrors, bitErrors int }{
+		{0, 2, 0, 0},
+		{2, 4, 1, 1},
+		{4, 6, 2, 10},
+	}
+	if len(segments) != len(want) {
+		t.Fatalf("got %d segments, want %d", len(segments), len(want))
+	}
+	for i, w := range want {
+		s := segments[i]
+		if s.Start != w.start || s.End != w.end || s.ByteErrors != w.byteErrors || s.BitErrors != w.bitErrors {
+			t.Errorf("segment %d = [%d,%d) %d bytes %d bits, want [%d,%d) %d bytes %d bits",
+				i, s.Start, s.End, s.ByteErrors, s.BitErrors, w.start, w.end, w.byteErrors, w.bitErrors)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"math"
+
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/audio"
+)
+
+const (
+	DefaultLSDFrame = 1024
+
+	// Bin powers are floored at roughly the 16-bit quantization noise level
+	// (per sample of frame length), so differences nobody can hear in near
+	// silent frames do not dominate the distance.
+	powerFloorPerSample = 1e-9
+)
+
+// LogSpectralDistance is the mean over Hann-windowed frames (50% overlap) of
+// the RMS difference in dB between the two power spectra. frameLen is rounded
+// up to a power of two.
+func LogSpectralDistance(reference, test []float64, frameLen int) float64 {
+	if frameLen <= 0 {
+		frameLen = DefaultLSDFrame
+	}
+	frameLen = nextPowerOfTwo(frameLen)
+
+	n := commonLength(reference, test)
+	if n < frameLen {
+		if n == 0 {
+			return 0
+		}
+		frameLen = nextPowerOfTwo(n)
+	}
+
+	window := audio.HannWindow(frameLen)
+	floor := powerFloorPerSample * float64(frameLen)
+	refFrame := make([]float64, frameLen)
+	testFrame := make([]float64, frameLen)
+
+	var sum float64
+	frames := 0
+	for start := 0; start < n; start += frameLen / 2 {
+		for i := range refFrame {
+			refFrame[i], testFrame[i] = 0, 0
+			if start+i < n {
+				refFrame[i], testFrame[i] = reference[start+i], test[start+i]
+			}
+		}
+
+		refPower := audio.PowerSpectrum(refFrame, window)
+		testPower := audio.PowerSpectrum(testFrame, window)
+		var dist float64
+		for k := range refPower {
+			d := 10 * math.Log10((refPower[k]+floor)/(testPower[k]+floor))
+			dist += d * d
+		}
+		sum += math.Sqrt(dist / float64(len(refPower)))
+		frames++
+
+		if start+frameLen >= n {
+			break
+		}
+	}
+
+	return sum / float64(frames)
+}
+
+func nextPowerOfTwo(n int) int {
+	p := 1
+	for p < n {
+		p <<= 1
+	}
+	return p
+}
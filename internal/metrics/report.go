@@ -0,0 +1,118 @@
+package metrics
+
+import "github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/audio"
+
+const (
+	DefaultSegments = 32
+
+	segmentalSNRSeconds = 0.02
+	segmentalSNRSamples = 1024
+)
+
+// Segment is one slice of the breakdown. Start and End are sample frames
+// for PCM segments and byte offsets for byte segments.
+type Segment struct {
+	Index        int      `json:"index"`
+	Start        int      `json:"start"`
+	End          int      `json:"end"`
+	StartSeconds *float64 `json:"start_seconds,omitempty"`
+	EndSeconds   *float64 `json:"end_seconds,omitempty"`
+	SNR          *float64 `json:"snr,omitempty"`
+	MSE          *float64 `json:"mse,omitempty"`
+	LSD          *float64 `json:"lsd,omitempty"`
+	*ErrorCounts
+}
+
+type PCMReport struct {
+	SNR          float64   `json:"snr"`
+	SegmentalSNR float64   `json:"segmental_snr"`
+	PSNR         float64   `json:"psnr"`
+	MSE          float64   `json:"mse"`
+	LSD          float64   `json:"lsd"`
+	SampleRate   int       `json:"sample_rate"`
+	Channels     int       `json:"channels"`
+	Samples      int       `json:"samples"`
+	Segments     []Segment `json:"segments"`
+}
+
+// ComparePCM measures test against reference. SNR, PSNR and MSE use all
+// interleaved samples; LSD and the per-segment LSD use the mono mix. Without
+// a sample rate, segmental SNR falls back to 1024-sample segments.
+func ComparePCM(reference, test *audio.PCM, segments int) *PCMReport {
+	ref, tst := reference.Samples, test.Samples
+	n := commonLength(ref, tst)
+	ref, tst = ref[:n], tst[:n]
+	channels := max(reference.Channels, 1)
+
+	segmentalLen := segmentalSNRSamples
+	if reference.SampleRate > 0 {
+		segmentalLen = int(float64(reference.SampleRate)*segmentalSNRSeconds) * channels
+	}
+	report := &PCMReport{
+		SNR:          SNR(ref, tst),
+		SegmentalSNR: SegmentalSNR(ref, tst, segmentalLen),
+		PSNR:         PSNR(ref, tst, 1),
+		MSE:          MSE(ref, tst),
+		SampleRate:   reference.SampleRate,
+		Channels:     reference.Channels,
+		Samples:      n / channels,
+	}
+
+	refMono := (&audio.PCM{Channels: channels, Samples: ref}).Mono()
+	testMono := (&audio.PCM{Channels: channels, Samples: tst}).Mono()
+	report.LSD = LogSpectralDistance(refMono, testMono, DefaultLSDFrame)
+
+	for i, r := range segmentRanges(report.Samples, segments) {
+		seg := Segment{Index: i, Start: r[0], End: r[1]}
+		if reference.SampleRate > 0 {
+			start := float64(r[0]) / float64(reference.SampleRate)
+			end := float64(r[1]) / float64(reference.SampleRate)
+			seg.StartSeconds, seg.EndSeconds = &start, &end
+		}
+		a, b := ref[r[0]*channels:r[1]*channels], tst[r[0]*channels:r[1]*channels]
+		snr, mse := SNR(a, b), MSE(a, b)
+		lsd := LogSpectralDistance(refMono[r[0]:r[1]], testMono[r[0]:r[1]], DefaultLSDFrame)
+		seg.SNR, seg.MSE, seg.LSD = &snr, &mse, &lsd
+		report.Segments = append(report.Segments, seg)
+	}
+
+	return report
+}
+
+// ByteSegments breaks CompareBytes down over equal byte ranges.
+func ByteSegments(a, b []byte, segments int) []Segment {
+	var result []Segment
+	for i, r := range segmentRanges(max(len(a), len(b)), segments) {
+		counts := CompareBytes(clip(a, r[0], r[1]), clip(b, r[0], r[1]))
+		result = append(result, Segment{Index: i, Start: r[0], End: r[1], ErrorCounts: &counts})
+	}
+	return result
+}
+
+func clip(data []byte, start, end int) []byte {
+	start, end = min(start, len(data)), min(end, len(data))
+	return data[start:end]
+}
+
+func segmentRanges(total, segments int) [][2]int {
+	if segments <= 0 {
+		segments = DefaultSegments
+	}
+	segments = min(segments, total)
+
+	ranges := make([][2]int, 0, segments)
+	for i := 0; i < segments; i++ {
+		ranges = append(ranges, [2]int{total * i / segments, total * (i + 1) / segments})
+	}
+	return ranges
+}
+
+// BytesAsPCM maps bytes to a signed mono signal in [-1, 1), so the signal
+// metrics can still be computed on carriers that cannot be decoded.
+func BytesAsPCM(data []byte) *audio.PCM {
+	samples := make([]float64, len(data))
+	for i, b := range data {
+		samples[i] = (float64(b) - 128) / 128
+	}
+	return &audio.PCM{Channels: 1, Samples: samples}
+}
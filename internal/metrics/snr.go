@@ -0,0 +1,90 @@
+package metrics
+
+import "math"
+
+const (
+	// MaxDB caps ratios that would otherwise be infinite for identical signals.
+	MaxDB = 100.0
+
+	segmentMinSNR = -10.0
+	segmentMaxSNR = 35.0
+	silentRMS     = 1e-5
+)
+
+func commonLength(reference, test []float64) int {
+	if len(test) < len(reference) {
+		return len(test)
+	}
+	return len(reference)
+}
+
+func energies(reference, test []float64) (float64, float64) {
+	var signal, noise float64
+	for i := 0; i < commonLength(reference, test); i++ {
+		e := reference[i] - test[i]
+		signal += reference[i] * reference[i]
+		noise += e * e
+	}
+	return signal, noise
+}
+
+func MSE(reference, test []float64) float64 {
+	n := commonLength(reference, test)
+	if n == 0 {
+		return 0
+	}
+	_, noise := energies(reference, test)
+	return noise / float64(n)
+}
+
+// PSNR compares against peak, which is 1 for full-scale PCM.
+func PSNR(reference, test []float64, peak float64) float64 {
+	mse := MSE(reference, test)
+	if mse == 0 {
+		return MaxDB
+	}
+	return math.Min(MaxDB, 10*math.Log10(peak*peak/mse))
+}
+
+func SNR(reference, test []float64) float64 {
+	signal, noise := energies(reference, test)
+	return snrDB(signal, noise)
+}
+
+func snrDB(signal, noise float64) float64 {
+	switch {
+	case noise == 0:
+		return MaxDB
+	case signal == 0:
+		return -MaxDB
+	}
+	return math.Max(-MaxDB, math.Min(MaxDB, 10*math.Log10(signal/noise)))
+}
+
+// SegmentalSNR averages the SNR of consecutive segments of segmentLen
+// samples. Segment values are clamped to the usual [-10, 35] dB range and
+// silent segments are skipped, so short loud errors are not hidden by long
+// clean passages the way they are in the global SNR.
+func SegmentalSNR(reference, test []float64, segmentLen int) float64 {
+	n := commonLength(reference, test)
+	if segmentLen <= 0 {
+		segmentLen = n
+	}
+
+	var sum float64
+	segments := 0
+	for start := 0; start < n; start += segmentLen {
+		end := min(start+segmentLen, n)
+		signal, noise := energies(reference[start:end], test[start:end])
+		if math.Sqrt(signal/float64(end-start)) < silentRMS {
+			continue
+		}
+		sum += math.Max(segmentMinSNR, math.Min(segmentMaxSNR, snrDB(signal, noise)))
+		segments++
+	}
+
+	if segments == 0 {
+		return segmentMaxSNR
+	}
+	return sum / float64(segments)
+}
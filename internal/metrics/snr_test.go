@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func approx(got, want, tolerance float64) bool {
+	return math.Abs(got-want) <= tolerance
+}
+
+func TestSignalMetrics(t *testing.T) {
+	tests := []struct {
+		name      string
+		reference []float64
+		test      []float64
+		mse       float64
+		psnr      float64
+		snr       float64
+	}{
+		{"identical", []float64{0.5, -0.5, 0.25}, []float64{0.5, -0.5, 0.25}, 0, MaxDB, MaxDB},
+		{"constant error", []float64{1, 1, 1, 1}, []float64{0.9, 0.9, 0.9, 0.9}, 0.01, 20, 20},
+		{"half amplitude", []float64{0.5, -0.5}, []float64{0.25, -0.25}, 0.0625, 10 * math.Log10(16), 10 * math.Log10(4)},
+		{"silent reference", []float64{0, 0}, []float64{0.1, 0.1}, 0.01, 20, -MaxDB},
+		{"shorter test", []float64{1, 1, 5}, []float64{0.9, 0.9}, 0.01, 20, 20},
+		{"empty", nil, nil, 0, MaxDB, MaxDB},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MSE(tt.reference, tt.test); !approx(got, tt.mse, 1e-12) {
+				t.Errorf("MSE = %v, want %v", got, tt.mse)
+			}
+			if got := PSNR(tt.reference, tt.test, 1); !approx(got, tt.psnr, 1e-9) {
+				t.Errorf("PSNR = %v, want %v", got, tt.psnr)
+			}
+			if got := SNR(tt.reference, tt.test); !approx(got, tt.snr, 1e-9) {
+				t.Errorf("SNR = %v, want %v", got, tt.snr)
+			}
+		})
+	}
+}
+
+func TestSegmentalSNR(t *testing.T) {
+	loud := []float64{1, -1, 1, -1}
+	tests := []struct {
+		name      string
+		reference []float64
+		test      []float64
+		segment   int
+		want      float64
+	}{
+		{"clamped high", loud, loud, 2, segmentMaxSNR},
+		{"mean of clamped segments", loud, []float64{0.9, -0.9, 0.99, -0.99}, 2, (20 + segmentMaxSNR) / 2},
+		{"inverted", loud, []float64{-1, 1, -1, 1}, 4, -20 * math.Log10(2)},
+		{"clamped low", loud, []float64{-4, 4, -4, 4}, 4, segmentMinSNR},
+		{"silence skipped", []float64{0, 0, 1, -1}, []float64{0.5, 0.5, 0.9, -0.9}, 2, 20},
+		{"all silent", []float64{0, 0}, []float64{0.5, 0.5}, 2, segmentMaxSNR},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SegmentalSNR(tt.reference, tt.test, tt.segment); !approx(got, tt.want, 1e-9) {
+				t.Errorf("SegmentalSNR = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogSpectralDistance(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	noise := make([]float64, 8192)
+	for i := range noise {
+		noise[i] = rng.Float64() - 0.5
+	}
+	scaled := make([]float64, len(noise))
+	for i, v := range noise {
+		scaled[i] = 2 * v
+	}
+
+	tests := []struct {
+		name      string
+		test      []float64
+		want      float64
+		tolerance float64
+	}{
+		{"identical", noise, 0, 1e-12},
+		{"double amplitude", scaled, 20 * math.Log10(2), 1e-3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LogSpectralDistance(noise, tt.test, 1024); !approx(got, tt.want, tt.tolerance) {
+				t.Errorf("LogSpectralDistance = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package robustness
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	mathrand "math/rand"
+
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/stego"
+)
+
+const (
+	DefaultKey        = "robustness"
+	DefaultSecretSize = 256
+	DefaultTrials     = 5
+	DefaultTrimFrames = 1
+)
+
+var DefaultBERs = []float64{1e-6, 1e-5, 1e-4}
+
+var ErrEmptyCarrier = errors.New("carrier cannot be empty")
+
+// Config controls a harness run. Zero values fall back to the defaults
+// above; Secret, when nil, is SecretSize random bytes.
+type Config struct {
+	Options    stego.EmbedOptions
+	Secret     []byte
+	SecretSize int
+	Trials     int
+	Seed       int64
+	Methods    []stego.Method
+	Transforms []Transform
+}
+
+type Result struct {
+	Method       string         `json:"method"`
+	LSBBits      int            `json:"lsb_bits,omitempty"`
+	Transform    string         `json:"transform"`
+	Trials       int            `json:"trials"`
+	Recovered    int            `json:"recovered"`
+	RecoveryRate float64        `json:"recovery_rate"`
+	Failures     map[string]int `json:"failures,omitempty"`
+	Skipped      string         `json:"skipped,omitempty"`
+}
+
+type MethodSummary struct {
+	Method       string   `json:"method"`
+	LSBBits      int      `json:"lsb_bits,omitempty"`
+	EmbedError   string   `json:"embed_error,omitempty"`
+	RecoveryRate float64  `json:"recovery_rate"`
+	Survives     []string `json:"survives"`
+	Fails        []string `json:"fails"`
+}
+
+type Report struct {
+	CarrierSize int             `json:"carrier_size"`
+	SecretSize  int             `json:"secret_size"`
+	Seed        int64           `json:"seed"`
+	Methods     []MethodSummary `json:"methods"`
+	Results     []Result        `json:"results"`
+}
+
+// Run embeds the secret with every method and bit depth, applies each
+// transform to the stego file and counts how often the exact secret can still
+// be extracted. Transforms that do not apply to the stego file are reported
+// as skipped and left out of the method's recovery rate.
+func Run(carrier []byte, cfg Config) (*Report, error) {
+	if len(carrier) == 0 {
+		return nil, ErrEmptyCarrier
+	}
+
+	if cfg.Options.Key == "" {
+		cfg.Options.Key = DefaultKey
+	}
+	if cfg.Trials <= 0 {
+		cfg.Trials = DefaultTrials
+	}
+	if cfg.Methods == nil {
+		cfg.Methods = stego.Methods()
+	}
+	if cfg.Transforms == nil {
+		cfg.Transforms = DefaultTransforms(carrier, DefaultTrimFrames, DefaultBERs)
+	}
+	if cfg.Secret == nil {
+		if cfg.SecretSize <= 0 {
+			cfg.SecretSize = DefaultSecretSize
+		}
+		cfg.Secret = make([]byte, cfg.SecretSize)
+		if _, err := rand.Read(cfg.Secret); err != nil {
+			return nil, err
+		}
+	}
+
+	report := &Report{
+		CarrierSize: len(carrier),
+		SecretSize:  len(cfg.Secret),
+		Seed:        cfg.Seed,
+	}
+
+	for _, method := range cfg.Methods {
+		for _, bits := range stego.BitDepths(method) {
+			opts := cfg.Options
+			opts.Bits = bits
+			summary := MethodSummary{Method: method.Name(), LSBBits: bits, Survives: []string{}, Fails: []string{}}
+
+			embedded, err := method.Embed(carrier, cfg.Secret, opts)
+			if err != nil {
+				summary.EmbedError = err.Error()
+				report.Methods = append(report.Methods, summary)
+				continue
+			}
+
+			var recovered, trials int
+			for i, transform := range cfg.Transforms {
+				rng := mathrand.New(mathrand.NewSource(cfg.Seed + int64(i)))
+				result := runTransform(method, embedded, cfg.Secret, opts.Key, transform, cfg.Trials, rng)
+				result.LSBBits = bits
+				report.Results = append(report.Results, result)
+
+				if result.Skipped != "" {
+					continue
+				}
+				recovered += result.Recovered
+				trials += result.Trials
+				if result.Recovered == result.Trials {
+					summary.Survives = append(summary.Survives, transform.Name)
+				} else {
+					summary.Fails = append(summary.Fails, transform.Name)
+				}
+			}
+			if trials > 0 {
+				summary.RecoveryRate = float64(recovered) / float64(trials)
+			}
+			report.Methods = append(report.Methods, summary)
+		}
+	}
+
+	return report, nil
+}
+
+func runTransform(method stego.Method, embedded, secret []byte, key string, transform Transform, trials int, rng *mathrand.Rand) Result {
+	result := Result{Method: method.Name(), Transform: transform.Name}
+	if !transform.Random {
+		trials = 1
+	}
+
+	for i := 0; i < trials; i++ {
+		// A transform that cannot run on the carrier at all is skipped; one
+		// that only fails for some draws counts those draws as failures.
+		modified, err := transform.Apply(embedded, rng)
+		if err != nil && i == 0 {
+			result.Skipped = err.Error()
+			return result
+		}
+		if err != nil {
+			result.Trials++
+			result.fail(err.Error())
+			continue
+		}
+
+		result.Trials++
+		extracted, err := method.Extract(modified, key)
+		switch {
+		case err != nil:
+			result.fail(err.Error())
+		case !bytes.Equal(extracted.Message, secret):
+			result.fail("extracted payload differs from the secret")
+		default:
+			result.Recovered++
+		}
+	}
+
+	result.RecoveryRate = float64(result.Recovered) / float64(result.Trials)
+	return result
+}
+
+func (r *Result) fail(reason string) {
+	if r.Failures == nil {
+		r.Failures = make(map[string]int)
+	}
+	r.Failures[reason]++
+}
+
+// Rate returns the recovery rate measured for one method, bit depth and
+// transform, and false when that combination was not run or was skipped.
+func (r *Report) Rate(method string, bits int, transform string) (float64, bool) {
+	for _, result := range r.Results {
+		if result.Method == method && result.LSBBits == bits && result.Transform == transform {
+			return result.RecoveryRate, result.Skipped == ""
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,119 @@
+package robustness_test
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/robustness"
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/robustness/robustnesstest"
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/stego"
+)
+
+// testMP3 builds an MPEG-1 Layer III stream of 128 kbps, 44.1 kHz frames
+// with random contents behind a small ID3v2 tag.
+func testMP3(frames int, seed int64) []byte {
+	rng := rand.New(rand.NewSource(seed))
+	out := append([]byte("ID3\x03\x00\x00\x00\x00\x00\x10"), make([]byte, 16)...)
+	for i := 0; i < frames; i++ {
+		var padding byte
+		if i%3 == 0 {
+			padding = 1
+		}
+		frame := make([]byte, 144*128000/44100+int(padding))
+		rng.Read(frame[4:])
+		frame[0], frame[1], frame[2], frame[3] = 0xFF, 0xFB, 0x90|padding<<1, 0x64
+		out = append(out, frame...)
+	}
+	return out
+}
+
+func TestRecoveryUnderTransforms(t *testing.T) {
+	carrier := testMP3(400, 1)
+	report := robustnesstest.Check(t, carrier, robustness.Config{
+		SecretSize: 64,
+		Trials:     2,
+		Seed:       1,
+		Methods:    []stego.Method{stego.NewLSBSteganography(), stego.NewChunkedSteganography()},
+	})
+
+	tests := []struct {
+		method    string
+		bits      int
+		transform string
+		survives  bool
+	}{
+		{"lsb", 1, "none", true},
+		{"lsb", 2, "none", true},
+		{"lsb", 1, "id3v2-add", false},
+		{"chunked", 1, "none", true},
+		{"chunked", 1, "trim-start-1", true},
+		{"chunked", 2, "id3v2-add", true},
+		{"chunked", 1, "concat-append", true},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s-%d/%s", tt.method, tt.bits, tt.transform), func(t *testing.T) {
+			if tt.survives {
+				robustnesstest.RequireRecovery(t, report, tt.method, tt.bits, tt.transform, 1)
+				return
+			}
+			rate, ok := report.Rate(tt.method, tt.bits, tt.transform)
+			if !ok {
+				t.Fatalf("no result for %s under %s", tt.method, tt.transform)
+			}
+			if rate != 0 {
+				t.Errorf("%s under %s recovered %.0f%%, want 0%%", tt.method, tt.transform, rate*100)
+			}
+		})
+	}
+}
+
+func TestTransformErrors(t *testing.T) {
+	errDraw := errors.New("draw failed")
+	tests := []struct {
+		name        string
+		failOn      int
+		wantSkipped bool
+		wantTrials  int
+		wantRate    float64
+	}{
+		{"no error", -1, false, 4, 1},
+		{"first trial", 0, true, 0, 0},
+		{"later trial", 2, false, 4, 0.75},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trial := 0
+			transform := robustness.Transform{
+				Name:   "flaky",
+				Random: true,
+				Apply: func(data []byte, _ *rand.Rand) ([]byte, error) {
+					defer func() { trial++ }()
+					if trial == tt.failOn {
+						return nil, errDraw
+					}
+					return data, nil
+				},
+			}
+
+			report := robustnesstest.Check(t, testMP3(100, 2), robustness.Config{
+				SecretSize: 16,
+				Trials:     4,
+				Methods:    []stego.Method{stego.NewLSBSteganography()},
+				Transforms: []robustness.Transform{transform},
+			})
+
+			result := report.Results[0]
+			if skipped := result.Skipped != ""; skipped != tt.wantSkipped {
+				t.Fatalf("skipped = %q, want skipped %t", result.Skipped, tt.wantSkipped)
+			}
+			if result.Trials != tt.wantTrials || result.RecoveryRate != tt.wantRate {
+				t.Errorf("trials = %d, rate = %v, want %d and %v", result.Trials, result.RecoveryRate, tt.wantTrials, tt.wantRate)
+			}
+			if tt.failOn > 0 && result.Failures[errDraw.Error()] != 1 {
+				t.Errorf("failures = %v, want one %q", result.Failures, errDraw)
+			}
+		})
+	}
+}
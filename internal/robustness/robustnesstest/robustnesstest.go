@@ -0,0 +1,33 @@
+package robustnesstest
+
+import (
+	"testing"
+
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/robustness"
+)
+
+// Check runs the harness from a test and fails it if the run itself errors.
+func Check(tb testing.TB, carrier []byte, cfg robustness.Config) *robustness.Report {
+	tb.Helper()
+
+	report, err := robustness.Run(carrier, cfg)
+	if err != nil {
+		tb.Fatalf("robustness harness: %v", err)
+	}
+	return report
+}
+
+// RequireRecovery fails the test when method at the given bit depth recovers
+// the secret after transform less often than minRate.
+func RequireRecovery(tb testing.TB, report *robustness.Report, method string, bits int, transform string, minRate float64) {
+	tb.Helper()
+
+	rate, ok := report.Rate(method, bits, transform)
+	if !ok {
+		tb.Fatalf("robustness: no result for %s (bits %d) under %s", method, bits, transform)
+	}
+	if rate < minRate {
+		tb.Errorf("robustness: %s (bits %d) under %s recovered %.0f%%, want at least %.0f%%",
+			method, bits, transform, rate*100, minRate*100)
+	}
+}
@@ -0,0 +1,274 @@
+package robustness
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/stego"
+)
+
+var ErrNotApplicable = errors.New("transformation does not apply to this carrier")
+
+const (
+	id3v1TagSize = 128
+
+	// addedTagPadding mirrors the padding taggers leave so later edits do
+	// not have to rewrite the whole file.
+	addedTagPadding = 1024
+)
+
+// Transform is one manipulation a stego file may go through after it leaves
+// the embedder. Random transforms draw from rng and are repeated for every
+// trial; deterministic ones run once.
+type Transform struct {
+	Name   string
+	Random bool
+	Apply  func(data []byte, rng *rand.Rand) ([]byte, error)
+}
+
+func Identity() Transform {
+	return Transform{
+		Name: "none",
+		Apply: func(data []byte, _ *rand.Rand) ([]byte, error) {
+			return data, nil
+		},
+	}
+}
+
+// AddID3v2 prepends a tag, replacing any existing ID3v2 tag.
+func AddID3v2() Transform {
+	return Transform{
+		Name: "id3v2-add",
+		Apply: func(data []byte, _ *rand.Rand) ([]byte, error) {
+			_, body, _ := splitTags(data)
+			tag := buildID3v2([][2]string{{"TIT2", "Robustness test"}, {"TPE1", "stegocli"}}, addedTagPadding)
+			return concat(tag, body, id3v1Of(data)), nil
+		},
+	}
+}
+
+// AddID3v1 appends a tag, replacing any existing ID3v1 tag.
+func AddID3v1() Transform {
+	return Transform{
+		Name: "id3v1-add",
+		Apply: func(data []byte, _ *rand.Rand) ([]byte, error) {
+			head, body, _ := splitTags(data)
+			tag := make([]byte, id3v1TagSize)
+			copy(tag, "TAG")
+			copy(tag[3:33], "Robustness test")
+			tag[127] = 0xFF
+			return concat(head, body, tag), nil
+		},
+	}
+}
+
+func RemoveID3() Transform {
+	return Transform{
+		Name: "id3-remove",
+		Apply: func(data []byte, _ *rand.Rand) ([]byte, error) {
+			head, body, tail := splitTags(data)
+			if len(head) == 0 && len(tail) == 0 {
+				return nil, ErrNotApplicable
+			}
+			return concat(body), nil
+		},
+	}
+}
+
+// TrimStart drops the first frames audio frames, keeping any leading tag,
+// as a cutting tool that works on frame boundaries would.
+func TrimStart(frames int) Transform {
+	return Transform{
+		Name: fmt.Sprintf("trim-start-%d", frames),
+		Apply: func(data []byte, _ *rand.Rand) ([]byte, error) {
+			offsets, _, err := frameBounds(data)
+			if err != nil || frames >= len(offsets) {
+				return nil, ErrNotApplicable
+			}
+			return concat(data[:offsets[0]], data[offsets[frames]:]), nil
+		},
+	}
+}
+
+// TrimEnd drops the last frames audio frames, keeping anything that follows
+// the final frame such as an ID3v1 tag.
+func TrimEnd(frames int) Transform {
+	return Transform{
+		Name: fmt.Sprintf("trim-end-%d", frames),
+		Apply: func(data []byte, _ *rand.Rand) ([]byte, error) {
+			offsets, ends, err := frameBounds(data)
+			if err != nil || frames >= len(offsets) {
+				return nil, ErrNotApplicable
+			}
+			last := len(offsets) - 1
+			return concat(data[:offsets[last-frames+1]], data[ends[last]:]), nil
+		},
+	}
+}
+
+// FlipBits flips every bit independently with probability ber. The number
+// of flips is drawn from the matching binomial distribution, so low error
+// rates on large files do not need one random draw per bit.
+func FlipBits(ber float64) Transform {
+	return Transform{
+		Name:   fmt.Sprintf("ber-%g", ber),
+		Random: true,
+		Apply: func(data []byte, rng *rand.Rand) ([]byte, error) {
+			if len(data) == 0 || ber <= 0 {
+				return nil, ErrNotApplicable
+			}
+			result := concat(data)
+			totalBits := len(result) * 8
+			flips := binomial(rng, totalBits, ber)
+			flipped := make(map[int]bool, flips)
+			for len(flipped) < flips {
+				bit := rng.Intn(totalBits)
+				if !flipped[bit] {
+					flipped[bit] = true
+					result[bit/8] ^= 1 << (bit % 8)
+				}
+			}
+			return result, nil
+		},
+	}
+}
+
+// Append concatenates other after the stego file, e.g. two tracks joined
+// into one stream.
+func Append(other []byte) Transform {
+	return Transform{
+		Name: "concat-append",
+		Apply: func(data []byte, _ *rand.Rand) ([]byte, error) {
+			_, body, _ := splitTags(other)
+			return concat(data, body), nil
+		},
+	}
+}
+
+// Prepend places the audio of other in front of the stego file.
+func Prepend(other []byte) Transform {
+	return Transform{
+		Name: "concat-prepend",
+		Apply: func(data []byte, _ *rand.Rand) ([]byte, error) {
+			_, body, _ := splitTags(other)
+			return concat(body, data), nil
+		},
+	}
+}
+
+// Remux rewrites the file the way a stream-copying muxer does: only the
+// audio frames are copied, unsynchronised bytes and the old tags are dropped,
+// and a fresh ID3v2 tag naming the muxer is written in front.
+func Remux() Transform {
+	return Transform{
+		Name: "remux",
+		Apply: func(data []byte, _ *rand.Rand) ([]byte, error) {
+			offsets, ends, err := frameBounds(data)
+			if err != nil {
+				return nil, ErrNotApplicable
+			}
+			result := buildID3v2([][2]string{{"TSSE", "Lavf58.76.100"}}, 0)
+			for i := range offsets {
+				result = append(result, data[offsets[i]:ends[i]]...)
+			}
+			return result, nil
+		},
+	}
+}
+
+// DefaultTransforms is the manipulation suite used by the CLI. other is the
+// audio concatenated with the stego file, normally the cover itself.
+func DefaultTransforms(other []byte, trimFrames int, bers []float64) []Transform {
+	transforms := []Transform{
+		Identity(),
+		AddID3v2(),
+		AddID3v1(),
+		RemoveID3(),
+		TrimStart(trimFrames),
+		TrimEnd(trimFrames),
+	}
+	for _, ber := range bers {
+		transforms = append(transforms, FlipBits(ber))
+	}
+	return append(transforms, Append(other), Prepend(other), Remux())
+}
+
+// splitTags separates a leading ID3v2 tag and a trailing ID3v1 tag from the
+// data between them.
+func splitTags(data []byte) (head, body, tail []byte) {
+	body = data
+	if tag := stego.ParseID3v2(data); tag != nil && tag.Size <= len(data) {
+		head, body = data[:tag.Size], data[tag.Size:]
+	}
+	if stego.ParseID3v1(body) != nil {
+		split := len(body) - id3v1TagSize
+		body, tail = body[:split], body[split:]
+	}
+	return head, body, tail
+}
+
+func id3v1Of(data []byte) []byte {
+	_, _, tail := splitTags(data)
+	return tail
+}
+
+func frameBounds(data []byte) ([]int, []int, error) {
+	frames, offsets, err := stego.ScanMP3Frames(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	ends := make([]int, len(frames))
+	for i, frame := range frames {
+		ends[i] = offsets[i] + frame.Size
+	}
+	return offsets, ends, nil
+}
+
+// buildID3v2 writes an ID3v2.3 tag holding ISO-8859-1 text frames.
+func buildID3v2(frames [][2]string, padding int) []byte {
+	var body []byte
+	for _, frame := range frames {
+		size := len(frame[1]) + 1
+		body = append(body, frame[0]...)
+		body = append(body, byte(size>>24), byte(size>>16), byte(size>>8), byte(size), 0, 0, 0)
+		body = append(body, frame[1]...)
+	}
+	body = append(body, make([]byte, padding)...)
+
+	size := len(body)
+	header := []byte{'I', 'D', '3', 3, 0, 0,
+		byte(size >> 21 & 0x7F), byte(size >> 14 & 0x7F), byte(size >> 7 & 0x7F), byte(size & 0x7F)}
+	return append(header, body...)
+}
+
+func concat(parts ...[]byte) []byte {
+	total := 0
+	for _, part := range parts {
+		total += len(part)
+	}
+	result := make([]byte, 0, total)
+	for _, part := range parts {
+		result = append(result, part...)
+	}
+	return result
+}
+
+// binomial draws the number of successes in n trials of probability p, using
+// the normal approximation once the mean is large enough.
+func binomial(rng *rand.Rand, n int, p float64) int {
+	mean := float64(n) * p
+	if mean > 30 {
+		k := int(math.Round(mean + rng.NormFloat64()*math.Sqrt(mean*(1-p))))
+		return min(max(k, 0), n)
+	}
+
+	// Poisson approximation by inversion; p is small whenever mean is.
+	k, threshold, product := 0, math.Exp(-mean), rng.Float64()
+	for product > threshold && k < n {
+		k++
+		product *= rng.Float64()
+	}
+	return k
+}
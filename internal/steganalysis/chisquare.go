@@ -0,0 +1,143 @@
+package steganalysis
+
+import "errors"
+
+var ErrInvalidWindow = errors.New("window size and step must be positive")
+
+// minExpected is the smallest expected category count that still takes part in
+// the test; sparser groups would dominate the statistic with noise.
+const minExpected = 5
+
+type ChiSquareOptions struct {
+	Bits       int
+	WindowSize int
+	Step       int
+	Cumulative bool
+}
+
+type ChiSquareWindow struct {
+	Start            int     `json:"start"`
+	End              int     `json:"end"`
+	Statistic        float64 `json:"statistic"`
+	DegreesOfFreedom int     `json:"degrees_of_freedom"`
+	Probability      float64 `json:"probability"`
+}
+
+type ChiSquareResult struct {
+	Bits            int               `json:"bits"`
+	WindowSize      int               `json:"window_size"`
+	Step            int               `json:"step"`
+	Cumulative      bool              `json:"cumulative"`
+	Units           int               `json:"units"`
+	Windows         []ChiSquareWindow `json:"windows"`
+	MeanProbability float64           `json:"mean_probability"`
+	MaxProbability  float64           `json:"max_probability"`
+	Overall         ChiSquareWindow   `json:"overall"`
+}
+
+// ChiSquare runs the Westfeld-Pfitzmann pairs-of-values test generalised to
+// k low bits: values that differ only in their k low bits form a group, and
+// LSB replacement with random data equalises the counts inside each group.
+// The returned probability is P(chi-square >= statistic), so values near 1
+// indicate the group counts are suspiciously uniform, i.e. embedding.
+func ChiSquare(values []int, bits int) ChiSquareWindow {
+	if bits < 1 {
+		bits = 1
+	}
+	groupSize := 1 << bits
+
+	counts := make(map[int][]int)
+	for _, v := range values {
+		key := v >> bits
+		group, ok := counts[key]
+		if !ok {
+			group = make([]int, groupSize)
+			counts[key] = group
+		}
+		group[v&(groupSize-1)]++
+	}
+
+	var statistic float64
+	df := 0
+	for _, group := range counts {
+		total := 0
+		for _, n := range group {
+			total += n
+		}
+		expected := float64(total) / float64(groupSize)
+		if expected < minExpected {
+			continue
+		}
+
+		for _, n := range group {
+			diff := float64(n) - expected
+			statistic += diff * diff / expected
+		}
+		df += groupSize - 1
+	}
+
+	window := ChiSquareWindow{End: len(values), Statistic: statistic, DegreesOfFreedom: df}
+	if df > 0 {
+		window.Probability = chiSquareSurvival(statistic, df)
+	}
+	return window
+}
+
+func ChiSquareWindows(values []int, opts ChiSquareOptions) (*ChiSquareResult, error) {
+	if opts.Bits < 1 {
+		opts.Bits = 1
+	}
+	if opts.WindowSize <= 0 {
+		return nil, ErrInvalidWindow
+	}
+	if opts.Step <= 0 {
+		opts.Step = opts.WindowSize
+	}
+
+	result := &ChiSquareResult{
+		Bits:       opts.Bits,
+		WindowSize: opts.WindowSize,
+		Step:       opts.Step,
+		Cumulative: opts.Cumulative,
+		Units:      len(values),
+		Overall:    ChiSquare(values, opts.Bits),
+	}
+
+	for start := 0; start < len(values); start += opts.Step {
+		end := start + opts.WindowSize
+		if end > len(values) {
+			end = len(values)
+		}
+		windowStart := start
+		if opts.Cumulative {
+			windowStart = 0
+		}
+
+		window := ChiSquare(values[windowStart:end], opts.Bits)
+		window.Start = windowStart
+		window.End = end
+		result.Windows = append(result.Windows, window)
+
+		result.MeanProbability += window.Probability
+		if window.Probability > result.MaxProbability {
+			result.MaxProbability = window.Probability
+		}
+		if end == len(values) {
+			break
+		}
+	}
+
+	if len(result.Windows) > 0 {
+		result.MeanProbability /= float64(len(result.Windows))
+	}
+
+	return result, nil
+}
+
+func BytesToValues(data []byte) []int {
+	values := make([]int, len(data))
+	for i, b := range data {
+		values[i] = int(b)
+	}
+	return values
+}
@@ -0,0 +1,99 @@
+package steganalysis
+
+import (
+	"math"
+	"testing"
+)
+
+func TestChiSquareSurvival(t *testing.T) {
+	tests := []struct {
+		x    float64
+		df   int
+		want float64
+	}{
+		{0, 4, 1},
+		{2, 2, math.Exp(-1)},
+		{10, 2, math.Exp(-5)},
+		{3.841459, 1, 0.05},
+		{6.634897, 1, 0.01},
+		{18.307038, 10, 0.05},
+		{124.342113, 100, 0.05},
+	}
+	for _, tt := range tests {
+		if got := chiSquareSurvival(tt.x, tt.df); math.Abs(got-tt.want) > 1e-6 {
+			t.Errorf("chiSquareSurvival(%v, %d) = %v, want %v", tt.x, tt.df, got, tt.want)
+		}
+	}
+}
+
+func repeat(counts map[int]int) []int {
+	var values []int
+	for value := 0; value < 256; value++ {
+		for i := 0; i < counts[value]; i++ {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func TestChiSquare(t *testing.T) {
+	tests := []struct {
+		name      string
+		values    []int
+		bits      int
+		statistic float64
+		df        int
+		prob      float64
+	}{
+		{"equal pair", repeat(map[int]int{0: 10, 1: 10}), 1, 0, 1, 1},
+		{"skewed pair", repeat(map[int]int{0: 15, 1: 5}), 1, 5, 1, 0.025347},
+		{"two pairs", repeat(map[int]int{0: 15, 1: 5, 6: 10, 7: 10}), 1, 5, 2, math.Exp(-2.5)},
+		{"sparse pair ignored", repeat(map[int]int{0: 3, 1: 3}), 1, 0, 0, 0},
+		{"two bits", repeat(map[int]int{4: 10, 5: 10, 6: 10, 7: 30}), 2, 20, 3, 1.697424e-4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ChiSquare(tt.values, tt.bits)
+			if math.Abs(got.Statistic-tt.statistic) > 1e-9 || got.DegreesOfFreedom != tt.df {
+				t.Errorf("statistic = %v with %d df, want %v with %d", got.Statistic, got.DegreesOfFreedom, tt.statistic, tt.df)
+			}
+			if math.Abs(got.Probability-tt.prob) > 1e-6 {
+				t.Errorf("probability = %v, want %v", got.Probability, tt.prob)
+			}
+		})
+	}
+}
+
+func TestChiSquareWindows(t *testing.T) {
+	values := make([]int, 10)
+	tests := []struct {
+		name   string
+		opts   ChiSquareOptions
+		ranges [][2]int
+		err    error
+	}{
+		{"tiled", ChiSquareOptions{WindowSize: 4}, [][2]int{{0, 4}, {4, 8}, {8, 10}}, nil},
+		{"overlapping", ChiSquareOptions{WindowSize: 6, Step: 4}, [][2]int{{0, 6}, {4, 10}}, nil},
+		{"cumulative", ChiSquareOptions{WindowSize: 5, Cumulative: true}, [][2]int{{0, 5}, {0, 10}}, nil},
+		{"no window", ChiSquareOptions{}, nil, ErrInvalidWindow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ChiSquareWindows(values, tt.opts)
+			if err != tt.err {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			if len(result.Windows) != len(tt.ranges) {
+				t.Fatalf("got %d windows, want %d", len(result.Windows), len(tt.ranges))
+			}
+			for i, r := range tt.ranges {
+				if w := result.Windows[i]; w.Start != r[0] || w.End != r[1] {
+					t.Errorf("window %d = [%d,%d), want [%d,%d)", i, w.Start, w.End, r[0], r[1])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package steganalysis
+
+import "math"
+
+// chiSquareSurvival returns P(X > x) for a chi-square distribution with df
+// degrees of freedom, i.e. the regularized upper incomplete gamma Q(df/2, x/2).
+func chiSquareSurvival(x float64, df int) float64 {
+	if df <= 0 || x <= 0 {
+		return 1
+	}
+	return upperGamma(float64(df)/2, x/2)
+}
+
+func upperGamma(a, x float64) float64 {
+	if x < a+1 {
+		return 1 - lowerGammaSeries(a, x)
+	}
+	return upperGammaFraction(a, x)
+}
+
+func lowerGammaSeries(a, x float64) float64 {
+	lgamma, _ := math.Lgamma(a)
+	sum := 1 / a
+	term := sum
+	for n := 1; n < 1000; n++ {
+		term *= x / (a + float64(n))
+		sum += term
+		if math.Abs(term) < math.Abs(sum)*1e-15 {
+			break
+		}
+	}
+	return sum * math.Exp(-x+a*math.Log(x)-lgamma)
+}
+
+func upperGammaFraction(a, x float64) float64 {
+	const tiny = 1e-300
+	lgamma, _ := math.Lgamma(a)
+
+	b := x + 1 - a
+	c := 1 / tiny
+	d := 1 / b
+	h := d
+	for i := 1; i < 1000; i++ {
+		an := -float64(i) * (float64(i) - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = b + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < 1e-15 {
+			break
+		}
+	}
+	return math.Exp(-x+a*math.Log(x)-lgamma) * h
+}
@@ -0,0 +1,39 @@
+package steganalysis
+
+import "github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/audio"
+
+type ChannelAnalysis struct {
+	Channel int        `json:"channel"`
+	RS      *RSResult  `json:"rs"`
+	SPA     *SPAResult `json:"spa"`
+}
+
+type PCMAnalysis struct {
+	Channels         []ChannelAnalysis `json:"channels"`
+	EstimatedRateRS  float64           `json:"estimated_rate_rs"`
+	EstimatedRateSPA float64           `json:"estimated_rate_spa"`
+}
+
+// AnalyzePCM runs RS and sample pair analysis on every channel separately,
+// since interleaved neighbours belong to different signals.
+func AnalyzePCM(wav *audio.WAV) *PCMAnalysis {
+	analysis := &PCMAnalysis{}
+	for c := 0; c < wav.Channels; c++ {
+		samples := wav.Channel(c)
+		channel := ChannelAnalysis{
+			Channel: c,
+			RS:      RSAnalysis(samples),
+			SPA:     SamplePairAnalysis(samples),
+		}
+		analysis.Channels = append(analysis.Channels, channel)
+		analysis.EstimatedRateRS += channel.RS.EstimatedRate
+		analysis.EstimatedRateSPA += channel.SPA.EstimatedRate
+	}
+
+	if wav.Channels > 0 {
+		analysis.EstimatedRateRS /= float64(wav.Channels)
+		analysis.EstimatedRateSPA /= float64(wav.Channels)
+	}
+
+	return analysis
+}
@@ -0,0 +1,113 @@
+package steganalysis
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestSamplePairAnalysisCounts(t *testing.T) {
+	tests := []struct {
+		name       string
+		samples    []int
+		x, y, z, w int
+		rate       float64
+	}{
+		// (1,2) is in X, (2,2) in Z, (2,5) in Y and (5,4) in Y and W.
+		{"mixed", []int{1, 2, 2, 5, 4}, 1, 2, 1, 1, 1},
+		{"constant", []int{3, 3, 3}, 0, 0, 2, 0, 0},
+		{"single sample", []int{7}, 0, 0, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SamplePairAnalysis(tt.samples)
+			if got.X != tt.x || got.Y != tt.y || got.Z != tt.z || got.W != tt.w {
+				t.Errorf("X, Y, Z, W = %d, %d, %d, %d, want %d, %d, %d, %d",
+					got.X, got.Y, got.Z, got.W, tt.x, tt.y, tt.z, tt.w)
+			}
+			if math.Abs(got.EstimatedRate-tt.rate) > 1e-9 {
+				t.Errorf("rate = %v, want %v", got.EstimatedRate, tt.rate)
+			}
+		})
+	}
+}
+
+// smoothSignal is a slowly varying signal with a little noise, the kind of
+// cover both rate estimators are designed for.
+func smoothSignal(n int, seed int64) []int {
+	rng := rand.New(rand.NewSource(seed))
+	samples := make([]int, n)
+	phase := 0.0
+	for i := range samples {
+		phase += 0.03 + 0.02*math.Sin(float64(i)*0.0001)
+		samples[i] = int(math.Round(300*math.Sin(phase/10) + 80*math.Sin(phase*0.37) + 3*rng.NormFloat64()))
+	}
+	return samples
+}
+
+// replaceLSBs overwrites the LSB of a fraction rate of the samples with
+// random bits.
+func replaceLSBs(samples []int, rate float64, seed int64) []int {
+	rng := rand.New(rand.NewSource(seed))
+	stego := make([]int, len(samples))
+	for i, v := range samples {
+		if rng.Float64() < rate {
+			v = v&^1 | rng.Intn(2)
+		}
+		stego[i] = v
+	}
+	return stego
+}
+
+func TestRateEstimators(t *testing.T) {
+	cover := smoothSignal(400000, 1)
+	tests := []struct {
+		name string
+		rate float64
+	}{
+		{"cover", 0},
+		{"quarter", 0.25},
+		{"half", 0.5},
+		{"three quarters", 0.75},
+	}
+	estimators := []struct {
+		name     string
+		estimate func([]int) float64
+	}{
+		{"rs", func(s []int) float64 { return RSAnalysis(s).EstimatedRate }},
+		{"spa", func(s []int) float64 { return SamplePairAnalysis(s).EstimatedRate }},
+	}
+	for _, tt := range tests {
+		stego := replaceLSBs(cover, tt.rate, 2)
+		for _, e := range estimators {
+			t.Run(tt.name+"/"+e.name, func(t *testing.T) {
+				if got := e.estimate(stego); math.Abs(got-tt.rate) > 0.05 {
+					t.Errorf("estimated rate %.3f, want %.2f", got, tt.rate)
+				}
+			})
+		}
+	}
+}
+
+func TestRSCounts(t *testing.T) {
+	tests := []struct {
+		name    string
+		samples []int
+		flip    func(int) int
+		want    RSCounts
+	}{
+		{"flat positive", []int{0, 0, 0, 0}, flipPositive, RSCounts{Regular: 1}},
+		{"flat negative", []int{0, 0, 0, 0}, flipNegative, RSCounts{Regular: 1}},
+		// The flat group roughens to 0 1 1 0, the zigzag smooths to 0 2 1 3
+		// and the ramp keeps its smoothness as 0 0 3 3.
+		{"mixed", []int{0, 0, 0, 0, 0, 3, 0, 3, 0, 1, 2, 3}, flipPositive, RSCounts{Regular: 1.0 / 3, Singular: 1.0 / 3}},
+		{"too short", []int{1, 2, 3}, flipPositive, RSCounts{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rsCounts(tt.samples, tt.flip); got != tt.want {
+				t.Errorf("rsCounts = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package steganalysis
+
+type SPAResult struct {
+	Pairs         int     `json:"pairs"`
+	X             int     `json:"x"`
+	Y             int     `json:"y"`
+	Z             int     `json:"z"`
+	W             int     `json:"w"`
+	EstimatedRate float64 `json:"estimated_rate"`
+}
+
+// SamplePairAnalysis estimates the LSB replacement rate from the trace sets
+// of adjacent sample pairs (Dumitrescu, Wu and Wang).
+func SamplePairAnalysis(samples []int) *SPAResult {
+	result := &SPAResult{}
+
+	for i := 0; i+1 < len(samples); i++ {
+		u, v := samples[i], samples[i+1]
+		result.Pairs++
+
+		even := v&1 == 0
+		switch {
+		case u == v:
+			result.Z++
+		case (even && u < v) || (!even && u > v):
+			result.X++
+		default:
+			result.Y++
+			if u>>1 == v>>1 {
+				result.W++
+			}
+		}
+	}
+
+	if result.Pairs == 0 {
+		return result
+	}
+
+	a := 0.5 * float64(result.W+result.Z)
+	b := float64(2*result.X - result.Pairs)
+	c := float64(result.Y - result.X)
+	if rate, ok := smallerRoot(a, b, c); ok {
+		result.EstimatedRate = clampRate(rate)
+	}
+
+	return result
+}
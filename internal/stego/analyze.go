@@ -0,0 +1,124 @@
+package stego
+
+import (
+	"bytes"
+
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/audio"
+)
+
+type ByteRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+func (r ByteRange) Length() int {
+	return r.End - r.Start
+}
+
+type MP3Analysis struct {
+	FileSize         int            `json:"file_size"`
+	ID3v2            *ID3v2Tag      `json:"id3v2,omitempty"`
+	ID3v1            *ID3v1Tag      `json:"id3v1,omitempty"`
+	Version          string         `json:"version"`
+	FrameCount       int            `json:"frame_count"`
+	AudioStart       int            `json:"audio_start"`
+	AudioEnd         int            `json:"audio_end"`
+	SampleRate       int            `json:"sample_rate"`
+	ChannelMode      string         `json:"channel_mode"`
+	BitrateMode      string         `json:"bitrate_mode"`
+	VBRHeader        string         `json:"vbr_header,omitempty"`
+	BitrateHistogram map[int]int    `json:"bitrate_histogram"`
+	AverageBitrate   float64        `json:"average_bitrate_kbps"`
+	DurationSeconds  float64        `json:"duration_seconds"`
+	UnsyncedRanges   []ByteRange    `json:"unsynced_ranges"`
+	UnsyncedBytes    int            `json:"unsynced_bytes"`
+	TrailingData     *ByteRange     `json:"trailing_data,omitempty"`
+	ChannelModes     map[string]int `json:"channel_modes"`
+	SampleRates      map[int]int    `json:"sample_rates"`
+}
+
+func AnalyzeMP3(mp3Data []byte) (*MP3Analysis, error) {
+	analysis := &MP3Analysis{
+		FileSize:         len(mp3Data),
+		ID3v2:            ParseID3v2(mp3Data),
+		ID3v1:            ParseID3v1(mp3Data),
+		BitrateHistogram: make(map[int]int),
+		ChannelModes:     make(map[string]int),
+		SampleRates:      make(map[int]int),
+		UnsyncedRanges:   []ByteRange{},
+	}
+
+	audioStart := 0
+	if analysis.ID3v2 != nil {
+		audioStart = analysis.ID3v2.Size
+	}
+	audioEnd := len(mp3Data)
+	if analysis.ID3v1 != nil {
+		audioEnd -= id3v1TagSize
+	}
+	if audioStart > audioEnd {
+		return nil, ErrInvalidMP3Format
+	}
+
+	frames, offsets := audio.FindMP3Frames(mp3Data[audioStart:audioEnd])
+	if len(frames) == 0 {
+		return nil, ErrNoValidFrames
+	}
+
+	analysis.FrameCount = len(frames)
+	analysis.AudioStart = audioStart + offsets[0]
+	last := len(frames) - 1
+	analysis.AudioEnd = audioStart + offsets[last] + frames[last].Size
+
+	position := audioStart
+	totalBits := 0.0
+	for i, frame := range frames {
+		offset := audioStart + offsets[i]
+		if offset > position {
+			analysis.addUnsynced(ByteRange{Start: position, End: offset})
+		}
+		position = offset + frame.Size
+
+		analysis.BitrateHistogram[frame.BitrateKbps()]++
+		analysis.ChannelModes[frame.ChannelMode()]++
+		analysis.SampleRates[frame.SampleRateHz()]++
+
+		frameSeconds := float64(frame.SamplesPerFrame()) / float64(frame.SampleRateHz())
+		analysis.DurationSeconds += frameSeconds
+		totalBits += float64(frame.Size * 8)
+	}
+
+	if position < audioEnd {
+		analysis.TrailingData = &ByteRange{Start: position, End: audioEnd}
+	}
+
+	first := frames[0]
+	analysis.Version = first.VersionName() + " Layer III"
+	analysis.SampleRate = first.SampleRateHz()
+	analysis.ChannelMode = first.ChannelMode()
+	analysis.VBRHeader = findVBRHeader(mp3Data[analysis.AudioStart : analysis.AudioStart+first.Size])
+
+	analysis.BitrateMode = "CBR"
+	if len(analysis.BitrateHistogram) > 1 || analysis.VBRHeader == "Xing" || analysis.VBRHeader == "VBRI" {
+		analysis.BitrateMode = "VBR"
+	}
+	if analysis.DurationSeconds > 0 {
+		analysis.AverageBitrate = totalBits / analysis.DurationSeconds / 1000
+	}
+
+	return analysis, nil
+}
+
+func (a *MP3Analysis) addUnsynced(r ByteRange) {
+	a.UnsyncedRanges = append(a.UnsyncedRanges, r)
+	a.UnsyncedBytes += r.Length()
+}
+
+func findVBRHeader(frame []byte) string {
+	for _, marker := range []string{"Xing", "Info", "VBRI"} {
+		if bytes.Contains(frame, []byte(marker)) {
+			return marker
+		}
+	}
+	return ""
+}
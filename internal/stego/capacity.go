@@ -0,0 +1,127 @@
+package stego
+
+// PayloadOverhead returns the number of bytes BuildPayload adds to a message,
+// without deriving the key check.
+func PayloadOverhead(opts EmbedOptions) (int, error) {
+	metadata := opts.metadata(0)
+	if metadata.Stealth && opts.Key == "" {
+		return 0, ErrKeyRequired
+	}
+	fields, err := encodeFields(metadata)
+	if err != nil {
+		return 0, err
+	}
+
+	overhead := containerFixedSize + len(fields) + 4
+	if metadata.Stealth {
+		overhead += stealthNonceSize
+	}
+	return overhead, nil
+}
+
+// Overheader is implemented by methods that frame the container with data of
+// their own, so a message takes more than PayloadOverhead extra bytes.
+type Overheader interface {
+	Overhead(messageSize int, opts EmbedOptions) (int, error)
+}
+
+// MethodOverhead returns the number of bytes method adds to a message of
+// messageSize bytes.
+func MethodOverhead(method Method, messageSize int, opts EmbedOptions) (int, error) {
+	if overheader, ok := method.(Overheader); ok {
+		return overheader.Overhead(messageSize, opts)
+	}
+	return PayloadOverhead(opts)
+}
+
+func secretCapacity(payloadCapacity int, opts EmbedOptions) (int, error) {
+	overhead, err := PayloadOverhead(opts)
+	if err != nil {
+		return 0, err
+	}
+
+	capacity := payloadCapacity - overhead
+	if capacity < 0 {
+		capacity = 0
+	}
+	return capacity, nil
+}
+
+func (l *LSBSteganography) payloadCapacity(carrierLen int, opts EmbedOptions) (int, error) {
+	if opts.Bits < 1 || opts.Bits > 4 {
+		return 0, ErrInvalidBitCount
+	}
+	if carrierLen <= l.headerSize {
+		return 0, ErrInvalidMP3Format
+	}
+
+	units := carrierLen - l.headerSize
+	if opts.UseKeyForPosition && opts.Key != "" {
+		units -= l.calculateKeyOffset(opts.Key) % units
+	}
+
+	return units * opts.Bits / 8, nil
+}
+
+func (l *LSBSteganography) Capacity(carrier []byte, opts EmbedOptions) (int, error) {
+	payloadCapacity, err := l.payloadCapacity(len(carrier), opts)
+	if err != nil {
+		return 0, err
+	}
+	return secretCapacity(payloadCapacity, opts)
+}
+
+func (l *LSBSteganography) DualCapacity(carrier []byte, bits int, key string, originalFilename string, fileType string) (int, error) {
+	if bits < 1 || bits > 4 {
+		return 0, ErrInvalidBitCount
+	}
+	if len(carrier) <= l.headerSize {
+		return 0, ErrInvalidMP3Format
+	}
+
+	region := (len(carrier) - l.headerSize) / partitionSlots
+	return secretCapacity(region*bits/8, EmbedOptions{
+		Bits:             bits,
+		Key:              key,
+		Stealth:          true,
+		OriginalFilename: originalFilename,
+		FileType:         fileType,
+	})
+}
+
+func (h *HeaderSteganography) Capacity(carrier []byte, opts EmbedOptions) (int, error) {
+	frames, _, err := h.locateFrames(carrier)
+	if err != nil {
+		return 0, err
+	}
+	return secretCapacity(h.calculateHeaderCapacity(frames), opts)
+}
+
+// Rater is implemented by methods whose capacity is set by the duration of a
+// PCM carrier rather than its size in bytes.
+type Rater interface {
+	BitsPerSecond(carrier []byte, opts EmbedOptions) (float64, error)
+}
+
+func (s *SpreadSpectrumSteganography) BitsPerSecond(carrier []byte, opts EmbedOptions) (float64, error) {
+	chipRate, _, err := spreadParameters(opts)
+	if err != nil {
+		return 0, err
+	}
+	wav, err := parsePCMCarrier(carrier)
+	if err != nil {
+		return 0, err
+	}
+	return float64(wav.SampleRate) / float64(chipRate), nil
+}
+
+func (e *EchoSteganography) BitsPerSecond(carrier []byte, opts EmbedOptions) (float64, error) {
+	if _, err := echoAmplitude(opts); err != nil {
+		return 0, err
+	}
+	wav, err := parsePCMCarrier(carrier)
+	if err != nil {
+		return 0, err
+	}
+	return float64(wav.SampleRate) / float64(newEchoLayout(wav).segment), nil
+}
@@ -0,0 +1,492 @@
+package stego
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"hash/crc32"
+)
+
+// Chunk layout. The payload (container and message, sealed in stealth mode)
+// is cut into chunks of chunkSize bytes and every chunk is written as
+//
+//	offset  size  field
+//	0       4     marker   first 4 bytes of SHA-256("stego-chunk:" + key)
+//	4       2     sequence index of the chunk in the payload
+//	6       2     total    number of chunks in the payload
+//	8       1     length   data bytes in this chunk
+//	9       n     data
+//	9+n     4     crc      CRC-32 (IEEE) of everything before it
+//
+// Chunks go into the low bits of each frame's main data (see mainDataSpans),
+// so cutting the file on frame boundaries removes whole units. The chunk
+// sequence is repeated until the carrier is full, so a chunk lost to
+// cropping at one end usually survives in a later copy. Readers look for the
+// marker at every bit position.
+const (
+	chunkMarkerSize = 4
+	chunkHeaderSize = chunkMarkerSize + 5
+	chunkCRCSize    = 4
+	chunkOverhead   = chunkHeaderSize + chunkCRCSize
+
+	defaultChunkSize = 64
+	maxChunks        = 0xFFFF
+
+	mp3HeaderSize = 4
+)
+
+type ChunkedSteganography struct {
+	chunkSize int
+}
+
+func NewChunkedSteganography() *ChunkedSteganography {
+	return &ChunkedSteganography{
+		chunkSize: defaultChunkSize,
+	}
+}
+
+type RecoveredChunk struct {
+	Sequence int `json:"sequence"`
+	Start    int `json:"start"`
+	End      int `json:"end"`
+	Copies   int `json:"copies"`
+}
+
+// ChunkRecovery describes what survived in a chunked carrier. Missing ranges
+// are payload byte offsets; MessageMissing maps them onto the secret message
+// once the container header has been recovered. When the last chunk is lost
+// PayloadSize is an upper bound.
+type ChunkRecovery struct {
+	LSBBits        int              `json:"lsb_bits"`
+	ChunkSize      int              `json:"chunk_size"`
+	TotalChunks    int              `json:"total_chunks"`
+	PayloadSize    int              `json:"payload_size"`
+	Complete       bool             `json:"complete"`
+	Chunks         []RecoveredChunk `json:"chunks"`
+	Missing        []ByteRange      `json:"missing"`
+	Metadata       *EmbedMetadata   `json:"metadata,omitempty"`
+	MessageMissing []ByteRange      `json:"message_missing,omitempty"`
+
+	Payload []byte `json:"-"`
+	Message []byte `json:"-"`
+}
+
+type parsedChunk struct {
+	sequence int
+	total    int
+	data     []byte
+}
+
+func ChunkMarker(key string) []byte {
+	sum := sha256.Sum256([]byte("stego-chunk:" + key))
+	return sum[:chunkMarkerSize]
+}
+
+func (c *ChunkedSteganography) Name() string {
+	return "chunked"
+}
+
+func (c *ChunkedSteganography) encodeChunks(payload []byte, key string) ([][]byte, error) {
+	total := (len(payload) + c.chunkSize - 1) / c.chunkSize
+	if total > maxChunks {
+		return nil, ErrInsufficientCapacity
+	}
+
+	marker := ChunkMarker(key)
+	chunks := make([][]byte, 0, total)
+	for seq := 0; seq < total; seq++ {
+		data := payload[seq*c.chunkSize : min((seq+1)*c.chunkSize, len(payload))]
+
+		chunk := make([]byte, 0, chunkOverhead+len(data))
+		chunk = append(chunk, marker...)
+		chunk = binary.BigEndian.AppendUint16(chunk, uint16(seq))
+		chunk = binary.BigEndian.AppendUint16(chunk, uint16(total))
+		chunk = append(chunk, byte(len(data)))
+		chunk = append(chunk, data...)
+		chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk))
+		chunks = append(chunks, chunk)
+	}
+
+	return chunks, nil
+}
+
+func (c *ChunkedSteganography) payloadCapacity(streamBytes int) int {
+	perChunk := c.chunkSize + chunkOverhead
+	capacity := streamBytes / perChunk * c.chunkSize
+	if rest := streamBytes%perChunk - chunkOverhead; rest > 0 {
+		capacity += rest
+	}
+	return min(capacity, maxChunks*c.chunkSize)
+}
+
+// Overhead counts the container and the header and CRC of every chunk.
+func (c *ChunkedSteganography) Overhead(messageSize int, opts EmbedOptions) (int, error) {
+	overhead, err := PayloadOverhead(opts)
+	if err != nil {
+		return 0, err
+	}
+	chunks := (overhead + messageSize + c.chunkSize - 1) / c.chunkSize
+	return overhead + chunks*chunkOverhead, nil
+}
+
+func (c *ChunkedSteganography) Capacity(carrier []byte, opts EmbedOptions) (int, error) {
+	if opts.Bits < 1 || opts.Bits > 4 {
+		return 0, ErrInvalidBitCount
+	}
+	_, units, err := mainDataSpans(carrier)
+	if err != nil {
+		return 0, err
+	}
+	return secretCapacity(c.payloadCapacity(units*opts.Bits/8), opts)
+}
+
+func (c *ChunkedSteganography) Embed(carrier, message []byte, opts EmbedOptions) ([]byte, error) {
+	if opts.Bits < 1 || opts.Bits > 4 {
+		return nil, ErrInvalidBitCount
+	}
+	if opts.Key == "" {
+		return nil, ErrKeyRequired
+	}
+
+	spans, units, err := mainDataSpans(carrier)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := BuildPayload(opts.metadata(len(message)), opts.Key, message)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks, err := c.encodeChunks(payload, opts.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	streamBytes := units * opts.Bits / 8
+	var stream []byte
+	for i := 0; ; i++ {
+		chunk := chunks[i%len(chunks)]
+		if len(stream)+len(chunk) > streamBytes {
+			break
+		}
+		stream = append(stream, chunk...)
+	}
+	if len(stream) < payloadStreamLength(chunks) {
+		return nil, ErrInsufficientCapacity
+	}
+
+	result := make([]byte, len(carrier))
+	copy(result, carrier)
+
+	noise, err := fillNoise(opts.Fill, opts.Key, units)
+	if err != nil {
+		return nil, err
+	}
+
+	mask := byte((1 << opts.Bits) - 1)
+	unit, bitPos := 0, 0
+	for _, span := range spans {
+		for i := span.Start; i < span.End; i++ {
+			if noise != nil {
+				result[i] = (result[i] &^ mask) | (noise[unit] & mask)
+			}
+			unit++
+
+			if bitPos >= len(stream)*8 {
+				continue
+			}
+			bits := result[i] & mask
+			for b := opts.Bits - 1; b >= 0 && bitPos < len(stream)*8; b-- {
+				bit := (stream[bitPos/8] >> (7 - bitPos%8)) & 1
+				bits = bits&^(1<<b) | bit<<b
+				bitPos++
+			}
+			result[i] = (result[i] &^ mask) | bits
+		}
+	}
+
+	return result, nil
+}
+
+func payloadStreamLength(chunks [][]byte) int {
+	length := 0
+	for _, chunk := range chunks {
+		length += len(chunk)
+	}
+	return length
+}
+
+// gatherBits packs the low bits of every unit into one MSB-first bit stream.
+func gatherBits(carrier []byte, spans []ByteRange, units, bits int) []byte {
+	stream := make([]byte, (units*bits+7)/8)
+	pos := 0
+	for _, span := range spans {
+		for _, value := range carrier[span.Start:span.End] {
+			for b := bits - 1; b >= 0; b-- {
+				if (value>>b)&1 == 1 {
+					stream[pos/8] |= 1 << (7 - pos%8)
+				}
+				pos++
+			}
+		}
+	}
+	return stream
+}
+
+func readStreamBytes(stream []byte, bitPos, length int) []byte {
+	data := make([]byte, length)
+	if bitPos%8 == 0 {
+		copy(data, stream[bitPos/8:])
+		return data
+	}
+	shift := uint(bitPos % 8)
+	for i := range data {
+		index := bitPos/8 + i
+		data[i] = stream[index] << shift
+		if index+1 < len(stream) {
+			data[i] |= stream[index+1] >> (8 - shift)
+		}
+	}
+	return data
+}
+
+// scanChunks slides a 32-bit window over the stream and parses a chunk
+// wherever the marker appears. The CRC rejects accidental marker matches.
+func scanChunks(stream []byte, totalBits int, marker []byte) []parsedChunk {
+	want := binary.BigEndian.Uint32(marker)
+	var chunks []parsedChunk
+	var window uint32
+
+	for pos := 0; pos < totalBits; pos++ {
+		window = window<<1 | uint32(stream[pos/8]>>(7-pos%8))&1
+		if pos < 31 || window != want {
+			continue
+		}
+
+		start := pos - 31
+		if start+chunkHeaderSize*8 > totalBits {
+			break
+		}
+		header := readStreamBytes(stream, start, chunkHeaderSize)
+		length := int(header[chunkHeaderSize-1])
+		end := start + (chunkOverhead+length)*8
+		if end > totalBits {
+			continue
+		}
+
+		chunk := readStreamBytes(stream, start, chunkOverhead+length)
+		body := chunk[:chunkHeaderSize+length]
+		if crc32.ChecksumIEEE(body) != binary.BigEndian.Uint32(chunk[len(body):]) {
+			continue
+		}
+
+		chunks = append(chunks, parsedChunk{
+			sequence: int(binary.BigEndian.Uint16(header[4:6])),
+			total:    int(binary.BigEndian.Uint16(header[6:8])),
+			data:     body[chunkHeaderSize:],
+		})
+		pos = end - 1
+		window = 0
+	}
+
+	return chunks
+}
+
+// Recover collects every intact chunk and reports which payload ranges are
+// missing. It tries each LSB depth and keeps the one with the most chunks.
+func (c *ChunkedSteganography) Recover(carrier []byte, key string) (*ChunkRecovery, error) {
+	if key == "" {
+		return nil, ErrNoSteganographicData
+	}
+
+	spans, units, err := mainDataSpans(carrier)
+	if err != nil {
+		return nil, err
+	}
+
+	marker := ChunkMarker(key)
+	var best *ChunkRecovery
+	for bits := 1; bits <= 4; bits++ {
+		stream := gatherBits(carrier, spans, units, bits)
+		chunks := scanChunks(stream, units*bits, marker)
+		recovery := c.assemble(chunks, bits)
+		if recovery != nil && (best == nil || len(recovery.Chunks) > len(best.Chunks)) {
+			best = recovery
+		}
+		if best != nil && best.Complete {
+			break
+		}
+	}
+	if best == nil {
+		return nil, ErrNoSteganographicData
+	}
+
+	best.decodeContainer(key)
+	return best, nil
+}
+
+func (c *ChunkedSteganography) assemble(chunks []parsedChunk, bits int) *ChunkRecovery {
+	votes := make(map[int]int)
+	for _, chunk := range chunks {
+		votes[chunk.total]++
+	}
+	total := 0
+	for candidate, count := range votes {
+		if count > votes[total] || (count == votes[total] && candidate < total) {
+			total = candidate
+		}
+	}
+	if total == 0 {
+		return nil
+	}
+
+	data := make([][]byte, total)
+	copies := make([]int, total)
+	for _, chunk := range chunks {
+		seq := chunk.sequence
+		if chunk.total != total || seq >= total {
+			continue
+		}
+		last := seq == total-1
+		if (!last && len(chunk.data) != c.chunkSize) || (last && len(chunk.data) > c.chunkSize) {
+			continue
+		}
+		if data[seq] == nil {
+			data[seq] = chunk.data
+		}
+		copies[seq]++
+	}
+
+	recovery := &ChunkRecovery{
+		LSBBits:     bits,
+		ChunkSize:   c.chunkSize,
+		TotalChunks: total,
+		PayloadSize: total * c.chunkSize,
+		Chunks:      []RecoveredChunk{},
+		Missing:     []ByteRange{},
+	}
+	if data[total-1] != nil {
+		recovery.PayloadSize = (total-1)*c.chunkSize + len(data[total-1])
+	}
+
+	recovery.Payload = make([]byte, recovery.PayloadSize)
+	for seq := 0; seq < total; seq++ {
+		start := seq * c.chunkSize
+		end := min(start+c.chunkSize, recovery.PayloadSize)
+		if data[seq] == nil {
+			recovery.Missing = appendRange(recovery.Missing, ByteRange{Start: start, End: end})
+			continue
+		}
+		copy(recovery.Payload[start:], data[seq])
+		recovery.Chunks = append(recovery.Chunks, RecoveredChunk{Sequence: seq, Start: start, End: end, Copies: copies[seq]})
+	}
+	if len(recovery.Chunks) == 0 {
+		return nil
+	}
+	recovery.Complete = len(recovery.Missing) == 0
+
+	return recovery
+}
+
+func appendRange(ranges []ByteRange, r ByteRange) []ByteRange {
+	if n := len(ranges); n > 0 && ranges[n-1].End == r.Start {
+		ranges[n-1].End = r.End
+		return ranges
+	}
+	return append(ranges, r)
+}
+
+func (r *ChunkRecovery) covers(start, end int) bool {
+	if end > r.PayloadSize {
+		return false
+	}
+	for _, missing := range r.Missing {
+		if missing.Start < end && start < missing.End {
+			return false
+		}
+	}
+	return true
+}
+
+// decodeContainer reads the container header when its chunks survived and
+// fills in the metadata and the message view of the payload. Missing message
+// bytes are left as zeros.
+func (r *ChunkRecovery) decodeContainer(key string) {
+	plain, messageStart := r.Payload, 0
+	headerLen, err := 0, ErrNoSteganographicData
+	if r.covers(0, containerFixedSize) {
+		headerLen, err = ContainerHeaderLength(plain[:containerFixedSize], key)
+	}
+	if err != nil && r.covers(0, stealthNonceSize+containerFixedSize) {
+		plain, messageStart = unsealPayload(r.Payload, key), stealthNonceSize
+		headerLen, err = ContainerHeaderLength(plain[:containerFixedSize], key)
+	}
+	if err != nil || !r.covers(0, messageStart+headerLen) {
+		return
+	}
+
+	metadata, _, err := DecodeContainer(plain[:headerLen], key)
+	if err != nil || metadata.Stealth != (messageStart > 0) {
+		return
+	}
+	messageStart += headerLen
+	if messageStart+metadata.SecretMessageSize > r.PayloadSize && r.Complete {
+		return
+	}
+
+	r.Metadata = metadata
+	r.Message = make([]byte, metadata.SecretMessageSize)
+	copy(r.Message, plain[headerLen:])
+	r.MessageMissing = []ByteRange{}
+	for _, missing := range r.Missing {
+		start := max(missing.Start-messageStart, 0)
+		end := min(missing.End-messageStart, len(r.Message))
+		if end > start {
+			clear(r.Message[start:end])
+			r.MessageMissing = append(r.MessageMissing, ByteRange{Start: start, End: end})
+		}
+	}
+	if tail := r.PayloadSize - messageStart; tail < len(r.Message) {
+		r.MessageMissing = appendRange(r.MessageMissing, ByteRange{Start: max(tail, 0), End: len(r.Message)})
+	}
+}
+
+func (c *ChunkedSteganography) Extract(carrier []byte, key string) (*ExtractResult, error) {
+	recovery, err := c.Recover(carrier, key)
+	if err != nil {
+		return nil, err
+	}
+	if !recovery.Complete {
+		return nil, ErrIncompletePayload
+	}
+	if recovery.Metadata == nil || recovery.Metadata.LSBBits != recovery.LSBBits {
+		return nil, ErrInvalidMetadata
+	}
+
+	return &ExtractResult{
+		Format:           FormatContainer,
+		Message:          recovery.Message,
+		Metadata:         recovery.Metadata,
+		OriginalFilename: recovery.Metadata.OriginalFilename,
+		FileType:         recovery.Metadata.FileType,
+	}, nil
+}
+
+func (c *ChunkedSteganography) EstimateChanges(carrier []byte, payloadSize int, opts EmbedOptions) (*ChangeEstimate, error) {
+	if opts.Bits < 1 || opts.Bits > 4 {
+		return nil, ErrInvalidBitCount
+	}
+	_, units, err := mainDataSpans(carrier)
+	if err != nil {
+		return nil, err
+	}
+
+	// The chunk sequence is repeated over the whole carrier, so every unit
+	// is rewritten regardless of the payload size.
+	levels := float64(int(1) << opts.Bits)
+	return &ChangeEstimate{
+		TotalUnits:       units,
+		TouchedUnits:     units,
+		ExpectedModified: float64(units) * (1 - 1/levels),
+	}, nil
+}
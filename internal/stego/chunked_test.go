@@ -0,0 +1,228 @@
+package stego
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestChunkedRoundTrip(t *testing.T) {
+	carrier := testMP3(120, 20)
+	message := testMessage(1500, 21)
+	c := NewChunkedSteganography()
+
+	for bits := 1; bits <= 4; bits++ {
+		for _, fill := range []FillMode{FillNone, FillKeyed} {
+			for flags := 0; flags < 8; flags++ {
+				opts := EmbedOptions{
+					Bits:             bits,
+					Key:              "chunk key",
+					UseEncryption:    flags&1 != 0,
+					Stealth:          flags&2 != 0,
+					Compressed:       flags&4 != 0,
+					Fill:             fill,
+					OriginalFilename: "a.txt",
+					FileType:         "text/plain",
+				}
+				name := fmt.Sprintf("bits=%d/fill=%q/encrypt=%v/stealth=%v/compressed=%v",
+					bits, fill, opts.UseEncryption, opts.Stealth, opts.Compressed)
+				t.Run(name, func(t *testing.T) {
+					stego, err := c.Embed(carrier, message, opts)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if comparison := CompareFrames(carrier, stego); !comparison.Match {
+						t.Fatalf("embedding changed the %s of frame %d", comparison.MismatchField, comparison.FirstMismatch)
+					}
+
+					result, err := c.Extract(stego, opts.Key)
+					if err != nil {
+						t.Fatal(err)
+					}
+					want := opts.metadata(len(message))
+					if !bytes.Equal(result.Message, message) || *result.Metadata != *want {
+						t.Errorf("extracted %d bytes with %+v, want %d bytes with %+v",
+							len(result.Message), *result.Metadata, len(message), *want)
+					}
+
+					if _, err := c.Extract(stego, "wrong"); !errors.Is(err, ErrNoSteganographicData) {
+						t.Errorf("wrong key: err = %v, want %v", err, ErrNoSteganographicData)
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestChunkedCropping(t *testing.T) {
+	carrier := testMP3(120, 22)
+	message := testMessage(1500, 23)
+	c := NewChunkedSteganography()
+	stego, err := c.Embed(carrier, message, EmbedOptions{Bits: 2, Key: "k", UseEncryption: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, offsets, err := ScanMP3Frames(stego)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tag := stego[:offsets[0]]
+	frames := func(from, to int) []byte {
+		end := len(stego)
+		if to < len(offsets) {
+			end = offsets[to]
+		}
+		return append(append([]byte(nil), tag...), stego[offsets[from]:end]...)
+	}
+
+	tests := []struct {
+		name     string
+		carrier  []byte
+		complete bool
+	}{
+		{"uncut", stego, true},
+		{"trim start", frames(10, 120), true},
+		{"trim end", frames(0, 100), true},
+		{"middle", frames(40, 80), true},
+		{"after cover", append(append([]byte(nil), carrier...), frames(0, 120)[len(tag):]...), true},
+		{"few frames", frames(60, 63), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recovery, err := c.Recover(tt.carrier, "k")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if recovery.Complete != tt.complete || recovery.LSBBits != 2 {
+				t.Fatalf("complete = %v at %d bits, want %v at 2 bits", recovery.Complete, recovery.LSBBits, tt.complete)
+			}
+
+			// Recovered and missing ranges tile the payload.
+			covered := make([]int, recovery.PayloadSize)
+			for _, r := range append(recoveredRanges(recovery.Chunks), recovery.Missing...) {
+				for i := r.Start; i < r.End; i++ {
+					covered[i]++
+				}
+			}
+			for i, n := range covered {
+				if n != 1 {
+					t.Fatalf("payload byte %d is in %d ranges", i, n)
+				}
+			}
+
+			result, err := c.Extract(tt.carrier, "k")
+			if !tt.complete {
+				if !errors.Is(err, ErrIncompletePayload) {
+					t.Errorf("err = %v, want %v", err, ErrIncompletePayload)
+				}
+				return
+			}
+			if err != nil || !bytes.Equal(result.Message, message) {
+				t.Errorf("extract: err = %v, message intact = %v", err, err == nil && bytes.Equal(result.Message, message))
+			}
+		})
+	}
+}
+
+// TestChunkedFrameLayout checks that chunks leave frame headers, CRCs and
+// side information alone and skip CRC-protected frames.
+func TestChunkedFrameLayout(t *testing.T) {
+	carrier := protectEveryOtherFrame(t, testMP3(120, 26))
+	message := testMessage(800, 27)
+	c := NewChunkedSteganography()
+	opts := EmbedOptions{Bits: 4, Key: "k", Fill: FillKeyed}
+	stego, err := c.Embed(carrier, message, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMainDataOnly(t, carrier, stego)
+
+	result, err := c.Extract(stego, "k")
+	if err != nil || !bytes.Equal(result.Message, message) {
+		t.Errorf("extract: err = %v, message intact = %v", err, err == nil && bytes.Equal(result.Message, message))
+	}
+}
+
+func recoveredRanges(chunks []RecoveredChunk) []ByteRange {
+	ranges := make([]ByteRange, len(chunks))
+	for i, chunk := range chunks {
+		ranges[i] = ByteRange{Start: chunk.Start, End: chunk.End}
+	}
+	return ranges
+}
+
+func TestChunkedErrors(t *testing.T) {
+	carrier := testMP3(40, 24)
+	c := NewChunkedSteganography()
+	capacity, err := c.Capacity(carrier, EmbedOptions{Bits: 1, Key: "k"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		carrier []byte
+		message []byte
+		opts    EmbedOptions
+		want    error
+	}{
+		{"fits", carrier, make([]byte, capacity), EmbedOptions{Bits: 1, Key: "k"}, nil},
+		{"too large", carrier, make([]byte, capacity+1), EmbedOptions{Bits: 1, Key: "k"}, ErrInsufficientCapacity},
+		{"no key", carrier, []byte("x"), EmbedOptions{Bits: 1}, ErrKeyRequired},
+		{"invalid bits", carrier, []byte("x"), EmbedOptions{Bits: 5, Key: "k"}, ErrInvalidBitCount},
+		{"no frames", testMessage(4000, 25), []byte("x"), EmbedOptions{Bits: 1, Key: "k"}, ErrNoValidFrames},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := c.Embed(tt.carrier, tt.message, tt.opts); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	stego, err := c.Embed(carrier, []byte("hello"), EmbedOptions{Bits: 3, Key: "k"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	extractTests := []struct {
+		name    string
+		carrier []byte
+		key     string
+		want    error
+	}{
+		{"right key", stego, "k", nil},
+		{"no key", stego, "", ErrNoSteganographicData},
+		{"wrong key", stego, "x", ErrNoSteganographicData},
+		{"cover", carrier, "k", ErrNoSteganographicData},
+	}
+	for _, tt := range extractTests {
+		t.Run("extract "+tt.name, func(t *testing.T) {
+			if _, err := c.Extract(tt.carrier, tt.key); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestChunkedOverhead(t *testing.T) {
+	c := NewChunkedSteganography()
+	for _, size := range []int{0, 1, 63, 64, 1500} {
+		opts := EmbedOptions{Bits: 2, Key: "k", OriginalFilename: "a.txt", FileType: "text/plain"}
+		payload, err := BuildPayload(opts.metadata(size), opts.Key, make([]byte, size))
+		if err != nil {
+			t.Fatal(err)
+		}
+		chunks, err := c.encodeChunks(payload, opts.Key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		overhead, err := c.Overhead(size, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := payloadStreamLength(chunks) - size; overhead != want {
+			t.Errorf("size %d: overhead = %d, want %d", size, overhead, want)
+		}
+	}
+}
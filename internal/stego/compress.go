@@ -0,0 +1,37 @@
+package stego
+
+import (
+	"bytes"
+	"compress/flate"
+	"io"
+)
+
+const maxDecompressedSize = 100 * 1024 * 1024
+
+func CompressMessage(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer, err := flate.NewWriter(&buf, flate.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func DecompressMessage(data []byte) ([]byte, error) {
+	reader := flate.NewReader(bytes.NewReader(data))
+	defer reader.Close()
+
+	decompressed, err := io.ReadAll(io.LimitReader(reader, maxDecompressedSize+1))
+	if err != nil || len(decompressed) > maxDecompressedSize {
+		return nil, ErrDecompressionFailed
+	}
+
+	return decompressed, nil
+}
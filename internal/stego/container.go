@@ -0,0 +1,335 @@
+package stego
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/crypto"
+)
+
+// A container precedes every message. Its fixed part is magic (4), version
+// (1), flags (1), LSB depth (1), key salt (8), key check (4) and the length of
+// the TLV field block (2); the fields and the message length (4) follow. Integers are
+// big-endian, the fields are Vigenere-encrypted when flagEncryption is set
+// and readers skip field types they do not know. Stealth payloads seal the
+// container and message behind a random nonce, so they can only be found by
+// trial decryption.
+const (
+	containerVersion   = 3
+	containerFixedSize = 21
+	containerMagicSize = 4
+	keySaltOffset      = 7
+	keySaltSize        = 8
+	keyCheckOffset     = keySaltOffset + keySaltSize
+	keyCheckSize       = 4
+
+	// keyCheckIterations slows down guessing the key from the key check.
+	keyCheckIterations = 100000
+
+	// keyedFlags are the flags whose payload cannot be read without the key.
+	keyedFlags = flagEncryption | flagKeyPosition | flagStealth | flagPartitioned
+
+	flagEncryption  = 1 << 0
+	flagKeyPosition = 1 << 1
+	flagStealth     = 1 << 2
+	flagPartitioned = 1 << 3
+	flagCompressed  = 1 << 4
+
+	fieldFilename = 0x01
+	fieldFileType = 0x02
+
+	maxFieldLength = 0xFFFF
+
+	stealthLabel     = "stego-payload"
+	stealthNonceSize = 16
+)
+
+// containerMagic is the first bytes of SHA-256("stego-container:").
+var containerMagic = func() []byte {
+	sum := sha256.Sum256([]byte("stego-container:"))
+	return sum[:containerMagicSize]
+}()
+
+func containerKeyCheck(key string, salt []byte) []byte {
+	return crypto.PBKDF2([]byte(key), salt, keyCheckIterations, keyCheckSize)
+}
+
+func EncodeContainer(metadata *EmbedMetadata, key string) ([]byte, error) {
+	fieldData, err := encodeFields(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	var flags byte
+	if metadata.UseEncryption {
+		flags |= flagEncryption
+		if key != "" {
+			fieldData = VigenereEncrypt(fieldData, key)
+		}
+	}
+	if metadata.UseKeyForPosition {
+		flags |= flagKeyPosition
+	}
+	if metadata.Stealth {
+		flags |= flagStealth
+	}
+	if metadata.Partitioned {
+		flags |= flagPartitioned
+	}
+	if metadata.Compressed {
+		flags |= flagCompressed
+	}
+
+	salt := make([]byte, keySaltSize)
+	keyCheck := make([]byte, keyCheckSize)
+	if flags&keyedFlags != 0 {
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+		keyCheck = containerKeyCheck(key, salt)
+	}
+
+	var buf bytes.Buffer
+	buf.Write(containerMagic)
+	buf.WriteByte(containerVersion)
+	buf.WriteByte(flags)
+	buf.WriteByte(byte(metadata.LSBBits))
+	buf.Write(salt)
+	buf.Write(keyCheck)
+	binary.Write(&buf, binary.BigEndian, uint16(len(fieldData)))
+	buf.Write(fieldData)
+	binary.Write(&buf, binary.BigEndian, uint32(metadata.SecretMessageSize))
+
+	return buf.Bytes(), nil
+}
+
+func encodeFields(metadata *EmbedMetadata) ([]byte, error) {
+	var fields bytes.Buffer
+	writeField(&fields, fieldFilename, []byte(metadata.OriginalFilename))
+	writeField(&fields, fieldFileType, []byte(metadata.FileType))
+
+	if fields.Len() > maxFieldLength {
+		return nil, ErrInvalidMetadata
+	}
+	return fields.Bytes(), nil
+}
+
+func BuildPayload(metadata *EmbedMetadata, key string, message []byte) ([]byte, error) {
+	if metadata.Stealth && key == "" {
+		return nil, ErrKeyRequired
+	}
+
+	header, err := EncodeContainer(metadata, key)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := make([]byte, 0, len(header)+len(message))
+	payload = append(payload, header...)
+	payload = append(payload, message...)
+
+	if metadata.Stealth {
+		return sealPayload(payload, key)
+	}
+
+	return payload, nil
+}
+
+func sealPayload(payload []byte, key string) ([]byte, error) {
+	nonce := make([]byte, stealthNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return append(nonce, crypto.KeystreamXOR(payload, key, stealthLabel, nonce)...), nil
+}
+
+func unsealPayload(sealed []byte, key string) []byte {
+	if len(sealed) < stealthNonceSize {
+		return nil
+	}
+
+	return crypto.KeystreamXOR(sealed[stealthNonceSize:], key, stealthLabel, sealed[:stealthNonceSize])
+}
+
+func decodePayload(read func(length int) ([]byte, bool), key string, sealed bool) (*ExtractResult, error) {
+	readPlain := func(length int) ([]byte, bool) {
+		if !sealed {
+			return read(length)
+		}
+		data, ok := read(stealthNonceSize + length)
+		if !ok {
+			return nil, false
+		}
+		return unsealPayload(data, key), true
+	}
+
+	fixed, ok := readPlain(containerFixedSize)
+	if !ok {
+		return nil, ErrNoSteganographicData
+	}
+
+	headerLen, err := ContainerHeaderLength(fixed, key)
+	if err != nil {
+		return nil, err
+	}
+
+	header, ok := readPlain(headerLen)
+	if !ok {
+		return nil, ErrInvalidMetadata
+	}
+
+	metadata, _, err := DecodeContainer(header, key)
+	if err != nil {
+		return nil, err
+	}
+	if metadata.Stealth != sealed {
+		return nil, ErrInvalidMetadata
+	}
+
+	payload, ok := readPlain(headerLen + metadata.SecretMessageSize)
+	if !ok {
+		return nil, ErrInvalidMetadata
+	}
+
+	return &ExtractResult{
+		Format:           FormatContainer,
+		Message:          payload[headerLen:],
+		Metadata:         metadata,
+		OriginalFilename: metadata.OriginalFilename,
+		FileType:         metadata.FileType,
+	}, nil
+}
+
+// peekPayload reads the parts of an unsealed container that need no key: the
+// flags, LSB depth and message size. The filename and file type are left
+// empty since they may be encrypted. It returns the container format, or
+// false when read does not start with a container this reader recognises
+// without the key.
+func peekPayload(read func(length int) ([]byte, bool)) (*EmbedMetadata, string, bool) {
+	fixed, ok := read(containerFixedSize)
+	if !ok {
+		return nil, "", false
+	}
+	if err := checkContainer(fixed); err != nil {
+		return nil, "", false
+	}
+
+	header, ok := read(containerFixedSize + containerFieldsLength(fixed) + 4)
+	if !ok {
+		return nil, "", false
+	}
+
+	metadata := containerFlags(header)
+	metadata.SecretMessageSize = int(binary.BigEndian.Uint32(header[len(header)-4:]))
+	return metadata, FormatContainer, true
+}
+
+func containerFlags(header []byte) *EmbedMetadata {
+	flags := header[5]
+	return &EmbedMetadata{
+		UseEncryption:     flags&flagEncryption != 0,
+		UseKeyForPosition: flags&flagKeyPosition != 0,
+		Stealth:           flags&flagStealth != 0,
+		Partitioned:       flags&flagPartitioned != 0,
+		Compressed:        flags&flagCompressed != 0,
+		LSBBits:           int(header[6]),
+	}
+}
+
+func writeField(buf *bytes.Buffer, fieldType byte, value []byte) {
+	if len(value) > maxFieldLength {
+		value = value[:maxFieldLength]
+	}
+	buf.WriteByte(fieldType)
+	binary.Write(buf, binary.BigEndian, uint16(len(value)))
+	buf.Write(value)
+}
+
+// checkContainer validates the magic and version of a container.
+func checkContainer(fixed []byte) error {
+	switch {
+	case len(fixed) < containerFixedSize:
+		return ErrInvalidMetadata
+	case !bytes.Equal(fixed[:containerMagicSize], containerMagic):
+		return ErrNoSteganographicData
+	case fixed[4] != containerVersion:
+		return ErrUnsupportedVersion
+	}
+	return nil
+}
+
+func containerFieldsLength(fixed []byte) int {
+	return int(binary.BigEndian.Uint16(fixed[containerFixedSize-2 : containerFixedSize]))
+}
+
+// ContainerHeaderLength inspects the fixed part of a container and reports the
+// total header length, including the trailing message length field. A
+// container whose key check does not match key yields ErrWrongKey.
+func ContainerHeaderLength(fixed []byte, key string) (int, error) {
+	if err := checkContainer(fixed); err != nil {
+		return 0, err
+	}
+	if fixed[5]&keyedFlags != 0 {
+		salt := fixed[keySaltOffset : keySaltOffset+keySaltSize]
+		if !bytes.Equal(fixed[keyCheckOffset:keyCheckOffset+keyCheckSize], containerKeyCheck(key, salt)) {
+			return 0, ErrWrongKey
+		}
+	}
+
+	return containerFixedSize + containerFieldsLength(fixed) + 4, nil
+}
+
+func DecodeContainer(data []byte, key string) (*EmbedMetadata, int, error) {
+	headerLen, err := ContainerHeaderLength(data, key)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(data) < headerLen {
+		return nil, 0, ErrInvalidMetadata
+	}
+
+	return decodeContainerFields(data[:headerLen], key)
+}
+
+// decodeContainerFields parses a header whose magic, version and key check
+// have already been validated.
+func decodeContainerFields(data []byte, key string) (*EmbedMetadata, int, error) {
+	metadata := containerFlags(data)
+
+	fieldsEnd := len(data) - 4
+	fieldData := data[containerFixedSize:fieldsEnd]
+	if metadata.UseEncryption && key != "" {
+		fieldData = VigenereDecrypt(fieldData, key)
+	}
+
+	for i := 0; i < len(fieldData); {
+		if i+3 > len(fieldData) {
+			return nil, 0, ErrInvalidMetadata
+		}
+		fieldType := fieldData[i]
+		fieldLen := int(binary.BigEndian.Uint16(fieldData[i+1 : i+3]))
+		i += 3
+		if i+fieldLen > len(fieldData) {
+			return nil, 0, ErrInvalidMetadata
+		}
+		value := fieldData[i : i+fieldLen]
+		i += fieldLen
+
+		switch fieldType {
+		case fieldFilename:
+			metadata.OriginalFilename = string(value)
+		case fieldFileType:
+			metadata.FileType = string(value)
+		}
+	}
+
+	metadata.SecretMessageSize = int(binary.BigEndian.Uint32(data[fieldsEnd:]))
+	if metadata.SecretMessageSize > 100*1024*1024 {
+		return nil, 0, ErrInvalidMetadata
+	}
+
+	return metadata, len(data), nil
+}
@@ -0,0 +1,191 @@
+package stego
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestContainerRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata EmbedMetadata
+		key      string
+	}{
+		{"plain", EmbedMetadata{LSBBits: 1, SecretMessageSize: 12}, ""},
+		{"plain with key", EmbedMetadata{LSBBits: 2, SecretMessageSize: 12}, "k"},
+		{"encrypted fields", EmbedMetadata{UseEncryption: true, LSBBits: 3, OriginalFilename: "secret.pdf", FileType: "application/pdf", SecretMessageSize: 4096}, "k"},
+		{"key position", EmbedMetadata{UseKeyForPosition: true, LSBBits: 4, OriginalFilename: "a.txt"}, "k"},
+		{"stealth", EmbedMetadata{Stealth: true, OriginalFilename: "a.txt", SecretMessageSize: 1}, "k"},
+		{"partitioned", EmbedMetadata{Partitioned: true, Stealth: true, LSBBits: 2}, "k"},
+		{"compressed", EmbedMetadata{Compressed: true, FileType: "text/plain", SecretMessageSize: 99}, ""},
+		{"every flag", EmbedMetadata{UseEncryption: true, UseKeyForPosition: true, Stealth: true, Partitioned: true, Compressed: true, LSBBits: 4, OriginalFilename: "ünïcode.bin", SecretMessageSize: 70000}, "key with spaces"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := EncodeContainer(&tt.metadata, tt.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			headerLen, err := ContainerHeaderLength(data[:containerFixedSize], tt.key)
+			if err != nil || headerLen != len(data) {
+				t.Fatalf("ContainerHeaderLength = %d, %v, want %d", headerLen, err, len(data))
+			}
+
+			got, n, err := DecodeContainer(append(data, "trailing message"...), tt.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != len(data) || *got != tt.metadata {
+				t.Errorf("decoded %+v (%d bytes), want %+v (%d bytes)", *got, n, tt.metadata, len(data))
+			}
+		})
+	}
+}
+
+func TestContainerErrors(t *testing.T) {
+	encode := func(metadata EmbedMetadata, key string) []byte {
+		data, err := EncodeContainer(&metadata, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	modify := func(data []byte, f func([]byte)) []byte {
+		data = append([]byte(nil), data...)
+		f(data)
+		return data
+	}
+	keyed := encode(EmbedMetadata{UseEncryption: true, OriginalFilename: "a.txt", SecretMessageSize: 5}, "right")
+	plain := encode(EmbedMetadata{OriginalFilename: "a.txt", SecretMessageSize: 5}, "")
+
+	tests := []struct {
+		name string
+		data []byte
+		key  string
+		want error
+	}{
+		{"wrong key", keyed, "wrong", ErrWrongKey},
+		{"corrupt salt", modify(keyed, func(b []byte) { b[keySaltOffset] ^= 1 }), "right", ErrWrongKey},
+		{"missing key", keyed, "", ErrWrongKey},
+		{"plain ignores key", plain, "anything", nil},
+		{"truncated fixed part", keyed[:containerFixedSize-1], "right", ErrInvalidMetadata},
+		{"truncated fields", keyed[:len(keyed)-6], "right", ErrInvalidMetadata},
+		{"corrupt magic", modify(plain, func(b []byte) { b[0] ^= 0xFF }), "", ErrNoSteganographicData},
+		{"random data", testMessage(64, 1), "right", ErrNoSteganographicData},
+		{"unknown version", modify(plain, func(b []byte) { b[4] = 9 }), "", ErrUnsupportedVersion},
+		{"overlong field", modify(plain, func(b []byte) { b[containerFixedSize+2] = 0xF0 }), "", ErrInvalidMetadata},
+		{"oversized message", modify(plain, func(b []byte) { b[len(b)-4] = 0x7F }), "", ErrInvalidMetadata},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := DecodeContainer(tt.data, tt.key); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestContainerKeyCheckSalted(t *testing.T) {
+	metadata := EmbedMetadata{UseEncryption: true, OriginalFilename: "a.txt"}
+	first, err := EncodeContainer(&metadata, "k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := EncodeContainer(&metadata, "k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(first[keySaltOffset:keyCheckOffset], second[keySaltOffset:keyCheckOffset]) {
+		t.Error("two containers for the same key share a salt")
+	}
+
+	plain, err := EncodeContainer(&EmbedMetadata{OriginalFilename: "a.txt"}, "k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(plain[keySaltOffset:keyCheckOffset+keyCheckSize], make([]byte, keySaltSize+keyCheckSize)) {
+		t.Error("a container without keyed flags carries a salt or key check")
+	}
+}
+
+func TestPayloadOverhead(t *testing.T) {
+	tests := []EmbedOptions{
+		{},
+		{Key: "k", UseEncryption: true, OriginalFilename: "secret.pdf", FileType: "application/pdf"},
+		{Key: "k", Stealth: true, Compressed: true, OriginalFilename: "a.txt"},
+	}
+	for _, opts := range tests {
+		payload, err := BuildPayload(opts.metadata(0), opts.Key, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if overhead, err := PayloadOverhead(opts); err != nil || overhead != len(payload) {
+			t.Errorf("PayloadOverhead(%+v) = %d, %v, want %d", opts, overhead, err, len(payload))
+		}
+	}
+	if _, err := PayloadOverhead(EmbedOptions{Stealth: true}); !errors.Is(err, ErrKeyRequired) {
+		t.Errorf("stealth without key: err = %v, want %v", err, ErrKeyRequired)
+	}
+}
+
+func TestStealthPayload(t *testing.T) {
+	message := []byte("stealth message")
+	metadata := EmbedMetadata{Stealth: true, UseEncryption: true, OriginalFilename: "s.txt", SecretMessageSize: len(message)}
+	sealed, err := BuildPayload(&metadata, "k", message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := BuildPayload(&metadata, "k", message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(sealed, again) {
+		t.Error("two stealth payloads of the same message are identical")
+	}
+	if bytes.Contains(sealed, message) || bytes.Contains(sealed, containerMagic) {
+		t.Error("stealth payload exposes the message or container magic")
+	}
+	if _, err := BuildPayload(&metadata, "", message); !errors.Is(err, ErrKeyRequired) {
+		t.Errorf("BuildPayload without key: err = %v, want %v", err, ErrKeyRequired)
+	}
+
+	plain, err := BuildPayload(&EmbedMetadata{SecretMessageSize: len(message)}, "", message)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		data   []byte
+		key    string
+		sealed bool
+		want   error
+	}{
+		{"right key", sealed, "k", true, nil},
+		{"wrong key", sealed, "x", true, ErrNoSteganographicData},
+		{"no key", sealed, "", true, ErrNoSteganographicData},
+		{"truncated nonce", sealed[:stealthNonceSize+4], "k", true, ErrNoSteganographicData},
+		{"truncated message", sealed[:len(sealed)-1], "k", true, ErrInvalidMetadata},
+		{"plain read as sealed", plain, "k", true, ErrNoSteganographicData},
+		{"sealed read as plain", sealed, "k", false, ErrNoSteganographicData},
+		{"plain", plain, "", false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			read := func(length int) ([]byte, bool) {
+				if length > len(tt.data) {
+					return nil, false
+				}
+				return tt.data[:length], true
+			}
+			result, err := decodePayload(read, tt.key, tt.sealed)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+			if err == nil && !bytes.Equal(result.Message, message) {
+				t.Errorf("message = %q, want %q", result.Message, message)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package stego
+
+import "crypto/sha256"
+
+// Dual-payload layout. The LSB carrier after the reserved header is split
+// into partitionSlots equal regions. Each payload takes the first region in
+// an order derived from its own key; when the secret's first choice is the
+// decoy's region it moves on to its next choice. Every region, including the
+// ones no payload uses, is filled with random bits, so the decoy key only
+// reveals its own region and the secret may sit in any of the others.
+const partitionSlots = 4
+
+type DualPayload struct {
+	Message          []byte
+	Key              string
+	OriginalFilename string
+	FileType         string
+	UseEncryption    bool
+	Compressed       bool
+}
+
+func (l *LSBSteganography) EmbedDualPayload(mp3Data []byte, decoy, secret DualPayload, bits int) ([]byte, error) {
+	if bits < 1 || bits > 4 {
+		return nil, ErrInvalidBitCount
+	}
+	if len(mp3Data) <= l.headerSize {
+		return nil, ErrInvalidMP3Format
+	}
+	if decoy.Key == "" || secret.Key == "" {
+		return nil, ErrKeyRequired
+	}
+	if decoy.Key == secret.Key {
+		return nil, ErrIdenticalKeys
+	}
+
+	result := make([]byte, len(mp3Data))
+	copy(result, mp3Data)
+
+	regions := l.partitionRegions(result[l.headerSize:])
+	slots := dualSlots(decoy.Key, secret.Key)
+	for _, region := range regions {
+		noise, err := fillNoise(FillRandom, "", len(region))
+		if err != nil {
+			return nil, err
+		}
+		applyFill(region, bits, noise)
+	}
+
+	for i, payload := range []DualPayload{decoy, secret} {
+		metadata := &EmbedMetadata{
+			UseEncryption:     payload.UseEncryption,
+			LSBBits:           bits,
+			Stealth:           true,
+			Partitioned:       true,
+			Compressed:        payload.Compressed,
+			OriginalFilename:  payload.OriginalFilename,
+			FileType:          payload.FileType,
+			SecretMessageSize: len(payload.Message),
+		}
+
+		payloadData, err := BuildPayload(metadata, payload.Key, payload.Message)
+		if err != nil {
+			return nil, err
+		}
+
+		region := regions[slots[i]]
+		if len(payloadData) > len(region)*bits/8 {
+			return nil, ErrInsufficientCapacity
+		}
+		l.embedDataWithOffset(region, payloadData, bits, 0, nil)
+	}
+
+	return result, nil
+}
+
+func (l *LSBSteganography) partitionRegions(carrier []byte) [][]byte {
+	size := len(carrier) / partitionSlots
+	regions := make([][]byte, partitionSlots)
+	for i := range regions {
+		regions[i] = carrier[i*size : (i+1)*size]
+	}
+	return regions
+}
+
+// slotOrder returns the regions in the order key tries them, a permutation
+// drawn from a hash of the key.
+func slotOrder(key string) []int {
+	sum := sha256.Sum256([]byte("stego-slot:" + key))
+	order := make([]int, partitionSlots)
+	for i := range order {
+		order[i] = i
+	}
+	for i := len(order) - 1; i > 0; i-- {
+		j := int(sum[i]) % (i + 1)
+		order[i], order[j] = order[j], order[i]
+	}
+	return order
+}
+
+// dualSlots returns the regions of the decoy and the secret payload.
+func dualSlots(decoyKey, secretKey string) [2]int {
+	decoySlot := slotOrder(decoyKey)[0]
+	order := slotOrder(secretKey)
+	if order[0] == decoySlot {
+		return [2]int{decoySlot, order[1]}
+	}
+	return [2]int{decoySlot, order[0]}
+}
@@ -0,0 +1,105 @@
+package stego
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestDualSlots(t *testing.T) {
+	for i := 0; i < 200; i++ {
+		decoyKey, secretKey := fmt.Sprintf("decoy-%d", i), fmt.Sprintf("secret-%d", i)
+		slots := dualSlots(decoyKey, secretKey)
+		if slots[0] == slots[1] {
+			t.Fatalf("keys %q and %q share slot %d", decoyKey, secretKey, slots[0])
+		}
+		if slots[0] != slotOrder(decoyKey)[0] {
+			t.Fatalf("decoy slot %d is not the first choice of its key", slots[0])
+		}
+
+		seen := make([]bool, partitionSlots)
+		for _, slot := range slotOrder(secretKey) {
+			seen[slot] = true
+		}
+		for slot, ok := range seen {
+			if !ok {
+				t.Fatalf("slotOrder(%q) misses slot %d", secretKey, slot)
+			}
+		}
+	}
+}
+
+func TestDualPayloadRoundTrip(t *testing.T) {
+	carrier := testMP3(120, 7)
+	l := NewLSBSteganography()
+
+	tests := []struct {
+		name   string
+		bits   int
+		decoy  DualPayload
+		secret DualPayload
+	}{
+		{"plain", 1, DualPayload{Message: []byte("shopping list"), Key: "a"}, DualPayload{Message: testMessage(600, 8), Key: "b"}},
+		{"encrypted", 2, DualPayload{Message: []byte("decoy"), Key: "decoy", UseEncryption: true, OriginalFilename: "d.txt"},
+			DualPayload{Message: testMessage(3000, 9), Key: "secret", UseEncryption: true, OriginalFilename: "s.pdf", FileType: "application/pdf"}},
+		{"compressed", 3, DualPayload{Message: []byte("x"), Key: "q", Compressed: true}, DualPayload{Message: []byte("y"), Key: "w", Compressed: true}},
+		{"four bits", 4, DualPayload{Message: testMessage(1000, 10), Key: "x1"}, DualPayload{Message: testMessage(1000, 11), Key: "x2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stego, err := l.EmbedDualPayload(carrier, tt.decoy, tt.secret, tt.bits)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, payload := range []DualPayload{tt.decoy, tt.secret} {
+				result, err := l.Extract(stego, payload.Key)
+				if err != nil {
+					t.Fatalf("key %q: %v", payload.Key, err)
+				}
+				m := result.Metadata
+				if !bytes.Equal(result.Message, payload.Message) || result.OriginalFilename != payload.OriginalFilename ||
+					result.FileType != payload.FileType || m.UseEncryption != payload.UseEncryption ||
+					m.Compressed != payload.Compressed || !m.Partitioned || !m.Stealth || m.LSBBits != tt.bits {
+					t.Errorf("key %q: extracted %q with %+v", payload.Key, result.Message, *m)
+				}
+			}
+
+			for _, key := range []string{"", "neither"} {
+				if _, err := l.Extract(stego, key); !errors.Is(err, ErrNoSteganographicData) {
+					t.Errorf("key %q: err = %v, want %v", key, err, ErrNoSteganographicData)
+				}
+			}
+		})
+	}
+}
+
+func TestDualPayloadErrors(t *testing.T) {
+	carrier := testMP3(40, 12)
+	l := NewLSBSteganography()
+	region := (len(carrier) - l.headerSize) / partitionSlots
+
+	tests := []struct {
+		name    string
+		carrier []byte
+		decoy   DualPayload
+		secret  DualPayload
+		bits    int
+		want    error
+	}{
+		{"invalid bits", carrier, DualPayload{Key: "a"}, DualPayload{Key: "b"}, 5, ErrInvalidBitCount},
+		{"header only", carrier[:l.headerSize], DualPayload{Key: "a"}, DualPayload{Key: "b"}, 1, ErrInvalidMP3Format},
+		{"missing decoy key", carrier, DualPayload{}, DualPayload{Key: "b"}, 1, ErrKeyRequired},
+		{"missing secret key", carrier, DualPayload{Key: "a"}, DualPayload{}, 1, ErrKeyRequired},
+		{"identical keys", carrier, DualPayload{Key: "a"}, DualPayload{Key: "a"}, 1, ErrIdenticalKeys},
+		{"secret over region", carrier, DualPayload{Key: "a"}, DualPayload{Key: "b", Message: make([]byte, region/8)}, 1, ErrInsufficientCapacity},
+		{"decoy over region", carrier, DualPayload{Key: "a", Message: make([]byte, region/4)}, DualPayload{Key: "b"}, 2, ErrInsufficientCapacity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := l.EmbedDualPayload(tt.carrier, tt.decoy, tt.secret, tt.bits); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package stego
+
+import (
+	"math"
+
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/audio"
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/crypto"
+)
+
+// Spread-spectrum layout. Every payload bit (MSB first) occupies ChipRate
+// consecutive sample frames. A ±1 chip sequence drawn from a keystream
+// derived from the key, one chip per sample frame of the file, is scaled,
+// multiplied by +1 for a one bit or -1 for a zero bit and added to every
+// channel. The extractor correlates the first difference of the mono mix
+// with the first difference of the chips, which removes most of the
+// low-frequency host energy. The embedder also subtracts the host's own
+// correlation with the chips (improved spread spectrum), up to
+// issMaxCorrection times Strength, so Strength, a fraction of full scale, is
+// the margin every bit keeps against noise added later rather than a margin
+// the host may already have used up. The chip
+// rate is not stored: the extractor tries every allowed rate and keeps the
+// one whose container decodes.
+const (
+	DefaultChipRate = 1024
+	MinChipRate     = 64
+	MaxChipRate     = 8192
+
+	DefaultStrength = 0.005
+	MaxStrength     = 0.1
+
+	issMaxCorrection = 3
+)
+
+type SpreadSpectrumSteganography struct{}
+
+func NewSpreadSpectrumSteganography() *SpreadSpectrumSteganography {
+	return &SpreadSpectrumSteganography{}
+}
+
+func (s *SpreadSpectrumSteganography) Name() string {
+	return "dsss"
+}
+
+func spreadParameters(opts EmbedOptions) (int, float64, error) {
+	chipRate, strength := opts.ChipRate, opts.Strength
+	if chipRate == 0 {
+		chipRate = DefaultChipRate
+	}
+	if strength == 0 {
+		strength = DefaultStrength
+	}
+
+	if chipRate < MinChipRate || chipRate > MaxChipRate || chipRate&(chipRate-1) != 0 {
+		return 0, 0, ErrInvalidChipRate
+	}
+	if strength < 0 || strength > MaxStrength {
+		return 0, 0, ErrInvalidStrength
+	}
+	return chipRate, strength, nil
+}
+
+func parsePCMCarrier(carrier []byte) (*audio.WAV, error) {
+	wav, err := audio.ParseWAV(carrier)
+	if err != nil {
+		return nil, ErrWAVRequired
+	}
+	return wav, nil
+}
+
+// chipSequence returns one ±1 chip per sample frame.
+func chipSequence(key string, frames int) []float64 {
+	stream := crypto.KeystreamXOR(make([]byte, (frames+7)/8), key, "stego-dsss", nil)
+	chips := make([]float64, frames)
+	for i := range chips {
+		chips[i] = float64(int(stream[i/8]>>(7-i%8)&1)*2 - 1)
+	}
+	return chips
+}
+
+func (s *SpreadSpectrumSteganography) Capacity(carrier []byte, opts EmbedOptions) (int, error) {
+	chipRate, _, err := spreadParameters(opts)
+	if err != nil {
+		return 0, err
+	}
+	wav, err := parsePCMCarrier(carrier)
+	if err != nil {
+		return 0, err
+	}
+	return secretCapacity(wav.Frames()/chipRate/8, opts)
+}
+
+func (s *SpreadSpectrumSteganography) Embed(carrier, message []byte, opts EmbedOptions) ([]byte, error) {
+	chipRate, strength, err := spreadParameters(opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Key == "" {
+		return nil, ErrKeyRequired
+	}
+
+	wav, err := parsePCMCarrier(carrier)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := BuildPayload(opts.metadata(len(message)), opts.Key, message)
+	if err != nil {
+		return nil, err
+	}
+
+	slots := wav.Frames() / chipRate
+	if len(payload)*8 > slots {
+		return nil, ErrInsufficientCapacity
+	}
+
+	noise, err := fillNoise(opts.Fill, opts.Key, (slots+7)/8)
+	if err != nil {
+		return nil, err
+	}
+
+	host := &despreader{mono: wav.Mono(), chips: chipSequence(opts.Key, wav.Frames()), chipRate: chipRate}
+	amplitude := strength * float64(wav.MaxSample()+1)
+	for slot := 0; slot < slots; slot++ {
+		var bit byte
+		switch {
+		case slot < len(payload)*8:
+			bit = payload[slot/8] >> (7 - slot%8) & 1
+		case noise != nil:
+			bit = noise[slot/8] >> (7 - slot%8) & 1
+		default:
+			return wav.Encode(carrier), nil
+		}
+
+		// On a loud host the correlation can be many times the strength, so
+		// the correction is clamped and no sample moves by more than
+		// issMaxCorrection+1 times the strength. A payload bit the clamped
+		// correction cannot carry fails the embed rather than the extract.
+		correlation, energy := host.correlate(slot)
+		sign := float64(int(bit)*2 - 1)
+		limit := issMaxCorrection * amplitude
+		scale := sign*amplitude - min(max(correlation/energy, -limit), limit)
+		if slot < len(payload)*8 && sign*(correlation/energy+scale) <= 0 {
+			return nil, ErrCarrierTooLoud
+		}
+		for i := slot * chipRate; i < (slot+1)*chipRate; i++ {
+			delta := int(math.Round(scale * host.chips[i]))
+			for c := 0; c < wav.Channels; c++ {
+				wav.Samples[i*wav.Channels+c] += delta
+			}
+		}
+	}
+
+	return wav.Encode(carrier), nil
+}
+
+// despreader recovers one bit per slot by correlation, decoding lazily so a
+// wrong chip rate is rejected after the first few bytes.
+type despreader struct {
+	mono     []float64
+	chips    []float64
+	chipRate int
+	decoded  []byte
+}
+
+// correlate returns the correlation of one slot with the chips and the
+// energy of the differenced chips, both taken inside the slot only so that
+// neighbouring slots do not interfere.
+func (d *despreader) correlate(slot int) (float64, float64) {
+	var correlation, energy float64
+	for i := slot*d.chipRate + 1; i < (slot+1)*d.chipRate; i++ {
+		chip := d.chips[i] - d.chips[i-1]
+		correlation += (d.mono[i] - d.mono[i-1]) * chip
+		energy += chip * chip
+	}
+	return correlation, energy
+}
+
+func (d *despreader) bit(slot int) byte {
+	if correlation, _ := d.correlate(slot); correlation > 0 {
+		return 1
+	}
+	return 0
+}
+
+func (d *despreader) read(length int) ([]byte, bool) {
+	if length*8 > len(d.mono)/d.chipRate {
+		return nil, false
+	}
+	for len(d.decoded) < length {
+		var value byte
+		slot := len(d.decoded) * 8
+		for b := 0; b < 8; b++ {
+			value = value<<1 | d.bit(slot+b)
+		}
+		d.decoded = append(d.decoded, value)
+	}
+	return d.decoded[:length], true
+}
+
+func (s *SpreadSpectrumSteganography) Extract(carrier []byte, key string) (*ExtractResult, error) {
+	wav, err := parsePCMCarrier(carrier)
+	if err != nil {
+		return nil, err
+	}
+	if key == "" {
+		return nil, ErrNoSteganographicData
+	}
+
+	mono := wav.Mono()
+	chips := chipSequence(key, len(mono))
+	var lastErr error
+	for chipRate := MinChipRate; chipRate <= MaxChipRate; chipRate *= 2 {
+		d := &despreader{mono: mono, chips: chips, chipRate: chipRate}
+		for _, sealed := range []bool{false, true} {
+			result, err := decodePayload(d.read, key, sealed)
+			if err == nil {
+				return result, nil
+			}
+			if err == ErrWrongKey {
+				lastErr = err
+			}
+		}
+	}
+
+	if lastErr != nil {
+		return nil, lastErr
+	}
+	return nil, ErrNoSteganographicData
+}
@@ -0,0 +1,168 @@
+package stego
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/audio"
+)
+
+func TestSpreadSpectrumRoundTrip(t *testing.T) {
+	s := NewSpreadSpectrumSteganography()
+	carriers := []struct {
+		name string
+		data []byte
+	}{
+		{"mono 16-bit", testWAV(60, 8000, 1, 16, 40)},
+		{"stereo 24-bit", testWAV(60, 8000, 2, 24, 41)},
+		{"stereo 8-bit", testWAV(60, 8000, 2, 8, 42)},
+	}
+	settings := []struct {
+		chipRate int
+		strength float64
+	}{
+		{0, 0},
+		{256, 0.02},
+		{MinChipRate, 0.01},
+	}
+
+	for _, carrier := range carriers {
+		for _, setting := range settings {
+			for _, stealth := range []bool{false, true} {
+				opts := EmbedOptions{
+					Key:              "dsss key",
+					ChipRate:         setting.chipRate,
+					Strength:         setting.strength,
+					Stealth:          stealth,
+					UseEncryption:    true,
+					OriginalFilename: "a.txt",
+				}
+				name := fmt.Sprintf("%s/chip=%d/strength=%g/stealth=%v", carrier.name, setting.chipRate, setting.strength, stealth)
+				t.Run(name, func(t *testing.T) {
+					capacity, err := s.Capacity(carrier.data, opts)
+					if err != nil {
+						t.Fatal(err)
+					}
+					message := testMessage(capacity, 43)
+					stego, err := s.Embed(carrier.data, message, opts)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if len(stego) != len(carrier.data) {
+						t.Fatalf("stego is %d bytes, cover %d", len(stego), len(carrier.data))
+					}
+					if _, err := s.Embed(carrier.data, make([]byte, capacity+1), opts); !errors.Is(err, ErrInsufficientCapacity) {
+						t.Errorf("one byte over capacity: err = %v, want %v", err, ErrInsufficientCapacity)
+					}
+
+					result, err := s.Extract(stego, opts.Key)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if !bytes.Equal(result.Message, message) || result.OriginalFilename != "a.txt" || result.Metadata.Stealth != stealth {
+						t.Errorf("extracted %d bytes with %+v", len(result.Message), *result.Metadata)
+					}
+
+					for _, key := range []string{"wrong", ""} {
+						if _, err := s.Extract(stego, key); !errors.Is(err, ErrNoSteganographicData) {
+							t.Errorf("key %q: err = %v, want %v", key, err, ErrNoSteganographicData)
+						}
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestSpreadSpectrumErrors(t *testing.T) {
+	carrier := testWAV(2, 8000, 1, 16, 44)
+	s := NewSpreadSpectrumSteganography()
+
+	tests := []struct {
+		name    string
+		carrier []byte
+		opts    EmbedOptions
+		want    error
+	}{
+		{"no key", carrier, EmbedOptions{}, ErrKeyRequired},
+		{"chip rate not a power of two", carrier, EmbedOptions{Key: "k", ChipRate: 100}, ErrInvalidChipRate},
+		{"chip rate too small", carrier, EmbedOptions{Key: "k", ChipRate: 32}, ErrInvalidChipRate},
+		{"chip rate too large", carrier, EmbedOptions{Key: "k", ChipRate: 16384}, ErrInvalidChipRate},
+		{"negative strength", carrier, EmbedOptions{Key: "k", Strength: -0.01}, ErrInvalidStrength},
+		{"strength too large", carrier, EmbedOptions{Key: "k", Strength: 0.2}, ErrInvalidStrength},
+		{"mp3 carrier", testMP3(10, 45), EmbedOptions{Key: "k"}, ErrWAVRequired},
+		{"carrier too short", carrier, EmbedOptions{Key: "k", ChipRate: MaxChipRate}, ErrInsufficientCapacity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.Embed(tt.carrier, []byte("x"), tt.opts); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	rate, err := s.BitsPerSecond(carrier, EmbedOptions{ChipRate: 256})
+	if err != nil || rate != 8000.0/256 {
+		t.Errorf("BitsPerSecond = %v, %v, want %v", rate, err, 8000.0/256)
+	}
+	if _, err := s.Extract(carrier, "k"); !errors.Is(err, ErrNoSteganographicData) {
+		t.Errorf("extract from cover: err = %v, want %v", err, ErrNoSteganographicData)
+	}
+}
+
+// TestSpreadSpectrumLoudCarrier embeds into a carrier whose quiet start
+// holds the payload and whose loud, noisy rest only holds fill. There the
+// host correlates with the chips far more than the strength, and cancelling
+// it in full would bury the audio under the correction.
+func TestSpreadSpectrumLoudCarrier(t *testing.T) {
+	cover, err := audio.ParseWAV(testWAV(30, 8000, 1, 16, 46))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rng := rand.New(rand.NewSource(47))
+	peak := cover.MaxSample()
+	for i, sample := range cover.Samples {
+		if i < 5*8000 {
+			sample /= 4
+		} else {
+			sample += int(0.3 * float64(peak) * rng.NormFloat64())
+		}
+		cover.Samples[i] = min(max(sample, -peak), peak)
+	}
+	carrier := cover.Encode(testWAV(30, 8000, 1, 16, 46))
+
+	s := NewSpreadSpectrumSteganography()
+	opts := EmbedOptions{Key: "loud", ChipRate: MinChipRate, Strength: 0.005, Fill: FillRandom}
+	stego, err := s.Embed(carrier, []byte("x"), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result, err := s.Extract(stego, opts.Key); err != nil || string(result.Message) != "x" {
+		t.Fatalf("extract: %v", err)
+	}
+
+	marked, err := audio.ParseWAV(stego)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var signal, noise float64
+	for i, sample := range cover.Samples {
+		delta := float64(marked.Samples[i] - sample)
+		signal += float64(sample) * float64(sample)
+		noise += delta * delta
+	}
+	limit := (issMaxCorrection + 1) * opts.Strength * float64(peak+1)
+	floor := 10 * math.Log10(signal/float64(len(cover.Samples))/(limit*limit))
+	if snr := 10 * math.Log10(signal/noise); snr < floor {
+		t.Errorf("SNR %.1f dB, want at least %.1f dB", snr, floor)
+	}
+
+	// A payload bit in the loud part cannot be carried within the clamp.
+	if _, err := s.Embed(carrier, testMessage(200, 48), opts); !errors.Is(err, ErrCarrierTooLoud) {
+		t.Errorf("payload in the loud part: err = %v, want %v", err, ErrCarrierTooLoud)
+	}
+}
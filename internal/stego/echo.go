@@ -0,0 +1,225 @@
+package stego
+
+import (
+	"math"
+
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/audio"
+)
+
+// Echo hiding layout. The carrier is cut into segments of a power-of-two
+// length close to an eighth of a second, and every payload bit (MSB first)
+// adds a faint copy of its segment delayed by 1 ms for a zero bit or 1.5 ms
+// for a one bit. Both delays stay below the threshold where the ear hears a
+// separate echo; the result sounds like slight colouring of the timbre. The
+// two echo kernels are blended by a mixer signal that moves between them
+// with a raised-cosine ramp at every segment boundary, so switching bits
+// does not produce clicks. The extractor computes the real cepstrum of each
+// segment of the mono mix and compares the peaks at the two delays. Segments
+// after the payload are left untouched unless fill is requested. Anyone can
+// read the echoes, so a payload embedded with a key is always sealed.
+const (
+	DefaultEchoAmplitude = 0.4
+	MaxEchoAmplitude     = 0.9
+
+	minEchoSegment = 256
+
+	// echoRampFraction is the part of a segment spent crossfading from the
+	// previous segment's echo to the current one.
+	echoRampFraction = 8
+)
+
+type EchoSteganography struct{}
+
+func NewEchoSteganography() *EchoSteganography {
+	return &EchoSteganography{}
+}
+
+func (e *EchoSteganography) Name() string {
+	return "echo"
+}
+
+type echoLayout struct {
+	segment int
+	delays  [2]int
+	slots   int
+}
+
+func newEchoLayout(wav *audio.WAV) echoLayout {
+	segment := minEchoSegment
+	for segment*2 <= wav.SampleRate/8 {
+		segment *= 2
+	}
+	zero := max(wav.SampleRate/1000, 4)
+	one := max(wav.SampleRate*3/2000, zero+2)
+	return echoLayout{
+		segment: segment,
+		delays:  [2]int{zero, one},
+		slots:   wav.Frames() / segment,
+	}
+}
+
+func echoAmplitude(opts EmbedOptions) (float64, error) {
+	amplitude := opts.Strength
+	if amplitude == 0 {
+		amplitude = DefaultEchoAmplitude
+	}
+	if amplitude < 0 || amplitude > MaxEchoAmplitude {
+		return 0, ErrInvalidEchoAmplitude
+	}
+	return amplitude, nil
+}
+
+// sealed returns opts with Stealth set when a key is given. The echoes sit
+// at the same places for every key, so sealing the payload is the only way
+// the key can keep it secret.
+func (e *EchoSteganography) sealed(opts EmbedOptions) EmbedOptions {
+	if opts.Key != "" {
+		opts.Stealth = true
+	}
+	return opts
+}
+
+// Overhead counts the stealth nonce a keyed payload is sealed with.
+func (e *EchoSteganography) Overhead(messageSize int, opts EmbedOptions) (int, error) {
+	return PayloadOverhead(e.sealed(opts))
+}
+
+func (e *EchoSteganography) Capacity(carrier []byte, opts EmbedOptions) (int, error) {
+	if _, err := echoAmplitude(opts); err != nil {
+		return 0, err
+	}
+	wav, err := parsePCMCarrier(carrier)
+	if err != nil {
+		return 0, err
+	}
+	return secretCapacity(newEchoLayout(wav).slots/8, e.sealed(opts))
+}
+
+func (e *EchoSteganography) Embed(carrier, message []byte, opts EmbedOptions) ([]byte, error) {
+	amplitude, err := echoAmplitude(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	wav, err := parsePCMCarrier(carrier)
+	if err != nil {
+		return nil, err
+	}
+
+	opts = e.sealed(opts)
+	payload, err := BuildPayload(opts.metadata(len(message)), opts.Key, message)
+	if err != nil {
+		return nil, err
+	}
+
+	layout := newEchoLayout(wav)
+	if len(payload)*8 > layout.slots {
+		return nil, ErrInsufficientCapacity
+	}
+
+	noise, err := fillNoise(opts.Fill, opts.Key, (layout.slots+7)/8)
+	if err != nil {
+		return nil, err
+	}
+
+	// mix[k] holds the weight of each echo kernel in segment k.
+	mix := make([][2]float64, layout.slots)
+	for slot := range mix {
+		var bit byte
+		switch {
+		case slot < len(payload)*8:
+			bit = payload[slot/8] >> (7 - slot%8) & 1
+		case noise != nil:
+			bit = noise[slot/8] >> (7 - slot%8) & 1
+		default:
+			continue
+		}
+		mix[slot][bit] = 1
+	}
+
+	original := wav.Samples
+	wav.Samples = make([]int, len(original))
+	copy(wav.Samples, original)
+
+	ramp := layout.segment / echoRampFraction
+	var previous [2]float64
+	for slot, current := range mix {
+		if current == ([2]float64{}) && previous == current {
+			continue
+		}
+		for n := 0; n < layout.segment; n++ {
+			weights := current
+			if n < ramp {
+				fade := 0.5 - 0.5*math.Cos(math.Pi*float64(n)/float64(ramp))
+				for k := range weights {
+					weights[k] = previous[k] + (current[k]-previous[k])*fade
+				}
+			}
+
+			frame := slot*layout.segment + n
+			for c := 0; c < wav.Channels; c++ {
+				var echo float64
+				for k, delay := range layout.delays {
+					if weights[k] != 0 && frame >= delay {
+						echo += weights[k] * float64(original[(frame-delay)*wav.Channels+c])
+					}
+				}
+				wav.Samples[frame*wav.Channels+c] += int(math.Round(amplitude * echo))
+			}
+		}
+		previous = current
+	}
+
+	return wav.Encode(carrier), nil
+}
+
+// echoDecoder reads bits lazily from the cepstrum of each segment.
+type echoDecoder struct {
+	mono    []float64
+	layout  echoLayout
+	window  []float64
+	decoded []byte
+}
+
+func newEchoDecoder(wav *audio.WAV) *echoDecoder {
+	layout := newEchoLayout(wav)
+	return &echoDecoder{mono: wav.Mono(), layout: layout, window: audio.HannWindow(layout.segment)}
+}
+
+func (d *echoDecoder) bit(slot int) byte {
+	start := slot * d.layout.segment
+	cepstrum := audio.RealCepstrum(d.mono[start:start+d.layout.segment], d.window)
+	if cepstrum[d.layout.delays[1]] > cepstrum[d.layout.delays[0]] {
+		return 1
+	}
+	return 0
+}
+
+func (d *echoDecoder) read(length int) ([]byte, bool) {
+	if length*8 > d.layout.slots {
+		return nil, false
+	}
+	for len(d.decoded) < length {
+		var value byte
+		slot := len(d.decoded) * 8
+		for b := 0; b < 8; b++ {
+			value = value<<1 | d.bit(slot+b)
+		}
+		d.decoded = append(d.decoded, value)
+	}
+	return d.decoded[:length], true
+}
+
+func (e *EchoSteganography) Extract(carrier []byte, key string) (*ExtractResult, error) {
+	wav, err := parsePCMCarrier(carrier)
+	if err != nil {
+		return nil, err
+	}
+
+	d := newEchoDecoder(wav)
+	result, err := decodePayload(d.read, key, false)
+	if err == ErrNoSteganographicData && key != "" {
+		result, err = decodePayload(d.read, key, true)
+	}
+	return result, err
+}
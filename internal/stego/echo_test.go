@@ -0,0 +1,98 @@
+package stego
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestEchoRoundTrip(t *testing.T) {
+	e := NewEchoSteganography()
+	carriers := []struct {
+		name string
+		data []byte
+	}{
+		{"mono 16-bit", testWAV(40, 8000, 1, 16, 50)},
+		{"stereo 24-bit", testWAV(40, 8000, 2, 24, 51)},
+	}
+
+	for _, carrier := range carriers {
+		for _, amplitude := range []float64{0, 0.6} {
+			for _, key := range []string{"", "echo key"} {
+				for _, fill := range []FillMode{FillNone, FillRandom} {
+					opts := EmbedOptions{
+						Key:      key,
+						Strength: amplitude,
+						Fill:     fill,
+					}
+					name := fmt.Sprintf("%s/amplitude=%g/key=%q/fill=%q", carrier.name, amplitude, key, fill)
+					t.Run(name, func(t *testing.T) {
+						capacity, err := e.Capacity(carrier.data, opts)
+						if err != nil {
+							t.Fatal(err)
+						}
+						message := testMessage(capacity, 52)
+						stego, err := e.Embed(carrier.data, message, opts)
+						if err != nil {
+							t.Fatal(err)
+						}
+						if _, err := e.Embed(carrier.data, make([]byte, capacity+1), opts); !errors.Is(err, ErrInsufficientCapacity) {
+							t.Errorf("one byte over capacity: err = %v, want %v", err, ErrInsufficientCapacity)
+						}
+
+						result, err := e.Extract(stego, opts.Key)
+						if err != nil {
+							t.Fatal(err)
+						}
+						// A keyed payload is always sealed.
+						if !bytes.Equal(result.Message, message) || result.Metadata.Stealth != (key != "") {
+							t.Errorf("extracted %d bytes with %+v", len(result.Message), *result.Metadata)
+						}
+
+						if key != "" {
+							for _, wrong := range []string{"", "wrong"} {
+								if _, err := e.Extract(stego, wrong); !errors.Is(err, ErrNoSteganographicData) {
+									t.Errorf("key %q: err = %v, want %v", wrong, err, ErrNoSteganographicData)
+								}
+							}
+						}
+					})
+				}
+			}
+		}
+	}
+}
+
+func TestEchoErrors(t *testing.T) {
+	carrier := testWAV(4, 8000, 1, 16, 53)
+	e := NewEchoSteganography()
+
+	tests := []struct {
+		name    string
+		carrier []byte
+		opts    EmbedOptions
+		want    error
+	}{
+		{"negative amplitude", carrier, EmbedOptions{Strength: -0.1}, ErrInvalidEchoAmplitude},
+		{"amplitude too large", carrier, EmbedOptions{Strength: 1}, ErrInvalidEchoAmplitude},
+		{"mp3 carrier", testMP3(10, 54), EmbedOptions{}, ErrWAVRequired},
+		{"carrier too short", carrier, EmbedOptions{}, ErrInsufficientCapacity},
+		{"stealth without key", testWAV(40, 8000, 1, 16, 55), EmbedOptions{Stealth: true}, ErrKeyRequired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := e.Embed(tt.carrier, []byte("x"), tt.opts); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	rate, err := e.BitsPerSecond(carrier, EmbedOptions{})
+	if err != nil || rate != 8000.0/512 {
+		t.Errorf("BitsPerSecond = %v, %v, want %v", rate, err, 8000.0/512)
+	}
+	if _, err := e.Extract(carrier, ""); !errors.Is(err, ErrNoSteganographicData) {
+		t.Errorf("extract from cover: err = %v, want %v", err, ErrNoSteganographicData)
+	}
+}
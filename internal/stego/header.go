@@ -5,24 +5,13 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/audio"
 )
 
-type MP3FrameHeader struct {
-	Sync       uint16
-	Version    uint8
-	Layer      uint8
-	Protection uint8
-	Bitrate    uint8
-	SampleRate uint8
-	Padding    uint8
-	Private    uint8
-	Channel    uint8
-	ModeExt    uint8
-	Copyright  uint8
-	Original   uint8
-	Emphasis   uint8
-	Size       int
-}
+// MP3FrameHeader is the frame header parsed by the audio package, which owns
+// the single MP3 frame scanner shared by the decoder and every method.
+type MP3FrameHeader = audio.MP3FrameHeader
 
 type HeaderSteganography struct{}
 
@@ -30,132 +19,270 @@ func NewHeaderSteganography() *HeaderSteganography {
 	return &HeaderSteganography{}
 }
 
-var bitrateTable = []int{
-	0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0,
+var headerBitPositions = []struct {
+	offset int
+	mask   byte
+	shift  int
+}{
+	{2, 0x01, 0},
+	{3, 0x08, 3},
+	{3, 0x04, 2},
 }
 
-var sampleRateTable = []int{
-	44100, 48000, 32000, 0,
+func (h *HeaderSteganography) EmbedMessage(mp3Data, message []byte, filename string) ([]byte, error) {
+	return h.EmbedMessageWithOptions(mp3Data, message, EmbedOptions{OriginalFilename: filename})
 }
 
-func (h *HeaderSteganography) EmbedMessage(mp3Data, message []byte, filename string) ([]byte, error) {
-	dataStart := h.skipID3Tag(mp3Data)
+func (h *HeaderSteganography) EmbedMessageWithMetadata(mp3Data, message []byte, key string, useKeyForPosition bool, useEncryption bool, originalFilename string, fileType string) ([]byte, error) {
+	return h.EmbedMessageWithOptions(mp3Data, message, EmbedOptions{
+		Key:               key,
+		UseKeyForPosition: useKeyForPosition,
+		UseEncryption:     useEncryption,
+		OriginalFilename:  originalFilename,
+		FileType:          fileType,
+	})
+}
 
-	frames, offsets, err := h.findMP3Frames(mp3Data[dataStart:])
+func (h *HeaderSteganography) EmbedMessageWithOptions(mp3Data, message []byte, opts EmbedOptions) ([]byte, error) {
+	frames, offsets, err := h.locateFrames(mp3Data)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse MP3 frames: %v", err)
+		return nil, err
 	}
 
-	if len(frames) == 0 {
-		return nil, fmt.Errorf("no valid MP3 frames found")
-	}
+	metadata := opts.metadata(len(message))
+	metadata.LSBBits = 0
 
-	for i := range offsets {
-		offsets[i] += dataStart
+	payload, err := BuildPayload(metadata, opts.Key, message)
+	if err != nil {
+		return nil, err
 	}
 
-	capacity := h.calculateHeaderCapacity(frames)
-	requiredSize := len(message) + 8
-
-	if requiredSize > capacity {
-		return nil, fmt.Errorf("secret file too large: need %d bytes, have %d bytes capacity",
-			requiredSize, capacity)
+	if len(payload) > h.calculateHeaderCapacity(frames) {
+		return nil, ErrInsufficientCapacity
 	}
 
-	result, err := h.embedDataInHeaders(mp3Data, message, frames, offsets, filename)
+	noise, err := fillNoise(opts.Fill, opts.Key, len(frames))
 	if err != nil {
-		return nil, fmt.Errorf("failed to embed data: %v", err)
+		return nil, err
+	}
+
+	var startFrame int
+	if opts.UseKeyForPosition && opts.Key != "" {
+		startFrame = keyOffset(opts.Key) % len(frames)
 	}
 
+	result := make([]byte, len(mp3Data))
+	copy(result, mp3Data)
+	h.embedDataInHeaders(result, payload, offsets, startFrame, noise)
+
 	return result, nil
 }
 
 func (h *HeaderSteganography) ExtractMessage(mp3Data []byte) ([]byte, error) {
-	dataStart := h.skipID3Tag(mp3Data)
+	result, err := h.ExtractMessageWithMetadata(mp3Data, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return result.Message, nil
+}
 
-	frames, offsets, err := h.findMP3Frames(mp3Data[dataStart:])
+func (h *HeaderSteganography) ExtractMessageWithMetadata(mp3Data []byte, key string) (*ExtractResult, error) {
+	frames, offsets, err := h.locateFrames(mp3Data)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse MP3 frames: %v", err)
+		return nil, err
 	}
 
+	var lastErr error
+	for _, useKeyForPos := range []bool{false, true} {
+		var startFrame int
+		if useKeyForPos {
+			if key == "" {
+				continue
+			}
+			startFrame = keyOffset(key) % len(frames)
+		}
+
+		stream := h.readHeaderBits(mp3Data, offsets, startFrame)
+		read := func(length int) ([]byte, bool) {
+			if length > len(stream) {
+				return nil, false
+			}
+			return stream[:length], true
+		}
+
+		for _, sealed := range []bool{false, true} {
+			if sealed && key == "" {
+				continue
+			}
+
+			result, err := decodePayload(read, key, sealed)
+			if err != nil {
+				if err == ErrWrongKey || err == ErrUnsupportedVersion {
+					lastErr = err
+				}
+				continue
+			}
+			if result.Metadata.UseKeyForPosition != useKeyForPos {
+				continue
+			}
+
+			return result, nil
+		}
+	}
+
+	secretData, filename, err := h.extractDataFromHeaders(mp3Data, frames, offsets)
+	if err == nil {
+		return &ExtractResult{
+			Format:  FormatLegacyHeader,
+			Message: secretData,
+			Metadata: &EmbedMetadata{
+				OriginalFilename:  filename,
+				FileType:          DetectFileType(secretData, filename),
+				SecretMessageSize: len(secretData),
+			},
+			OriginalFilename: filename,
+			FileType:         DetectFileType(secretData, filename),
+		}, nil
+	}
+
+	if lastErr != nil {
+		return nil, lastErr
+	}
+	if _, found := h.findKeyPositioned(mp3Data, offsets); found {
+		return nil, ErrWrongKey
+	}
+	return nil, ErrNoSteganographicData
+}
+
+// findKeyPositioned tries every start frame for a container written at a
+// key-derived position and returns the start frame it was found at.
+func (h *HeaderSteganography) findKeyPositioned(mp3Data []byte, offsets []int) (int, bool) {
+	perFrame := len(headerBitPositions)
+	bits := make([]byte, len(offsets)*perFrame)
+	for i, offset := range offsets {
+		for j, pos := range headerBitPositions {
+			bits[i*perFrame+j] = (mp3Data[offset+pos.offset] & pos.mask) >> pos.shift
+		}
+	}
+	if len(bits) < containerFixedSize*8 {
+		return 0, false
+	}
+
+	magic := containerMagic
+	fixed := make([]byte, containerFixedSize)
+	for start := range offsets {
+		clear(fixed)
+		matched := true
+		for i := 0; i < containerFixedSize*8 && matched; i++ {
+			fixed[i/8] |= bits[(start*perFrame+i)%len(bits)] << (7 - i%8)
+			if i%8 == 7 && i/8 < containerMagicSize {
+				matched = fixed[i/8] == magic[i/8]
+			}
+		}
+		if matched && fixed[4] == containerVersion && fixed[5]&flagKeyPosition != 0 {
+			return start, true
+		}
+	}
+
+	return 0, false
+}
+
+func (h *HeaderSteganography) locateFrames(mp3Data []byte) ([]*MP3FrameHeader, []int, error) {
+	dataStart := audio.ID3v2Size(mp3Data)
+	if dataStart >= len(mp3Data) {
+		return nil, nil, ErrInvalidMP3Format
+	}
+
+	frames, offsets := audio.FindMP3Frames(mp3Data[dataStart:])
 	if len(frames) == 0 {
-		return nil, fmt.Errorf("no valid MP3 frames found")
+		return nil, nil, ErrNoValidFrames
 	}
 
 	for i := range offsets {
 		offsets[i] += dataStart
 	}
 
-	secretData, _, err := h.extractDataFromHeaders(mp3Data, frames, offsets)
-	if err != nil {
-		return nil, fmt.Errorf("failed to extract data: %v", err)
-	}
-
-	return secretData, nil
+	return frames, offsets, nil
 }
 
-func (h *HeaderSteganography) embedDataInHeaders(mp3Data []byte, secretData []byte, frames []*MP3FrameHeader, offsets []int, filename string) ([]byte, error) {
-	result := make([]byte, len(mp3Data))
-	copy(result, mp3Data)
+// mainDataSpans lists the main data of every frame, the bytes after the
+// header and side information. Frames with a CRC are skipped whole, so no
+// protected frame is rewritten.
+func mainDataSpans(carrier []byte) ([]ByteRange, int, error) {
+	frames, offsets, err := NewHeaderSteganography().locateFrames(carrier)
+	if err != nil {
+		return nil, 0, err
+	}
 
-	filenameBytes := []byte(filename)
-	if len(filenameBytes) > 255 {
-		filenameBytes = filenameBytes[:255]
+	spans := make([]ByteRange, 0, len(frames))
+	units := 0
+	for i, frame := range frames {
+		if frame.Protection == 0 {
+			continue
+		}
+		start := offsets[i] + mp3HeaderSize + frame.SideInfoSize()
+		end := offsets[i] + frame.Size
+		if end > start {
+			spans = append(spans, ByteRange{Start: start, End: end})
+			units += end - start
+		}
+	}
+	if units == 0 {
+		return nil, 0, ErrNoValidFrames
 	}
 
-	payload := make([]byte, 0)
-	dataLen := len(secretData)
-	payload = append(payload, byte(dataLen>>24), byte(dataLen>>16), byte(dataLen>>8), byte(dataLen))
-	filenameLen := len(filenameBytes)
-	payload = append(payload, byte(filenameLen>>24), byte(filenameLen>>16), byte(filenameLen>>8), byte(filenameLen))
-	payload = append(payload, filenameBytes...)
-	payload = append(payload, secretData...)
+	return spans, units, nil
+}
 
+func (h *HeaderSteganography) embedDataInHeaders(result []byte, payload []byte, offsets []int, startFrame int, fill []byte) {
+	totalBits := len(payload) * 8
 	bitIndex := 0
-	payloadIndex := 0
 
-	for frameIdx := range frames {
-		if payloadIndex >= len(payload) {
+	for i := range offsets {
+		frameIdx := (startFrame + i) % len(offsets)
+		if bitIndex >= totalBits && fill == nil {
 			break
 		}
 
 		frameOffset := offsets[frameIdx]
 
-		safeBitPositions := []struct {
-			offset int
-			mask   byte
-			shift  int
-		}{
-			{2, 0x01, 0},
-			{3, 0x08, 3},
-			{3, 0x04, 2},
-		}
-
-		for _, pos := range safeBitPositions {
-			if payloadIndex >= len(payload) {
+		for posIdx, pos := range headerBitPositions {
+			var bitToEmbed byte
+			if bitIndex < totalBits {
+				bitToEmbed = (payload[bitIndex/8] >> (7 - bitIndex%8)) & 1
+				bitIndex++
+			} else if fill != nil {
+				bitToEmbed = (fill[frameIdx] >> posIdx) & 1
+			} else {
 				break
 			}
 
-			payloadByte := payload[payloadIndex]
-			bitToEmbed := (payloadByte >> (7 - bitIndex)) & 1
-
 			byteOffset := frameOffset + pos.offset
 			result[byteOffset] = (result[byteOffset] & ^pos.mask) | (bitToEmbed << pos.shift)
-
-			bitIndex++
-			if bitIndex == 8 {
-				bitIndex = 0
-				payloadIndex++
-			}
 		}
 	}
+}
+
+func (h *HeaderSteganography) readHeaderBits(mp3Data []byte, offsets []int, startFrame int) []byte {
+	stream := make([]byte, len(offsets)*len(headerBitPositions)/8)
+	bitIndex := 0
 
-	if payloadIndex < len(payload) {
-		return nil, fmt.Errorf("could not embed all data: embedded %d/%d bytes",
-			payloadIndex, len(payload))
+	for i := range offsets {
+		frameOffset := offsets[(startFrame+i)%len(offsets)]
+
+		for _, pos := range headerBitPositions {
+			if bitIndex/8 >= len(stream) {
+				return stream
+			}
+
+			bit := (mp3Data[frameOffset+pos.offset] & pos.mask) >> pos.shift
+			stream[bitIndex/8] |= bit << (7 - bitIndex%8)
+			bitIndex++
+		}
 	}
 
-	return result, nil
+	return stream
 }
 
 func (h *HeaderSteganography) extractDataFromHeaders(mp3Data []byte, frames []*MP3FrameHeader, offsets []int) ([]byte, string, error) {
@@ -170,20 +297,10 @@ func (h *HeaderSteganography) extractDataFromHeaders(mp3Data []byte, frames []*M
 	var filenameBytes []byte
 	var dataBytes []byte
 
-	safeBitPositions := []struct {
-		offset int
-		mask   byte
-		shift  int
-	}{
-		{2, 0x01, 0},
-		{3, 0x08, 3},
-		{3, 0x04, 2},
-	}
-
 	for frameIdx := range frames {
 		frameOffset := offsets[frameIdx]
 
-		for _, pos := range safeBitPositions {
+		for _, pos := range headerBitPositions {
 			byteOffset := frameOffset + pos.offset
 			extractedBit := (mp3Data[byteOffset] & pos.mask) >> pos.shift
 
@@ -240,103 +357,21 @@ func (h *HeaderSteganography) calculateHeaderCapacity(frames []*MP3FrameHeader)
 }
 
 func (h *HeaderSteganography) CalculateCapacity(mp3Data []byte) (int, int, error) {
-	dataStart := h.skipID3Tag(mp3Data)
-
-	frames, _, err := h.findMP3Frames(mp3Data[dataStart:])
+	frames, _, err := h.locateFrames(mp3Data)
 	if err != nil {
-		return 0, 0, fmt.Errorf("failed to parse MP3 frames: %v", err)
-	}
-
-	if len(frames) == 0 {
-		return 0, 0, fmt.Errorf("no valid MP3 frames found")
-	}
-
-	rawCapacity := h.calculateHeaderCapacity(frames)
-
-	actualCapacity := rawCapacity - 8
-	if actualCapacity < 0 {
-		actualCapacity = 0
-	}
-
-	return actualCapacity, len(frames), nil
-}
-
-func (h *HeaderSteganography) skipID3Tag(data []byte) int {
-	if len(data) < 10 {
-		return 0
-	}
-
-	if data[0] == 'I' && data[1] == 'D' && data[2] == '3' {
-		size := int(data[6])<<21 | int(data[7])<<14 | int(data[8])<<7 | int(data[9])
-		return 10 + size
+		return 0, 0, err
 	}
 
-	return 0
-}
-
-func (h *HeaderSteganography) parseMP3Frame(data []byte, offset int) (*MP3FrameHeader, error) {
-	if len(data) < offset+4 {
-		return nil, fmt.Errorf("not enough data for frame header")
-	}
-
-	if data[offset] != 0xFF || (data[offset+1]&0xE0) != 0xE0 {
-		return nil, fmt.Errorf("invalid frame sync")
-	}
-
-	header := &MP3FrameHeader{}
-	b1, b2, b3, b4 := data[offset], data[offset+1], data[offset+2], data[offset+3]
-
-	header.Sync = uint16(b1)<<3 | uint16(b2>>5)
-	header.Version = (b2 >> 3) & 0x03
-	header.Layer = (b2 >> 1) & 0x03
-	header.Protection = b2 & 0x01
-	header.Bitrate = (b3 >> 4) & 0x0F
-	header.SampleRate = (b3 >> 2) & 0x03
-	header.Padding = (b3 >> 1) & 0x01
-	header.Private = b3 & 0x01
-	header.Channel = (b4 >> 6) & 0x03
-	header.ModeExt = (b4 >> 4) & 0x03
-	header.Copyright = (b4 >> 3) & 0x01
-	header.Original = (b4 >> 2) & 0x01
-	header.Emphasis = b4 & 0x03
-
-	if header.Version == 3 && header.Layer == 1 {
-		bitrate := bitrateTable[header.Bitrate] * 1000
-		sampleRate := sampleRateTable[header.SampleRate]
-
-		if bitrate == 0 || sampleRate == 0 {
-			return nil, fmt.Errorf("invalid bitrate or sample rate")
-		}
-
-		header.Size = (144*bitrate)/sampleRate + int(header.Padding)
-	} else {
-		return nil, fmt.Errorf("unsupported MP3 format")
+	capacity, err := secretCapacity(h.calculateHeaderCapacity(frames), EmbedOptions{})
+	if err != nil {
+		return 0, 0, err
 	}
 
-	return header, nil
+	return capacity, len(frames), nil
 }
 
-func (h *HeaderSteganography) findMP3Frames(data []byte) ([]*MP3FrameHeader, []int, error) {
-	var frames []*MP3FrameHeader
-	var offsets []int
-
-	i := 0
-	for i < len(data)-4 {
-		if data[i] == 0xFF && (data[i+1]&0xE0) == 0xE0 {
-			frame, err := h.parseMP3Frame(data, i)
-			if err == nil && frame.Size > 0 && i+frame.Size <= len(data) {
-				frames = append(frames, frame)
-				offsets = append(offsets, i)
-				i += frame.Size
-			} else {
-				i++
-			}
-		} else {
-			i++
-		}
-	}
-
-	return frames, offsets, nil
+func ScanMP3Frames(mp3Data []byte) ([]*MP3FrameHeader, []int, error) {
+	return NewHeaderSteganography().locateFrames(mp3Data)
 }
 
 func readFile(path string) ([]byte, error) {
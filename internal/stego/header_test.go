@@ -0,0 +1,155 @@
+package stego
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestHeaderRoundTrip(t *testing.T) {
+	carrier := testMP3(1500, 13)
+	message := []byte("header secret payload")
+	h := NewHeaderSteganography()
+	frames, offsets, err := ScanMP3Frames(carrier)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, fill := range []FillMode{FillNone, FillRandom, FillKeyed} {
+		for flags := 0; flags < 16; flags++ {
+			opts := EmbedOptions{
+				Key:               "header key",
+				UseEncryption:     flags&1 != 0,
+				UseKeyForPosition: flags&2 != 0,
+				Stealth:           flags&4 != 0,
+				Compressed:        flags&8 != 0,
+				Fill:              fill,
+				OriginalFilename:  "s.txt",
+			}
+			name := fmt.Sprintf("fill=%q/encrypt=%v/position=%v/stealth=%v/compressed=%v",
+				fill, opts.UseEncryption, opts.UseKeyForPosition, opts.Stealth, opts.Compressed)
+			t.Run(name, func(t *testing.T) {
+				stego, err := h.Embed(carrier, message, opts)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for i, offset := range offsets {
+					end := offset + frames[i].Size
+					if !bytes.Equal(stego[offset+4:end], carrier[offset+4:end]) {
+						t.Fatalf("embedding changed the audio bytes of frame %d", i)
+					}
+				}
+				if comparison := CompareFrames(carrier, stego); !comparison.Match {
+					t.Fatalf("embedding changed the %s of frame %d", comparison.MismatchField, comparison.FirstMismatch)
+				}
+
+				result, err := h.Extract(stego, opts.Key)
+				if err != nil {
+					t.Fatal(err)
+				}
+				want := opts.metadata(len(message))
+				want.LSBBits = 0
+				if !bytes.Equal(result.Message, message) || *result.Metadata != *want {
+					t.Errorf("extracted %q with %+v, want %+v", result.Message, *result.Metadata, *want)
+				}
+
+				_, err = h.Extract(stego, "wrong")
+				switch {
+				case opts.Stealth && !errors.Is(err, ErrNoSteganographicData):
+					t.Errorf("wrong key on stealth payload: err = %v, want %v", err, ErrNoSteganographicData)
+				case !opts.Stealth && (opts.UseEncryption || opts.UseKeyForPosition) && !errors.Is(err, ErrWrongKey):
+					t.Errorf("wrong key: err = %v, want %v", err, ErrWrongKey)
+				}
+			})
+		}
+	}
+}
+
+func TestHeaderErrors(t *testing.T) {
+	carrier := testMP3(100, 14)
+	h := NewHeaderSteganography()
+	capacity, err := h.Capacity(carrier, EmbedOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		carrier []byte
+		message []byte
+		opts    EmbedOptions
+		want    error
+	}{
+		{"fits", carrier, make([]byte, capacity), EmbedOptions{}, nil},
+		{"too large", carrier, make([]byte, capacity+1), EmbedOptions{}, ErrInsufficientCapacity},
+		{"no frames", testMessage(4000, 15), []byte("x"), EmbedOptions{}, ErrNoValidFrames},
+		{"stealth without key", carrier, []byte("x"), EmbedOptions{Stealth: true}, ErrKeyRequired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := h.Embed(tt.carrier, tt.message, tt.opts); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	stego, err := h.Embed(carrier, []byte("hello"), EmbedOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, offsets, err := ScanMP3Frames(stego)
+	if err != nil {
+		t.Fatal(err)
+	}
+	extractTests := []struct {
+		name    string
+		carrier []byte
+		want    error
+	}{
+		{"cover", carrier, ErrNoSteganographicData},
+		{"truncated", stego[:offsets[10]], ErrNoSteganographicData},
+		{"not mp3", testMessage(4000, 16), ErrNoValidFrames},
+	}
+	for _, tt := range extractTests {
+		t.Run("extract "+tt.name, func(t *testing.T) {
+			if _, err := h.Extract(tt.carrier, ""); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// testdata/baseline_header.mp3 was written by the header method before the
+// container existed: a length-prefixed filename and message in the private,
+// copyright and original bits of 160 MPEG-1 frames after an ID3v2.3 tag.
+func TestHeaderBaselineCarrier(t *testing.T) {
+	carrier, err := os.ReadFile("testdata/baseline_header.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Version 2.3 has no footer, so a stray 0x10 flag must not move the scan.
+	flagged := append([]byte(nil), carrier...)
+	flagged[5] |= 0x10
+
+	tests := []struct {
+		name    string
+		carrier []byte
+	}{
+		{"as written", carrier},
+		{"footer flag on v2.3 tag", flagged},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewHeaderSteganography().Extract(tt.carrier, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Format != FormatLegacyHeader || result.OriginalFilename != "note.txt" ||
+				string(result.Message) != "written by the original header method" {
+				t.Errorf("extracted %q from %q as %s", result.Message, result.OriginalFilename, result.Format)
+			}
+		})
+	}
+}
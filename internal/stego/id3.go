@@ -0,0 +1,216 @@
+package stego
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+type ID3v2Tag struct {
+	Version   string       `json:"version"`
+	Size      int          `json:"size"`
+	Flags     byte         `json:"flags"`
+	Frames    []ID3v2Frame `json:"frames"`
+	HasFooter bool         `json:"has_footer"`
+}
+
+type ID3v2Frame struct {
+	ID   string `json:"id"`
+	Size int    `json:"size"`
+	Text string `json:"text,omitempty"`
+}
+
+type ID3v1Tag struct {
+	Title   string `json:"title"`
+	Artist  string `json:"artist"`
+	Album   string `json:"album"`
+	Year    string `json:"year"`
+	Comment string `json:"comment"`
+	Track   int    `json:"track,omitempty"`
+	Genre   int    `json:"genre"`
+}
+
+const (
+	id3v2HeaderSize = 10
+	id3v1TagSize    = 128
+)
+
+func syncsafeInt(b []byte) int {
+	value := 0
+	for _, c := range b {
+		value = value<<7 | int(c&0x7F)
+	}
+	return value
+}
+
+func bigEndianInt(b []byte) int {
+	value := 0
+	for _, c := range b {
+		value = value<<8 | int(c)
+	}
+	return value
+}
+
+func ParseID3v2(data []byte) *ID3v2Tag {
+	if len(data) < id3v2HeaderSize || string(data[:3]) != "ID3" {
+		return nil
+	}
+
+	major := data[3]
+	tag := &ID3v2Tag{
+		Version:   fmt.Sprintf("2.%d.%d", major, data[4]),
+		Flags:     data[5],
+		Size:      id3v2HeaderSize + syncsafeInt(data[6:10]),
+		HasFooter: major == 4 && data[5]&0x10 != 0,
+	}
+	if tag.HasFooter {
+		tag.Size += id3v2HeaderSize
+	}
+
+	end := tag.Size
+	if end > len(data) {
+		end = len(data)
+	}
+	body := data[id3v2HeaderSize:end]
+
+	if data[5]&0x40 != 0 && len(body) >= 4 {
+		var extSize int
+		if major == 4 {
+			extSize = syncsafeInt(body[:4])
+		} else {
+			extSize = bigEndianInt(body[:4]) + 4
+		}
+		if extSize > len(body) {
+			return tag
+		}
+		body = body[extSize:]
+	}
+
+	idLen, sizeLen, headerLen := 4, 4, 10
+	if major == 2 {
+		idLen, sizeLen, headerLen = 3, 3, 6
+	}
+
+	for len(body) >= headerLen && body[0] != 0 {
+		id := string(body[:idLen])
+		sizeBytes := body[idLen : idLen+sizeLen]
+
+		var size int
+		if major == 4 {
+			size = syncsafeInt(sizeBytes)
+		} else {
+			size = bigEndianInt(sizeBytes)
+		}
+		if size < 0 || headerLen+size > len(body) {
+			break
+		}
+
+		content := body[headerLen : headerLen+size]
+		frame := ID3v2Frame{ID: id, Size: size}
+		switch {
+		case id == "COMM" || id == "COM":
+			frame.Text = decodeCommentFrame(content)
+		case strings.HasPrefix(id, "T"):
+			frame.Text = decodeTextFrame(content)
+		}
+		tag.Frames = append(tag.Frames, frame)
+
+		body = body[headerLen+size:]
+	}
+
+	return tag
+}
+
+func decodeTextFrame(content []byte) string {
+	if len(content) == 0 {
+		return ""
+	}
+	return strings.TrimRight(decodeID3String(content[0], content[1:]), "\x00")
+}
+
+func decodeCommentFrame(content []byte) string {
+	if len(content) < 4 {
+		return ""
+	}
+
+	encoding := content[0]
+	rest := content[4:]
+
+	terminator := []byte{0}
+	if encoding == 1 || encoding == 2 {
+		terminator = []byte{0, 0}
+	}
+	for i := 0; i+len(terminator) <= len(rest); i += len(terminator) {
+		if string(rest[i:i+len(terminator)]) == string(terminator) {
+			rest = rest[i+len(terminator):]
+			break
+		}
+	}
+
+	return strings.TrimRight(decodeID3String(encoding, rest), "\x00")
+}
+
+func decodeID3String(encoding byte, data []byte) string {
+	switch encoding {
+	case 1, 2:
+		bigEndian := encoding == 2
+		if len(data) >= 2 {
+			if data[0] == 0xFF && data[1] == 0xFE {
+				bigEndian = false
+				data = data[2:]
+			} else if data[0] == 0xFE && data[1] == 0xFF {
+				bigEndian = true
+				data = data[2:]
+			}
+		}
+
+		units := make([]uint16, 0, len(data)/2)
+		for i := 0; i+1 < len(data); i += 2 {
+			if bigEndian {
+				units = append(units, uint16(data[i])<<8|uint16(data[i+1]))
+			} else {
+				units = append(units, uint16(data[i+1])<<8|uint16(data[i]))
+			}
+		}
+		return string(utf16.Decode(units))
+	case 3:
+		return string(data)
+	default:
+		runes := make([]rune, len(data))
+		for i, c := range data {
+			runes[i] = rune(c)
+		}
+		return string(runes)
+	}
+}
+
+func ParseID3v1(data []byte) *ID3v1Tag {
+	if len(data) < id3v1TagSize {
+		return nil
+	}
+
+	tag := data[len(data)-id3v1TagSize:]
+	if string(tag[:3]) != "TAG" {
+		return nil
+	}
+
+	field := func(b []byte) string {
+		return strings.TrimRight(decodeID3String(0, b), "\x00 ")
+	}
+
+	result := &ID3v1Tag{
+		Title:   field(tag[3:33]),
+		Artist:  field(tag[33:63]),
+		Album:   field(tag[63:93]),
+		Year:    field(tag[93:97]),
+		Comment: field(tag[97:127]),
+		Genre:   int(tag[127]),
+	}
+
+	if tag[125] == 0 && tag[126] != 0 {
+		result.Comment = field(tag[97:125])
+		result.Track = int(tag[126])
+	}
+
+	return result
+}
@@ -1,9 +1,6 @@
 package stego
 
-import (
-	"bytes"
-	"encoding/binary"
-)
+import "encoding/binary"
 
 type LSBSteganography struct {
 	headerSize int
@@ -16,6 +13,14 @@ func NewLSBSteganography() *LSBSteganography {
 }
 
 func (l *LSBSteganography) calculateKeyOffset(key string) int {
+	return keyOffset(key)
+}
+
+// maxKeyOffset bounds the key-derived offsets searched without the key.
+// keyOffset stays below it for keys of up to 512 characters.
+const maxKeyOffset = 1 << 16
+
+func keyOffset(key string) int {
 	if key == "" {
 		return 0
 	}
@@ -36,39 +41,28 @@ func (l *LSBSteganography) EmbedMessageWithKey(mp3Data, message []byte, bits int
 }
 
 func (l *LSBSteganography) EmbedMessageWithMetadata(mp3Data, message []byte, bits int, key string, useKeyForPosition bool, useEncryption bool, originalFilename string, fileType string) ([]byte, error) {
-	if bits < 1 || bits > 4 {
-		return nil, ErrInvalidBitCount
-	}
-
-	if len(mp3Data) <= l.headerSize {
-		return nil, ErrInvalidMP3Format
-	}
-
-	metadata := &EmbedMetadata{
-		UseEncryption:     useEncryption,
+	return l.EmbedMessageWithOptions(mp3Data, message, EmbedOptions{
+		Bits:              bits,
+		Key:               key,
 		UseKeyForPosition: useKeyForPosition,
-		LSBBits:           bits,
+		UseEncryption:     useEncryption,
 		OriginalFilename:  originalFilename,
 		FileType:          fileType,
-		SecretMessageSize: len(message),
-	}
+	})
+}
 
-	metadataBytes, err := SerializeMetadata(metadata, key)
+func (l *LSBSteganography) EmbedMessageWithOptions(mp3Data, message []byte, opts EmbedOptions) ([]byte, error) {
+	bits := opts.Bits
+	capacity, err := l.payloadCapacity(len(mp3Data), opts)
 	if err != nil {
 		return nil, err
 	}
 
-	var payload bytes.Buffer
-
-	binary.Write(&payload, binary.BigEndian, uint32(len(metadataBytes)))
-	payload.Write(metadataBytes)
-
-	binary.Write(&payload, binary.BigEndian, uint32(len(message)))
-	payload.Write(message)
-
-	payloadData := payload.Bytes()
+	payloadData, err := BuildPayload(opts.metadata(len(message)), opts.Key, message)
+	if err != nil {
+		return nil, err
+	}
 
-	capacity := CalculateCapacity(len(mp3Data), bits)
 	if len(payloadData) > capacity {
 		return nil, ErrInsufficientCapacity
 	}
@@ -77,16 +71,26 @@ func (l *LSBSteganography) EmbedMessageWithMetadata(mp3Data, message []byte, bit
 	copy(result, mp3Data)
 
 	var offset int
-	if useKeyForPosition && key != "" {
-		offset = l.calculateKeyOffset(key)
+	if opts.UseKeyForPosition && opts.Key != "" {
+		offset = l.calculateKeyOffset(opts.Key)
 	}
 
-	l.embedDataWithOffset(result[l.headerSize:], payloadData, bits, offset)
+	carrier := result[l.headerSize:]
+	noise, err := fillNoise(opts.Fill, opts.Key, len(carrier))
+	if err != nil {
+		return nil, err
+	}
+
+	l.embedDataWithOffset(carrier, payloadData, bits, offset, noise)
 
 	return result, nil
 }
 
-func (l *LSBSteganography) embedDataWithOffset(carrier, message []byte, bits int, offset int) {
+func (l *LSBSteganography) embedDataWithOffset(carrier, message []byte, bits int, offset int, fill []byte) {
+	if fill != nil {
+		applyFill(carrier, bits, fill)
+	}
+
 	mask := byte((1 << bits) - 1)
 	totalMessageBits := len(message) * 8
 
@@ -106,10 +110,15 @@ func (l *LSBSteganography) embedDataWithOffset(carrier, message []byte, bits int
 
 		messageBitStart := i
 		var chunk byte = 0
-		for b := 0; b < bits && messageBitStart+b < totalMessageBits; b++ {
-			msgByte := message[(messageBitStart+b)/8]
-			msgBitPos := 7 - ((messageBitStart + b) % 8)
-			msgBit := (msgByte >> msgBitPos) & 1
+		for b := 0; b < bits; b++ {
+			var msgBit byte
+			if messageBitStart+b < totalMessageBits {
+				msgByte := message[(messageBitStart+b)/8]
+				msgBitPos := 7 - ((messageBitStart + b) % 8)
+				msgBit = (msgByte >> msgBitPos) & 1
+			} else {
+				msgBit = (carrier[carrierIndex] >> (bits - 1 - b)) & 1
+			}
 			chunk = (chunk << 1) | msgBit
 		}
 
@@ -119,6 +128,31 @@ func (l *LSBSteganography) embedDataWithOffset(carrier, message []byte, bits int
 	}
 }
 
+func (l *LSBSteganography) readPayload(carrier []byte, startOffset, bits, startBit, length int) ([]byte, bool) {
+	mask := byte((1 << bits) - 1)
+	data := make([]byte, length)
+	totalBits := length * 8
+
+	for i := 0; i < totalBits; {
+		bitPos := startBit + i
+		carrierIndex := startOffset + bitPos/bits
+		if carrierIndex >= len(carrier) {
+			return nil, false
+		}
+
+		bitChunk := carrier[carrierIndex] & mask
+		for b := bitPos % bits; b < bits && i < totalBits; b++ {
+			bitValue := (bitChunk >> (bits - 1 - b)) & 1
+			if bitValue == 1 {
+				data[i/8] |= 1 << (7 - i%8)
+			}
+			i++
+		}
+	}
+
+	return data, true
+}
+
 func (l *LSBSteganography) ExtractMessage(mp3Data []byte, bits int) ([]byte, error) {
 	return l.ExtractMessageWithKey(mp3Data, bits, "", false)
 }
@@ -190,6 +224,8 @@ func (l *LSBSteganography) ExtractMessageWithKey(mp3Data []byte, bits int, key s
 }
 
 type ExtractResult struct {
+	Method           string
+	Format           string
 	Message          []byte
 	Metadata         *EmbedMetadata
 	OriginalFilename string
@@ -202,10 +238,9 @@ func (l *LSBSteganography) ExtractMessageWithMetadata(mp3Data []byte, key string
 	}
 
 	stegoData := mp3Data[l.headerSize:]
+	var lastErr error
 
 	for bits := 1; bits <= 4; bits++ {
-		mask := byte((1 << bits) - 1)
-
 		for _, useKeyForPos := range []bool{false, true} {
 			var offset int
 			if useKeyForPos && key != "" {
@@ -213,148 +248,133 @@ func (l *LSBSteganography) ExtractMessageWithMetadata(mp3Data []byte, key string
 			}
 			startOffset := offset % len(stegoData)
 
-			metadataLengthBits := 32
-			metadataLengthBytes := make([]byte, 4)
-
-			success := true
-			for i := 0; i < metadataLengthBits && success; i += bits {
-				carrierIndex := startOffset + (i / bits)
-				if carrierIndex >= len(stegoData) {
-					success = false
-					break
-				}
-
-				bitChunk := stegoData[carrierIndex] & mask
-
-				for b := 0; b < bits && i+b < metadataLengthBits; b++ {
-					bitValue := (bitChunk >> (bits - 1 - b)) & 1
-					byteIndex := (i + b) / 8
-					bitPos := 7 - ((i + b) % 8)
-
-					if bitValue == 1 {
-						metadataLengthBytes[byteIndex] |= (1 << bitPos)
-					}
-				}
+			result, err := l.extractContainer(stegoData, startOffset, bits, key, false)
+			if err == ErrNoSteganographicData && key != "" {
+				result, err = l.extractContainer(stegoData, startOffset, bits, key, true)
 			}
-
-			if !success {
+			if err == ErrNoSteganographicData {
+				result, err = l.extractLegacy(stegoData, startOffset, bits, key)
+			}
+			if err != nil {
+				if err == ErrWrongKey {
+					lastErr = err
+				}
 				continue
 			}
 
-			metadataLength := binary.BigEndian.Uint32(metadataLengthBytes)
-
-			if metadataLength == 0 || metadataLength > 10000 {
+			if result.Metadata.LSBBits != bits || result.Metadata.UseKeyForPosition != useKeyForPos || result.Metadata.Partitioned {
 				continue
 			}
 
-			metadataBytes := make([]byte, metadataLength)
-			totalMetadataBits := int(metadataLength) * 8
-			metadataStartBit := 32
+			return result, nil
+		}
+	}
 
-			for i := 0; i < totalMetadataBits; i += bits {
-				carrierIndex := startOffset + ((i + metadataStartBit) / bits)
-				if carrierIndex >= len(stegoData) {
-					success = false
-					break
+	if key != "" {
+		for bits := 1; bits <= 4; bits++ {
+			regions := l.partitionRegions(stegoData)
+			for _, slot := range slotOrder(key) {
+				result, err := l.extractContainer(regions[slot], 0, bits, key, true)
+				if err != nil || result.Metadata.LSBBits != bits || !result.Metadata.Partitioned {
+					continue
 				}
 
-				bitChunk := stegoData[carrierIndex] & mask
-
-				for b := 0; b < bits && i+b < totalMetadataBits; b++ {
-					bitValue := (bitChunk >> (bits - 1 - b)) & 1
-					byteIndex := (i + b) / 8
-					bitPos := 7 - ((i + b) % 8)
-
-					if bitValue == 1 {
-						metadataBytes[byteIndex] |= (1 << bitPos)
-					}
-				}
+				return result, nil
 			}
+		}
+	}
 
-			if !success {
-				continue
-			}
+	if lastErr != nil {
+		return nil, lastErr
+	}
+	if _, _, found := l.findKeyPositioned(stegoData); found {
+		return nil, ErrWrongKey
+	}
+	return nil, ErrNoSteganographicData
+}
 
-			metadata, _, err := DeserializeMetadata(metadataBytes, key)
-			if err != nil {
-				continue
-			}
+// findKeyPositioned searches the first maxKeyOffset bytes for a container
+// written at a key-derived offset and returns that offset and its LSB depth.
+// The magic no longer depends on the key, so this finds the container even
+// when the key that would locate it is unknown or wrong.
+func (l *LSBSteganography) findKeyPositioned(stegoData []byte) (int, int, bool) {
+	magic := uint64(binary.BigEndian.Uint32(containerMagic))
+	limit := min(maxKeyOffset, len(stegoData))
 
-			if metadata.LSBBits != bits || metadata.UseKeyForPosition != useKeyForPos {
+	for bits := 1; bits <= 4; bits++ {
+		mask := byte(1<<bits - 1)
+		var window uint64
+		for i := 0; i < limit; i++ {
+			window = window<<bits | uint64(stegoData[i]&mask)
+			consumed := (i + 1) * bits
+			if consumed < 32 {
 				continue
 			}
 
-			messageStartBit := metadataStartBit + totalMetadataBits
-
-			messageLengthBits := 32
-			messageLengthBytes := make([]byte, 4)
-
-			for i := 0; i < messageLengthBits; i += bits {
-				carrierIndex := startOffset + ((i + messageStartBit) / bits)
-				if carrierIndex >= len(stegoData) {
-					success = false
-					break
-				}
-
-				bitChunk := stegoData[carrierIndex] & mask
-
-				for b := 0; b < bits && i+b < messageLengthBits; b++ {
-					bitValue := (bitChunk >> (bits - 1 - b)) & 1
-					byteIndex := (i + b) / 8
-					bitPos := 7 - ((i + b) % 8)
-
-					if bitValue == 1 {
-						messageLengthBytes[byteIndex] |= (1 << bitPos)
-					}
-				}
+			offset := (consumed - 32) / bits
+			if window>>(consumed-offset*bits-32)&0xFFFFFFFF != magic {
+				continue
 			}
 
-			if !success {
-				continue
+			fixed, ok := l.readPayload(stegoData, offset, bits, 0, containerFixedSize)
+			if ok && fixed[4] == containerVersion && fixed[5]&flagKeyPosition != 0 && int(fixed[6]) == bits {
+				return offset, bits, true
 			}
+		}
+	}
 
-			messageLength := binary.BigEndian.Uint32(messageLengthBytes)
+	return 0, 0, false
+}
 
-			if int(messageLength) != metadata.SecretMessageSize {
-				continue
-			}
+func (l *LSBSteganography) extractContainer(stegoData []byte, startOffset, bits int, key string, sealed bool) (*ExtractResult, error) {
+	return decodePayload(func(length int) ([]byte, bool) {
+		return l.readPayload(stegoData, startOffset, bits, 0, length)
+	}, key, sealed)
+}
 
-			message := make([]byte, messageLength)
-			totalMessageBits := int(messageLength) * 8
-			messageDataStartBit := messageStartBit + messageLengthBits
+func (l *LSBSteganography) extractLegacy(stegoData []byte, startOffset, bits int, key string) (*ExtractResult, error) {
+	metadataLengthBytes, ok := l.readPayload(stegoData, startOffset, bits, 0, 4)
+	if !ok {
+		return nil, ErrNoSteganographicData
+	}
 
-			for i := 0; i < totalMessageBits; i += bits {
-				carrierIndex := startOffset + ((i + messageDataStartBit) / bits)
-				if carrierIndex >= len(stegoData) {
-					success = false
-					break
-				}
+	metadataLength := binary.BigEndian.Uint32(metadataLengthBytes)
+	if metadataLength == 0 || metadataLength > 10000 {
+		return nil, ErrNoSteganographicData
+	}
 
-				bitChunk := stegoData[carrierIndex] & mask
+	metadataStartBit := 32
+	metadataBytes, ok := l.readPayload(stegoData, startOffset, bits, metadataStartBit, int(metadataLength))
+	if !ok {
+		return nil, ErrNoSteganographicData
+	}
 
-				for b := 0; b < bits && i+b < totalMessageBits; b++ {
-					bitValue := (bitChunk >> (bits - 1 - b)) & 1
-					byteIndex := (i + b) / 8
-					bitPos := 7 - ((i + b) % 8)
+	metadata, _, err := DeserializeMetadata(metadataBytes, key)
+	if err != nil {
+		return nil, err
+	}
 
-					if bitValue == 1 {
-						message[byteIndex] |= (1 << bitPos)
-					}
-				}
-			}
+	messageStartBit := metadataStartBit + int(metadataLength)*8
+	messageLengthBytes, ok := l.readPayload(stegoData, startOffset, bits, messageStartBit, 4)
+	if !ok {
+		return nil, ErrNoSteganographicData
+	}
 
-			if !success {
-				continue
-			}
+	messageLength := binary.BigEndian.Uint32(messageLengthBytes)
+	if int(messageLength) != metadata.SecretMessageSize {
+		return nil, ErrNoSteganographicData
+	}
 
-			return &ExtractResult{
-				Message:          message,
-				Metadata:         metadata,
-				OriginalFilename: metadata.OriginalFilename,
-				FileType:         metadata.FileType,
-			}, nil
-		}
+	message, ok := l.readPayload(stegoData, startOffset, bits, messageStartBit+32, int(messageLength))
+	if !ok {
+		return nil, ErrNoSteganographicData
 	}
 
-	return nil, ErrNoSteganographicData
+	return &ExtractResult{
+		Format:           FormatLegacyJSON,
+		Message:          message,
+		Metadata:         metadata,
+		OriginalFilename: metadata.OriginalFilename,
+		FileType:         metadata.FileType,
+	}, nil
 }
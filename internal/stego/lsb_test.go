@@ -0,0 +1,157 @@
+package stego
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestLSBRoundTrip(t *testing.T) {
+	carrier := testMP3(300, 1)
+	message := testMessage(500, 2)
+	l := NewLSBSteganography()
+
+	for bits := 1; bits <= 4; bits++ {
+		for _, fill := range []FillMode{FillNone, FillRandom, FillKeyed} {
+			for flags := 0; flags < 16; flags++ {
+				opts := EmbedOptions{
+					Bits:              bits,
+					Key:               "round-trip key",
+					UseEncryption:     flags&1 != 0,
+					UseKeyForPosition: flags&2 != 0,
+					Stealth:           flags&4 != 0,
+					Compressed:        flags&8 != 0,
+					Fill:              fill,
+					OriginalFilename:  "secret.bin",
+					FileType:          "application/octet-stream",
+				}
+				name := fmt.Sprintf("bits=%d/fill=%q/encrypt=%v/position=%v/stealth=%v/compressed=%v",
+					bits, fill, opts.UseEncryption, opts.UseKeyForPosition, opts.Stealth, opts.Compressed)
+				t.Run(name, func(t *testing.T) {
+					stego, err := l.Embed(carrier, message, opts)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if len(stego) != len(carrier) || !bytes.Equal(stego[:l.headerSize], carrier[:l.headerSize]) {
+						t.Fatal("embedding changed the carrier size or its reserved header")
+					}
+
+					result, err := l.Extract(stego, opts.Key)
+					if err != nil {
+						t.Fatal(err)
+					}
+					want := opts.metadata(len(message))
+					if !bytes.Equal(result.Message, message) || *result.Metadata != *want {
+						t.Errorf("extracted %d bytes with %+v, want %d bytes with %+v",
+							len(result.Message), *result.Metadata, len(message), *want)
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestLSBWrongKey(t *testing.T) {
+	carrier := testMP3(300, 3)
+	message := []byte("hello")
+	l := NewLSBSteganography()
+
+	tests := []struct {
+		name  string
+		opts  EmbedOptions
+		wrong error
+		empty error
+	}{
+		{"plain", EmbedOptions{Bits: 2, Key: "k"}, nil, nil},
+		{"encrypted", EmbedOptions{Bits: 2, Key: "k", UseEncryption: true}, ErrWrongKey, ErrWrongKey},
+		{"key position", EmbedOptions{Bits: 3, Key: "k", UseKeyForPosition: true}, ErrWrongKey, ErrWrongKey},
+		{"stealth", EmbedOptions{Bits: 3, Key: "k", Stealth: true}, ErrNoSteganographicData, ErrNoSteganographicData},
+		{"stealth keyed fill", EmbedOptions{Bits: 1, Key: "k", Stealth: true, Fill: FillKeyed}, ErrNoSteganographicData, ErrNoSteganographicData},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stego, err := l.Embed(carrier, message, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for key, want := range map[string]error{"wrong": tt.wrong, "": tt.empty} {
+				result, err := l.Extract(stego, key)
+				if !errors.Is(err, want) {
+					t.Errorf("key %q: err = %v, want %v", key, err, want)
+				}
+				if err == nil && !bytes.Equal(result.Message, message) {
+					t.Errorf("key %q: message = %q, want %q", key, result.Message, message)
+				}
+			}
+		})
+	}
+}
+
+func TestLSBErrors(t *testing.T) {
+	carrier := testMP3(20, 4)
+	l := NewLSBSteganography()
+
+	tests := []struct {
+		name    string
+		carrier []byte
+		message []byte
+		opts    EmbedOptions
+		want    error
+	}{
+		{"zero bits", carrier, []byte("x"), EmbedOptions{Bits: 0}, ErrInvalidBitCount},
+		{"five bits", carrier, []byte("x"), EmbedOptions{Bits: 5}, ErrInvalidBitCount},
+		{"header only", carrier[:l.headerSize], []byte("x"), EmbedOptions{Bits: 1}, ErrInvalidMP3Format},
+		{"too large", carrier, make([]byte, len(carrier)/8), EmbedOptions{Bits: 1}, ErrInsufficientCapacity},
+		{"stealth without key", carrier, []byte("x"), EmbedOptions{Bits: 1, Stealth: true}, ErrKeyRequired},
+		{"keyed fill without key", carrier, []byte("x"), EmbedOptions{Bits: 1, Fill: FillKeyed}, ErrKeyRequired},
+		{"unknown fill", carrier, []byte("x"), EmbedOptions{Bits: 1, Fill: "noise"}, ErrInvalidFillMode},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := l.Embed(tt.carrier, tt.message, tt.opts); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	capacity, err := l.Capacity(carrier, EmbedOptions{Bits: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Embed(carrier, make([]byte, capacity), EmbedOptions{Bits: 2}); err != nil {
+		t.Errorf("embedding exactly the capacity: %v", err)
+	}
+	if _, err := l.Embed(carrier, make([]byte, capacity+1), EmbedOptions{Bits: 2}); !errors.Is(err, ErrInsufficientCapacity) {
+		t.Errorf("embedding one byte over capacity: err = %v, want %v", err, ErrInsufficientCapacity)
+	}
+}
+
+func TestLSBCorruptCarrier(t *testing.T) {
+	carrier := testMP3(60, 5)
+	message := testMessage(2000, 6)
+	l := NewLSBSteganography()
+	stego, err := l.Embed(carrier, message, EmbedOptions{Bits: 2, Key: "k", UseEncryption: true, OriginalFilename: "a.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		carrier []byte
+		want    error
+	}{
+		{"cover", carrier, ErrNoSteganographicData},
+		{"truncated message", stego[:l.headerSize+1000], ErrNoSteganographicData},
+		{"truncated container", stego[:l.headerSize+20], ErrNoSteganographicData},
+		{"header only", stego[:l.headerSize], ErrInvalidMP3Format},
+		{"empty", nil, ErrInvalidMP3Format},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := l.Extract(tt.carrier, "k"); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -13,6 +13,9 @@ type EmbedMetadata struct {
 	UseEncryption     bool `json:"use_encryption"`
 	UseKeyForPosition bool `json:"use_key_for_position"`
 	LSBBits           int  `json:"lsb_bits"`
+	Stealth           bool `json:"stealth"`
+	Partitioned       bool `json:"partitioned"`
+	Compressed        bool `json:"compressed"`
 
 	OriginalFilename  string `json:"original_filename"`
 	FileType          string `json:"file_type"`
@@ -23,38 +26,43 @@ func DetectFileType(data []byte, filename string) string {
 	contentType := http.DetectContentType(data)
 
 	if contentType == "application/octet-stream" || contentType == "text/plain" {
-		ext := strings.ToLower(filepath.Ext(filename))
-		switch ext {
-		case ".pdf":
-			return "application/pdf"
-		case ".txt":
-			return "text/plain"
-		case ".doc":
-			return "application/msword"
-		case ".docx":
-			return "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
-		case ".jpg", ".jpeg":
-			return "image/jpeg"
-		case ".png":
-			return "image/png"
-		case ".gif":
-			return "image/gif"
-		case ".mp3":
-			return "audio/mpeg"
-		case ".wav":
-			return "audio/wav"
-		case ".mp4":
-			return "video/mp4"
-		case ".zip":
-			return "application/zip"
-		default:
-			return contentType
+		if fileType := FileTypeFromName(filename); fileType != "" {
+			return fileType
 		}
 	}
 
 	return contentType
 }
 
+func FileTypeFromName(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".pdf":
+		return "application/pdf"
+	case ".txt":
+		return "text/plain"
+	case ".doc":
+		return "application/msword"
+	case ".docx":
+		return "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	case ".jpg", ".jpeg":
+		return "image/jpeg"
+	case ".png":
+		return "image/png"
+	case ".gif":
+		return "image/gif"
+	case ".mp3":
+		return "audio/mpeg"
+	case ".wav":
+		return "audio/wav"
+	case ".mp4":
+		return "video/mp4"
+	case ".zip":
+		return "application/zip"
+	default:
+		return ""
+	}
+}
+
 func SerializeMetadata(metadata *EmbedMetadata, key string) ([]byte, error) {
 	unencryptedData := struct {
 		UseEncryption     bool `json:"use_encryption"`
@@ -195,6 +203,34 @@ func DeserializeMetadata(data []byte, key string) (*EmbedMetadata, int, error) {
 	return metadata, totalBytesRead, nil
 }
 
+func PeekMetadata(data []byte) (*EmbedMetadata, error) {
+	if len(data) < 4 {
+		return nil, ErrInvalidMetadata
+	}
+
+	unencryptedSize := binary.BigEndian.Uint32(data)
+	if len(data) < int(unencryptedSize)+4 {
+		return nil, ErrInvalidMetadata
+	}
+
+	var unencryptedPart struct {
+		UseEncryption     bool `json:"use_encryption"`
+		UseKeyForPosition bool `json:"use_key_for_position"`
+		LSBBits           int  `json:"lsb_bits"`
+	}
+
+	err := json.Unmarshal(data[4:4+unencryptedSize], &unencryptedPart)
+	if err != nil {
+		return nil, ErrInvalidMetadata
+	}
+
+	return &EmbedMetadata{
+		UseEncryption:     unencryptedPart.UseEncryption,
+		UseKeyForPosition: unencryptedPart.UseKeyForPosition,
+		LSBBits:           unencryptedPart.LSBBits,
+	}, nil
+}
+
 func VigenereEncrypt(data []byte, key string) []byte {
 	if len(key) == 0 {
 		return data
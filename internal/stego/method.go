@@ -0,0 +1,91 @@
+package stego
+
+type Method interface {
+	Name() string
+	Embed(carrier, message []byte, opts EmbedOptions) ([]byte, error)
+	Extract(carrier []byte, key string) (*ExtractResult, error)
+	Capacity(carrier []byte, opts EmbedOptions) (int, error)
+}
+
+var registeredMethods []Method
+
+func init() {
+	RegisterMethod(NewLSBSteganography())
+	RegisterMethod(NewHeaderSteganography())
+	RegisterMethod(NewChunkedSteganography())
+	RegisterMethod(NewSpreadSpectrumSteganography())
+	RegisterMethod(NewEchoSteganography())
+}
+
+func RegisterMethod(method Method) {
+	registeredMethods = append(registeredMethods, method)
+}
+
+func Methods() []Method {
+	methods := make([]Method, len(registeredMethods))
+	copy(methods, registeredMethods)
+	return methods
+}
+
+func LookupMethod(name string) (Method, bool) {
+	for _, method := range registeredMethods {
+		if method.Name() == name {
+			return method, true
+		}
+	}
+	return nil, false
+}
+
+func DetectAndExtract(carrier []byte, key string) (*ExtractResult, error) {
+	var errs []error
+	for _, method := range registeredMethods {
+		result, err := method.Extract(carrier, key)
+		if err == nil {
+			result.Method = method.Name()
+			return result, nil
+		}
+		errs = append(errs, err)
+	}
+
+	return nil, mostRelevantError(errs)
+}
+
+func mostRelevantError(errs []error) error {
+	priority := []error{ErrWrongKey, ErrIncompletePayload, ErrUnsupportedVersion, ErrInvalidMetadata, ErrNoSteganographicData}
+	for _, candidate := range priority {
+		for _, err := range errs {
+			if err == candidate {
+				return candidate
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return ErrNoSteganographicData
+}
+
+func (l *LSBSteganography) Name() string {
+	return "lsb"
+}
+
+func (l *LSBSteganography) Embed(carrier, message []byte, opts EmbedOptions) ([]byte, error) {
+	return l.EmbedMessageWithOptions(carrier, message, opts)
+}
+
+func (l *LSBSteganography) Extract(carrier []byte, key string) (*ExtractResult, error) {
+	return l.ExtractMessageWithMetadata(carrier, key)
+}
+
+func (h *HeaderSteganography) Name() string {
+	return "header"
+}
+
+func (h *HeaderSteganography) Embed(carrier, message []byte, opts EmbedOptions) ([]byte, error) {
+	return h.EmbedMessageWithOptions(carrier, message, opts)
+}
+
+func (h *HeaderSteganography) Extract(carrier []byte, key string) (*ExtractResult, error) {
+	return h.ExtractMessageWithMetadata(carrier, key)
+}
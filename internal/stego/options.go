@@ -0,0 +1,84 @@
+package stego
+
+import (
+	"crypto/rand"
+
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/crypto"
+)
+
+type FillMode string
+
+const (
+	FillNone   FillMode = ""
+	FillRandom FillMode = "random"
+	FillKeyed  FillMode = "keyed"
+)
+
+type EmbedOptions struct {
+	Bits              int
+	Key               string
+	UseKeyForPosition bool
+	UseEncryption     bool
+	OriginalFilename  string
+	FileType          string
+	Stealth           bool
+	Fill              FillMode
+	Compressed        bool
+
+	// ChipRate and Strength configure spread-spectrum embedding; zero
+	// selects DefaultChipRate and DefaultStrength. Echo hiding reads
+	// Strength as the echo amplitude, DefaultEchoAmplitude when zero.
+	ChipRate int
+	Strength float64
+}
+
+func (o EmbedOptions) metadata(messageSize int) *EmbedMetadata {
+	return &EmbedMetadata{
+		UseEncryption:     o.UseEncryption,
+		UseKeyForPosition: o.UseKeyForPosition,
+		LSBBits:           o.Bits,
+		Stealth:           o.Stealth,
+		Compressed:        o.Compressed,
+		OriginalFilename:  o.OriginalFilename,
+		FileType:          o.FileType,
+		SecretMessageSize: messageSize,
+	}
+}
+
+func ParseFillMode(value string) (FillMode, error) {
+	switch FillMode(value) {
+	case FillNone, FillRandom, FillKeyed:
+		return FillMode(value), nil
+	case "none":
+		return FillNone, nil
+	default:
+		return FillNone, ErrInvalidFillMode
+	}
+}
+
+func fillNoise(mode FillMode, key string, size int) ([]byte, error) {
+	switch mode {
+	case FillNone:
+		return nil, nil
+	case FillRandom:
+		noise := make([]byte, size)
+		if _, err := rand.Read(noise); err != nil {
+			return nil, err
+		}
+		return noise, nil
+	case FillKeyed:
+		if key == "" {
+			return nil, ErrKeyRequired
+		}
+		return crypto.KeystreamXOR(make([]byte, size), key, "stego-fill", nil), nil
+	default:
+		return nil, ErrInvalidFillMode
+	}
+}
+
+func applyFill(carrier []byte, bits int, noise []byte) {
+	mask := byte((1 << bits) - 1)
+	for i := range carrier {
+		carrier[i] = (carrier[i] &^ mask) | (noise[i] & mask)
+	}
+}
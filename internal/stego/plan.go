@@ -0,0 +1,232 @@
+package stego
+
+import "sort"
+
+type ChangeEstimate struct {
+	TotalUnits       int
+	TouchedUnits     int
+	ExpectedModified float64
+}
+
+// Estimator is implemented by methods that can predict how many carrier units
+// an embed will modify. Estimates assume the embedded bits are uniformly
+// random, which holds for stealth, encrypted and compressed payloads.
+type Estimator interface {
+	EstimateChanges(carrier []byte, payloadSize int, opts EmbedOptions) (*ChangeEstimate, error)
+}
+
+// BitDepther is implemented by methods with a selectable bit depth.
+type BitDepther interface {
+	BitDepths() []int
+}
+
+// PlanEntry describes one candidate configuration. ModifiedFraction is nil
+// for methods without an Estimator, whose changes the planner cannot predict.
+type PlanEntry struct {
+	Method           string   `json:"method"`
+	LSBBits          int      `json:"lsb_bits,omitempty"`
+	Compressed       bool     `json:"compressed"`
+	SecretSize       int      `json:"secret_size"`
+	PayloadBytes     int      `json:"payload_bytes"`
+	CapacityBytes    int      `json:"capacity_bytes"`
+	Fits             bool     `json:"fits"`
+	ModifiedFraction *float64 `json:"modified_fraction,omitempty"`
+	Recommended      bool     `json:"recommended"`
+	Error            string   `json:"error,omitempty"`
+}
+
+// PlanEmbedding tries every registered method at each of its bit depths with
+// and without compression, or only as compressions lists. opts holds the
+// options for each method by name; Bits and Compressed are set per entry.
+func PlanEmbedding(carrier, secret []byte, opts map[string]EmbedOptions, compressions []bool) ([]PlanEntry, error) {
+	compressed, err := CompressMessage(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []PlanEntry
+	for _, method := range registeredMethods {
+		for _, bits := range BitDepths(method) {
+			for _, compress := range compressions {
+				entryOpts := opts[method.Name()]
+				entryOpts.Bits = bits
+				entryOpts.Compressed = compress
+
+				secretSize := len(secret)
+				if compress {
+					secretSize = len(compressed)
+				}
+
+				entry := PlanEntry{
+					Method:     method.Name(),
+					LSBBits:    bits,
+					Compressed: compress,
+					SecretSize: secretSize,
+				}
+
+				overhead, err := MethodOverhead(method, secretSize, entryOpts)
+				if err != nil {
+					entry.Error = err.Error()
+					entries = append(entries, entry)
+					continue
+				}
+				entry.PayloadBytes = overhead + secretSize
+
+				capacity, err := method.Capacity(carrier, entryOpts)
+				if err != nil {
+					entry.Error = err.Error()
+					entries = append(entries, entry)
+					continue
+				}
+				entry.CapacityBytes = capacity
+				entry.Fits = secretSize <= capacity
+
+				if estimator, ok := method.(Estimator); ok {
+					estimate, err := estimator.EstimateChanges(carrier, entry.PayloadBytes, entryOpts)
+					if err != nil {
+						entry.Error = err.Error()
+					} else {
+						fraction := estimate.ExpectedModified / float64(estimate.TotalUnits)
+						entry.ModifiedFraction = &fraction
+					}
+				}
+
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	if best := recommendedEntry(entries); best >= 0 {
+		entries[best].Recommended = true
+	}
+
+	return entries, nil
+}
+
+// BitDepths lists the EmbedOptions.Bits values worth trying with method.
+// Methods that do not implement BitDepther report a single zero entry.
+func BitDepths(method Method) []int {
+	if depther, ok := method.(BitDepther); ok {
+		return depther.BitDepths()
+	}
+	return []int{0}
+}
+
+func (l *LSBSteganography) BitDepths() []int {
+	return []int{1, 2, 3, 4}
+}
+
+func (c *ChunkedSteganography) BitDepths() []int {
+	return []int{1, 2, 3, 4}
+}
+
+// recommendedEntry picks the fitting entry with the smallest expected
+// fraction of modified units. Entries without an estimate are only
+// recommended when no estimated entry fits, since their changes are unknown
+// rather than zero.
+func recommendedEntry(entries []PlanEntry) int {
+	candidates := make([]int, 0, len(entries))
+	for i, entry := range entries {
+		if entry.Fits && entry.Error == "" {
+			candidates = append(candidates, i)
+		}
+	}
+	if len(candidates) == 0 {
+		return -1
+	}
+
+	sort.SliceStable(candidates, func(a, b int) bool {
+		ea, eb := entries[candidates[a]], entries[candidates[b]]
+		if (ea.ModifiedFraction == nil) != (eb.ModifiedFraction == nil) {
+			return ea.ModifiedFraction != nil
+		}
+		if ea.ModifiedFraction == nil {
+			return false
+		}
+		return *ea.ModifiedFraction < *eb.ModifiedFraction
+	})
+
+	return candidates[0]
+}
+
+func (l *LSBSteganography) EstimateChanges(carrier []byte, payloadSize int, opts EmbedOptions) (*ChangeEstimate, error) {
+	if opts.Bits < 1 || opts.Bits > 4 {
+		return nil, ErrInvalidBitCount
+	}
+	if len(carrier) <= l.headerSize {
+		return nil, ErrInvalidMP3Format
+	}
+
+	units := len(carrier) - l.headerSize
+	touched := (payloadSize*8 + opts.Bits - 1) / opts.Bits
+	if opts.Fill != FillNone || touched > units {
+		touched = units
+	}
+
+	levels := float64(int(1) << opts.Bits)
+	return &ChangeEstimate{
+		TotalUnits:       units,
+		TouchedUnits:     touched,
+		ExpectedModified: float64(touched) * (1 - 1/levels),
+	}, nil
+}
+
+func (h *HeaderSteganography) EstimateChanges(carrier []byte, payloadSize int, opts EmbedOptions) (*ChangeEstimate, error) {
+	frames, _, err := h.locateFrames(carrier)
+	if err != nil {
+		return nil, err
+	}
+
+	bitsPerFrame := len(headerBitPositions)
+	touched := (payloadSize*8 + bitsPerFrame - 1) / bitsPerFrame
+	if opts.Fill != FillNone || touched > len(frames) {
+		touched = len(frames)
+	}
+
+	return &ChangeEstimate{
+		TotalUnits:       len(frames),
+		TouchedUnits:     touched,
+		ExpectedModified: float64(touched) * (1 - 1/float64(int(1)<<bitsPerFrame)),
+	}, nil
+}
+
+func (l *LSBSteganography) SelectBits(carrier []byte, messageSize int, opts EmbedOptions) (int, error) {
+	return SelectBits(l, carrier, messageSize, opts)
+}
+
+// SelectBits returns the smallest bit depth of method that still fits a
+// message of messageSize bytes.
+func SelectBits(method Method, carrier []byte, messageSize int, opts EmbedOptions) (int, error) {
+	for _, bits := range BitDepths(method) {
+		opts.Bits = bits
+		capacity, err := method.Capacity(carrier, opts)
+		if err != nil {
+			return 0, err
+		}
+		if messageSize <= capacity {
+			return bits, nil
+		}
+	}
+
+	return 0, ErrInsufficientCapacity
+}
+
+func (l *LSBSteganography) SelectDualBits(carrier []byte, decoy, secret DualPayload) (int, error) {
+	for bits := 1; bits <= 4; bits++ {
+		fits := true
+		for _, payload := range []DualPayload{decoy, secret} {
+			capacity, err := l.DualCapacity(carrier, bits, payload.Key, payload.OriginalFilename, payload.FileType)
+			if err != nil {
+				return 0, err
+			}
+			if len(payload.Message) > capacity {
+				fits = false
+			}
+		}
+		if fits {
+			return bits, nil
+		}
+	}
+
+	return 0, ErrInsufficientCapacity
+}
@@ -0,0 +1,306 @@
+package stego
+
+import "encoding/binary"
+
+const (
+	FormatContainer    = "container-v3"
+	FormatLegacyJSON   = "legacy-json"
+	FormatLegacyHeader = "legacy-header"
+)
+
+type ProbeResult struct {
+	Detected          bool   `json:"detected"`
+	Method            string `json:"method,omitempty"`
+	Format            string `json:"format,omitempty"`
+	LSBBits           int    `json:"lsb_bits"`
+	UseEncryption     bool   `json:"use_encryption"`
+	UseKeyForPosition bool   `json:"use_key_for_position"`
+	Stealth           bool   `json:"stealth"`
+	Compressed        bool   `json:"compressed"`
+	PayloadSize       int    `json:"payload_size"`
+	KeyRequired       bool   `json:"key_required"`
+	Status            string `json:"status"`
+	Basis             string `json:"basis,omitempty"`
+	Note              string `json:"note,omitempty"`
+}
+
+// Probe statuses.
+const (
+	ProbeFound    = "found"     // the payload was read with the key given
+	ProbeWrongKey = "wrong_key" // a container was found but its key check failed
+	ProbePartial  = "partial"   // only some chunks of the payload were recovered
+	ProbeNotFound = "not_found"
+)
+
+// Detection bases: the evidence a positive probe rests on.
+const (
+	BasisContainer      = "container"        // container magic, version and fields parsed
+	BasisSealed         = "sealed_container" // trial decryption with the key yielded a container
+	BasisChunkCRC       = "chunk_crc"        // key-derived chunk markers whose CRC-32 matched
+	BasisLegacyMetadata = "legacy_metadata"  // length-prefixed JSON metadata parsed
+	BasisLegacyLength   = "legacy_length"    // a legacy length and filename that fit the carrier
+)
+
+const (
+	// noteWrongKey accompanies a payload whose container was found but whose
+	// key check failed, so only the unencrypted header fields are reported.
+	noteWrongKey = "the key is missing or incorrect; filename and file type need the key used during embedding"
+
+	// notePartial accompanies a chunked payload of which some chunks are
+	// missing.
+	notePartial = "only part of the payload survived; see /api/extract for the recovered chunks"
+
+	// noteKeyedPossible explains a negative result without a key: payloads
+	// that are encrypted as a whole or located by the key look like noise.
+	noteKeyedPossible = "no payload is readable without a key; stealth, dual-payload, chunked and dsss payloads can only be detected with the key used during embedding"
+)
+
+type Prober interface {
+	Probe(carrier []byte, key string) *ProbeResult
+}
+
+func formatBasis(format string, sealed bool) string {
+	switch {
+	case format == FormatContainer && sealed:
+		return BasisSealed
+	case format == FormatContainer:
+		return BasisContainer
+	case format == FormatLegacyJSON:
+		return BasisLegacyMetadata
+	case format == FormatLegacyHeader:
+		return BasisLegacyLength
+	default:
+		return ""
+	}
+}
+
+func probeFromResult(method string, result *ExtractResult) *ProbeResult {
+	return probeFromMetadata(method, result.Format, result.Metadata, len(result.Message))
+}
+
+func probeFromMetadata(method, format string, metadata *EmbedMetadata, payloadSize int) *ProbeResult {
+	return &ProbeResult{
+		Detected:          true,
+		Method:            method,
+		Format:            format,
+		LSBBits:           metadata.LSBBits,
+		UseEncryption:     metadata.UseEncryption,
+		UseKeyForPosition: metadata.UseKeyForPosition,
+		Stealth:           metadata.Stealth,
+		Compressed:        metadata.Compressed,
+		PayloadSize:       payloadSize,
+		KeyRequired:       metadata.UseEncryption || metadata.UseKeyForPosition || metadata.Stealth || metadata.Partitioned,
+		Status:            ProbeFound,
+		Basis:             formatBasis(format, metadata.Stealth),
+	}
+}
+
+// probeLocked reports a container found by peekPayload, whose key check did
+// not match the key given.
+func probeLocked(method, format string, metadata *EmbedMetadata) *ProbeResult {
+	result := probeFromMetadata(method, format, metadata, metadata.SecretMessageSize)
+	result.KeyRequired = true
+	result.Status = ProbeWrongKey
+	result.Note = noteWrongKey
+	return result
+}
+
+func notDetected() *ProbeResult {
+	return &ProbeResult{PayloadSize: -1, Status: ProbeNotFound}
+}
+
+// ProbeCarrier asks every registered Prober in turn. When none detects a
+// payload and no key was given, the result says so in KeyRequired and Note,
+// since a keyed payload cannot be ruled out.
+func ProbeCarrier(carrier []byte, key string) *ProbeResult {
+	for _, method := range registeredMethods {
+		prober, ok := method.(Prober)
+		if !ok {
+			continue
+		}
+
+		if result := prober.Probe(carrier, key); result.Detected {
+			return result
+		}
+	}
+
+	result := notDetected()
+	if key == "" {
+		result.KeyRequired = true
+		result.Note = noteKeyedPossible
+	}
+	return result
+}
+
+func (l *LSBSteganography) Probe(mp3Data []byte, key string) *ProbeResult {
+	result, err := l.ExtractMessageWithMetadata(mp3Data, key)
+	if err == nil {
+		return probeFromResult(l.Name(), result)
+	}
+	if err != ErrWrongKey {
+		return notDetected()
+	}
+
+	stegoData := mp3Data[l.headerSize:]
+	if result := l.probeContainer(stegoData, key); result != nil {
+		return result
+	}
+
+	for bits := 1; bits <= 4; bits++ {
+		for _, useKeyForPos := range []bool{false, true} {
+			var offset int
+			if useKeyForPos {
+				offset = l.calculateKeyOffset(key)
+			}
+			startOffset := offset % len(stegoData)
+
+			lengthBytes, ok := l.readPayload(stegoData, startOffset, bits, 0, 4)
+			if !ok {
+				continue
+			}
+			metadataLength := binary.BigEndian.Uint32(lengthBytes)
+			if metadataLength == 0 || metadataLength > 10000 {
+				continue
+			}
+
+			metadataBytes, ok := l.readPayload(stegoData, startOffset, bits, 32, int(metadataLength))
+			if !ok {
+				continue
+			}
+			metadata, err := PeekMetadata(metadataBytes)
+			if err != nil || metadata.LSBBits != bits || metadata.UseKeyForPosition != useKeyForPos {
+				continue
+			}
+
+			payloadSize := -1
+			sizeBytes, ok := l.readPayload(stegoData, startOffset, bits, 32+int(metadataLength)*8, 4)
+			if ok {
+				payloadSize = int(binary.BigEndian.Uint32(sizeBytes))
+			}
+
+			return &ProbeResult{
+				Detected:          true,
+				Method:            l.Name(),
+				Format:            FormatLegacyJSON,
+				LSBBits:           bits,
+				UseEncryption:     metadata.UseEncryption,
+				UseKeyForPosition: metadata.UseKeyForPosition,
+				PayloadSize:       payloadSize,
+				KeyRequired:       true,
+				Status:            ProbeWrongKey,
+				Basis:             BasisLegacyMetadata,
+				Note:              noteWrongKey,
+			}
+		}
+	}
+
+	return notDetected()
+}
+
+// probeContainer looks for a container the key does not open at every
+// position the extractor would use, including key-derived offsets.
+func (l *LSBSteganography) probeContainer(stegoData []byte, key string) *ProbeResult {
+	type start struct{ offset, bits int }
+	var starts []start
+	for bits := 1; bits <= 4; bits++ {
+		starts = append(starts, start{0, bits}, start{keyOffset(key) % len(stegoData), bits})
+	}
+	if offset, bits, ok := l.findKeyPositioned(stegoData); ok {
+		starts = append(starts, start{offset, bits})
+	}
+
+	for _, s := range starts {
+		metadata, format, ok := peekPayload(func(length int) ([]byte, bool) {
+			return l.readPayload(stegoData, s.offset, s.bits, 0, length)
+		})
+		if ok && metadata.LSBBits == s.bits {
+			return probeLocked(l.Name(), format, metadata)
+		}
+	}
+	return nil
+}
+
+func (h *HeaderSteganography) Probe(mp3Data []byte, key string) *ProbeResult {
+	result, err := h.ExtractMessageWithMetadata(mp3Data, key)
+	if err == nil {
+		return probeFromResult(h.Name(), result)
+	}
+	if err != ErrWrongKey {
+		return notDetected()
+	}
+
+	_, offsets, err := h.locateFrames(mp3Data)
+	if err != nil {
+		return notDetected()
+	}
+	starts := []int{0, keyOffset(key) % len(offsets)}
+	if start, ok := h.findKeyPositioned(mp3Data, offsets); ok {
+		starts = append(starts, start)
+	}
+
+	for _, start := range starts {
+		stream := h.readHeaderBits(mp3Data, offsets, start)
+		metadata, format, ok := peekPayload(func(length int) ([]byte, bool) {
+			if length > len(stream) {
+				return nil, false
+			}
+			return stream[:length], true
+		})
+		if ok {
+			return probeLocked(h.Name(), format, metadata)
+		}
+	}
+	return notDetected()
+}
+
+// Probe reports the payload chunked extraction finds. The chunk markers are
+// derived from the key, so without it nothing is detected.
+func (c *ChunkedSteganography) Probe(carrier []byte, key string) *ProbeResult {
+	recovery, err := c.Recover(carrier, key)
+	if err != nil {
+		return notDetected()
+	}
+
+	metadata := recovery.Metadata
+	if metadata == nil {
+		metadata = &EmbedMetadata{LSBBits: recovery.LSBBits, SecretMessageSize: -1}
+	}
+	result := probeFromMetadata(c.Name(), FormatContainer, metadata, metadata.SecretMessageSize)
+	result.KeyRequired = true
+	result.Basis = BasisChunkCRC
+	if !recovery.Complete {
+		result.Status = ProbePartial
+		result.Note = notePartial
+	}
+	return result
+}
+
+// Probe reports the payload spread-spectrum extraction finds. The chip
+// sequence is derived from the key, so without it nothing is detected.
+func (s *SpreadSpectrumSteganography) Probe(carrier []byte, key string) *ProbeResult {
+	result, err := s.Extract(carrier, key)
+	if err != nil {
+		return notDetected()
+	}
+	return probeFromResult(s.Name(), result)
+}
+
+func (e *EchoSteganography) Probe(carrier []byte, key string) *ProbeResult {
+	result, err := e.Extract(carrier, key)
+	if err == nil {
+		return probeFromResult(e.Name(), result)
+	}
+	if err != ErrWrongKey {
+		return notDetected()
+	}
+
+	wav, err := parsePCMCarrier(carrier)
+	if err != nil {
+		return notDetected()
+	}
+	metadata, format, ok := peekPayload(newEchoDecoder(wav).read)
+	if !ok {
+		return notDetected()
+	}
+	return probeLocked(e.Name(), format, metadata)
+}
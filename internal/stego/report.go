@@ -0,0 +1,141 @@
+package stego
+
+import "math/bits"
+
+const defaultReportRegions = 16
+
+type EmbedReport struct {
+	Method              string           `json:"method"`
+	LSBBits             int              `json:"lsb_bits,omitempty"`
+	PayloadBytes        int              `json:"payload_bytes"`
+	TotalUnits          int              `json:"total_units"`
+	UnitsTouched        int              `json:"units_touched"`
+	UnitsChanged        int              `json:"units_changed"`
+	BytesChanged        int              `json:"bytes_changed"`
+	BitsFlipped         int              `json:"bits_flipped"`
+	Regions             []RegionChanges  `json:"regions"`
+	FrameBitsFlipped    []int            `json:"frame_bits_flipped"`
+	UnframedBitsFlipped int              `json:"unframed_bits_flipped"`
+	DistributionBefore  *LSBDistribution `json:"distribution_before"`
+	DistributionAfter   *LSBDistribution `json:"distribution_after"`
+}
+
+type RegionChanges struct {
+	Start        int `json:"start"`
+	End          int `json:"end"`
+	BytesChanged int `json:"bytes_changed"`
+	BitsFlipped  int `json:"bits_flipped"`
+}
+
+// ChangedRegions diffs stego against cover and returns the spans of changed
+// bytes, merging changes less than gap bytes apart.
+func ChangedRegions(cover, stego []byte, gap int) ([]RegionChanges, error) {
+	if len(cover) != len(stego) {
+		return nil, ErrInvalidMP3Format
+	}
+
+	var regions []RegionChanges
+	for i := range cover {
+		flipped := bits.OnesCount8(cover[i] ^ stego[i])
+		if flipped == 0 {
+			continue
+		}
+		if n := len(regions); n == 0 || i-regions[n-1].End >= gap {
+			regions = append(regions, RegionChanges{Start: i})
+		}
+		region := &regions[len(regions)-1]
+		region.End = i + 1
+		region.BytesChanged++
+		region.BitsFlipped += flipped
+	}
+	return regions, nil
+}
+
+func BuildEmbedReport(method Method, cover, stego []byte, messageSize int, opts EmbedOptions) (*EmbedReport, error) {
+	if len(cover) != len(stego) {
+		return nil, ErrInvalidMP3Format
+	}
+
+	overhead, err := PayloadOverhead(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &EmbedReport{
+		Method:       method.Name(),
+		LSBBits:      opts.Bits,
+		PayloadBytes: overhead + messageSize,
+	}
+
+	if estimator, ok := method.(Estimator); ok {
+		estimate, err := estimator.EstimateChanges(cover, report.PayloadBytes, opts)
+		if err != nil {
+			return nil, err
+		}
+		report.TotalUnits = estimate.TotalUnits
+		report.UnitsTouched = estimate.TouchedUnits
+	}
+
+	regionSize := (len(cover) + defaultReportRegions - 1) / defaultReportRegions
+	if regionSize == 0 {
+		regionSize = 1
+	}
+	for start := 0; start < len(cover); start += regionSize {
+		end := start + regionSize
+		if end > len(cover) {
+			end = len(cover)
+		}
+		report.Regions = append(report.Regions, RegionChanges{Start: start, End: end})
+	}
+
+	for i := range cover {
+		flipped := bits.OnesCount8(cover[i] ^ stego[i])
+		if flipped == 0 {
+			continue
+		}
+		report.BytesChanged++
+		report.BitsFlipped += flipped
+
+		region := &report.Regions[i/regionSize]
+		region.BytesChanged++
+		region.BitsFlipped += flipped
+	}
+
+	frames, offsets, err := ScanMP3Frames(cover)
+	if err == nil {
+		report.FrameBitsFlipped = make([]int, len(frames))
+		framed := 0
+		for i, offset := range offsets {
+			for j := offset; j < offset+frames[i].Size; j++ {
+				report.FrameBitsFlipped[i] += bits.OnesCount8(cover[j] ^ stego[j])
+			}
+			framed += report.FrameBitsFlipped[i]
+		}
+		report.UnframedBitsFlipped = report.BitsFlipped - framed
+	} else {
+		report.UnframedBitsFlipped = report.BitsFlipped
+	}
+
+	switch m := method.(type) {
+	case *LSBSteganography:
+		report.UnitsChanged = report.BytesChanged
+		report.DistributionBefore = m.MeasureDistribution(cover, opts.Bits)
+		report.DistributionAfter = m.MeasureDistribution(stego, opts.Bits)
+	case *ChunkedSteganography:
+		report.UnitsChanged = report.BytesChanged
+		report.DistributionBefore = m.MeasureDistribution(cover, opts.Bits)
+		report.DistributionAfter = m.MeasureDistribution(stego, opts.Bits)
+	case *HeaderSteganography:
+		before := m.headerBitValues(cover)
+		after := m.headerBitValues(stego)
+		for i := range before {
+			if i < len(after) && before[i] != after[i] {
+				report.UnitsChanged++
+			}
+		}
+		report.DistributionBefore = MeasureLSBDistribution(before, len(headerBitPositions))
+		report.DistributionAfter = MeasureLSBDistribution(after, len(headerBitPositions))
+	}
+
+	return report, nil
+}
@@ -13,6 +13,17 @@ var (
 	ErrInvalidMetadata      = errors.New("invalid metadata format")
 	ErrWrongKey             = errors.New("incorrect key provided - unable to decrypt encrypted metadata")
 	ErrNoSteganographicData = errors.New("no steganographic data found in this MP3 file")
+	ErrUnsupportedVersion   = errors.New("unsupported steganographic container version")
+	ErrKeyRequired          = errors.New("a key is required for this embedding mode")
+	ErrIdenticalKeys        = errors.New("decoy and secret payloads must use different keys")
+	ErrInvalidFillMode      = errors.New("fill mode must be none, random or keyed")
+	ErrDecompressionFailed  = errors.New("failed to decompress extracted message")
+	ErrIncompletePayload    = errors.New("only part of the embedded payload could be recovered")
+	ErrWAVRequired          = errors.New("this method requires an integer PCM WAV carrier")
+	ErrInvalidChipRate      = errors.New("chip rate must be a power of two between 64 and 8192")
+	ErrInvalidStrength      = errors.New("strength must be greater than 0 and at most 0.1")
+	ErrInvalidEchoAmplitude = errors.New("echo amplitude must be greater than 0 and at most 0.9")
+	ErrCarrierTooLoud       = errors.New("carrier is too loud for the spread-spectrum strength; raise strength or chip_rate")
 )
 
 type HeaderRequest struct {
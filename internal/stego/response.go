@@ -39,5 +39,10 @@ func CalculateCapacity(mp3FileSize, bits int) int {
 }
 
 func PaperCalculateCapacity(mp3FileSize, bits int) int {
-	return CalculateCapacity(mp3FileSize, bits) - 100
+	overhead, _ := PayloadOverhead(EmbedOptions{Bits: bits})
+	capacity := CalculateCapacity(mp3FileSize, bits) - overhead
+	if capacity < 0 {
+		return 0
+	}
+	return capacity
 }
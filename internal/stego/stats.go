@@ -0,0 +1,84 @@
+package stego
+
+import "math"
+
+type LSBDistribution struct {
+	Bits      int     `json:"bits"`
+	Units     int     `json:"units"`
+	Histogram []int   `json:"histogram"`
+	OnesRatio float64 `json:"ones_ratio"`
+	Entropy   float64 `json:"entropy"`
+}
+
+func MeasureLSBDistribution(data []byte, bits int) *LSBDistribution {
+	mask := byte((1 << bits) - 1)
+	dist := &LSBDistribution{
+		Bits:      bits,
+		Units:     len(data),
+		Histogram: make([]int, 1<<bits),
+	}
+
+	ones := 0
+	for _, b := range data {
+		value := b & mask
+		dist.Histogram[value]++
+		for v := value; v != 0; v &= v - 1 {
+			ones++
+		}
+	}
+
+	if len(data) == 0 {
+		return dist
+	}
+
+	dist.OnesRatio = float64(ones) / float64(len(data)*bits)
+	for _, count := range dist.Histogram {
+		if count == 0 {
+			continue
+		}
+		p := float64(count) / float64(len(data))
+		dist.Entropy -= p * math.Log2(p)
+	}
+	dist.Entropy /= float64(bits)
+
+	return dist
+}
+
+func (l *LSBSteganography) MeasureDistribution(mp3Data []byte, bits int) *LSBDistribution {
+	if len(mp3Data) <= l.headerSize {
+		return MeasureLSBDistribution(nil, bits)
+	}
+	return MeasureLSBDistribution(mp3Data[l.headerSize:], bits)
+}
+
+func (c *ChunkedSteganography) MeasureDistribution(mp3Data []byte, bits int) *LSBDistribution {
+	spans, units, err := mainDataSpans(mp3Data)
+	if err != nil {
+		return MeasureLSBDistribution(nil, bits)
+	}
+	values := make([]byte, 0, units)
+	for _, span := range spans {
+		values = append(values, mp3Data[span.Start:span.End]...)
+	}
+	return MeasureLSBDistribution(values, bits)
+}
+
+func (h *HeaderSteganography) MeasureDistribution(mp3Data []byte) *LSBDistribution {
+	return MeasureLSBDistribution(h.headerBitValues(mp3Data), len(headerBitPositions))
+}
+
+func (h *HeaderSteganography) headerBitValues(mp3Data []byte) []byte {
+	_, offsets, err := h.locateFrames(mp3Data)
+	if err != nil {
+		return nil
+	}
+
+	values := make([]byte, len(offsets))
+	for i, offset := range offsets {
+		for _, pos := range headerBitPositions {
+			bit := (mp3Data[offset+pos.offset] & pos.mask) >> pos.shift
+			values[i] = (values[i] << 1) | bit
+		}
+	}
+	return values
+}
@@ -0,0 +1,116 @@
+package stego
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// testMP3 returns an ID3v2 tag followed by frames MPEG-1 Layer III frames at
+// 128 kbps and 44.1 kHz with random bodies, which is all the MP3 methods look
+// at.
+func testMP3(frames int, seed int64) []byte {
+	rng := rand.New(rand.NewSource(seed))
+	data := []byte("ID3\x03\x00\x00\x00\x00\x00\x10")
+	data = append(data, make([]byte, 16)...)
+	for i := 0; i < frames; i++ {
+		padding := byte(0)
+		if i%3 == 0 {
+			padding = 1
+		}
+		frame := make([]byte, 144*128000/44100+int(padding))
+		copy(frame, []byte{0xFF, 0xFB, 0x90 | padding<<1, 0x64})
+		rng.Read(frame[4:])
+		data = append(data, frame...)
+	}
+	return data
+}
+
+// protectEveryOtherFrame clears the protection bit of every other frame, so
+// those frames claim a CRC.
+func protectEveryOtherFrame(t *testing.T, carrier []byte) []byte {
+	t.Helper()
+	_, offsets, err := ScanMP3Frames(carrier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, offset := range offsets {
+		if i%2 == 0 {
+			carrier[offset+1] &^= 1
+		}
+	}
+	return carrier
+}
+
+// checkMainDataOnly fails t when marked differs from cover anywhere but the
+// main data of frames without a CRC.
+func checkMainDataOnly(t *testing.T, cover, marked []byte) {
+	t.Helper()
+	frames, offsets, err := ScanMP3Frames(cover)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(cover[:offsets[0]], marked[:offsets[0]]) {
+		t.Errorf("the bytes before the first frame changed")
+	}
+	for i, frame := range frames {
+		end := offsets[i] + frame.Size
+		if frame.Protection == 1 {
+			end = offsets[i] + mp3HeaderSize + frame.SideInfoSize()
+		}
+		if !bytes.Equal(cover[offsets[i]:end], marked[offsets[i]:end]) {
+			t.Errorf("frame %d (protection %d) changed before byte %d", i, frame.Protection, end-offsets[i])
+		}
+	}
+}
+
+// testWAV returns a PCM WAV file of seconds of a few harmonics with a slow
+// tremolo and a little noise, loud enough that the carriers behave like
+// music rather than silence.
+func testWAV(seconds float64, sampleRate, channels, bitsPerSample int, seed int64) []byte {
+	rng := rand.New(rand.NewSource(seed))
+	frames := int(seconds * float64(sampleRate))
+	bytesPerSample := bitsPerSample / 8
+	peak := float64(int(1)<<(bitsPerSample-1) - 1)
+
+	data := make([]byte, 44+frames*channels*bytesPerSample)
+	copy(data, "RIFF")
+	binary.LittleEndian.PutUint32(data[4:], uint32(len(data)-8))
+	copy(data[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(data[16:], 16)
+	binary.LittleEndian.PutUint16(data[20:], 1)
+	binary.LittleEndian.PutUint16(data[22:], uint16(channels))
+	binary.LittleEndian.PutUint32(data[24:], uint32(sampleRate))
+	binary.LittleEndian.PutUint32(data[28:], uint32(sampleRate*channels*bytesPerSample))
+	binary.LittleEndian.PutUint16(data[32:], uint16(channels*bytesPerSample))
+	binary.LittleEndian.PutUint16(data[34:], uint16(bitsPerSample))
+	copy(data[36:], "data")
+	binary.LittleEndian.PutUint32(data[40:], uint32(frames*channels*bytesPerSample))
+
+	pos := 44
+	for i := 0; i < frames; i++ {
+		t := float64(i) / float64(sampleRate)
+		envelope := 0.6 + 0.3*math.Sin(2*math.Pi*0.7*t)
+		for ch := 0; ch < channels; ch++ {
+			v := envelope * (0.3*math.Sin(2*math.Pi*220*t+float64(ch)) +
+				0.15*math.Sin(2*math.Pi*660*t) + 0.05*math.Sin(2*math.Pi*1870*t))
+			sample := int64(math.Round((v + 0.05*rng.NormFloat64()) * peak))
+			if bitsPerSample == 8 {
+				sample += 128
+			}
+			for b := 0; b < bytesPerSample; b++ {
+				data[pos] = byte(sample >> (8 * b))
+				pos++
+			}
+		}
+	}
+	return data
+}
+
+func testMessage(size int, seed int64) []byte {
+	message := make([]byte, size)
+	rand.New(rand.NewSource(seed)).Read(message)
+	return message
+}
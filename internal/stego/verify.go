@@ -0,0 +1,73 @@
+package stego
+
+// FrameComparison checks that embedding left the MP3 frame structure alone:
+// the same frames at the same offsets with the same header fields. The
+// private, copyright and original bits are not compared because the header
+// method stores its payload in them. Carriers without MP3 frames, such as WAV
+// files, are reported with Applicable false.
+type FrameComparison struct {
+	Applicable    bool   `json:"applicable"`
+	CoverFrames   int    `json:"cover_frames"`
+	StegoFrames   int    `json:"stego_frames"`
+	Match         bool   `json:"match"`
+	FirstMismatch int    `json:"first_mismatch"`
+	MismatchField string `json:"mismatch_field,omitempty"`
+}
+
+var comparedHeaderFields = []struct {
+	name  string
+	value func(*MP3FrameHeader) int
+}{
+	{"version", func(f *MP3FrameHeader) int { return int(f.Version) }},
+	{"layer", func(f *MP3FrameHeader) int { return int(f.Layer) }},
+	{"protection", func(f *MP3FrameHeader) int { return int(f.Protection) }},
+	{"bitrate", func(f *MP3FrameHeader) int { return int(f.Bitrate) }},
+	{"sample_rate", func(f *MP3FrameHeader) int { return int(f.SampleRate) }},
+	{"padding", func(f *MP3FrameHeader) int { return int(f.Padding) }},
+	{"channel_mode", func(f *MP3FrameHeader) int { return int(f.Channel) }},
+	{"mode_extension", func(f *MP3FrameHeader) int { return int(f.ModeExt) }},
+	{"emphasis", func(f *MP3FrameHeader) int { return int(f.Emphasis) }},
+	{"size", func(f *MP3FrameHeader) int { return f.Size }},
+}
+
+func CompareFrames(cover, stego []byte) *FrameComparison {
+	coverFrames, coverOffsets, _ := ScanMP3Frames(cover)
+	stegoFrames, stegoOffsets, _ := ScanMP3Frames(stego)
+
+	comparison := &FrameComparison{
+		CoverFrames:   len(coverOffsets),
+		StegoFrames:   len(stegoOffsets),
+		FirstMismatch: -1,
+	}
+	if len(coverOffsets) == 0 {
+		return comparison
+	}
+	comparison.Applicable = true
+	comparison.Match = true
+
+	for i := 0; i < len(coverOffsets) || i < len(stegoOffsets); i++ {
+		field := ""
+		switch {
+		case i >= len(coverOffsets) || i >= len(stegoOffsets):
+			field = "count"
+		case coverOffsets[i] != stegoOffsets[i]:
+			field = "offset"
+		default:
+			for _, compared := range comparedHeaderFields {
+				if compared.value(coverFrames[i]) != compared.value(stegoFrames[i]) {
+					field = compared.name
+					break
+				}
+			}
+		}
+
+		if field != "" {
+			comparison.Match = false
+			comparison.FirstMismatch = i
+			comparison.MismatchField = field
+			break
+		}
+	}
+
+	return comparison
+}
@@ -0,0 +1,187 @@
+package stego
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/crypto"
+)
+
+// Watermark layout. The codeword, a 64-bit identifier and a 32-bit HMAC tag,
+// is repeated through the LSBs of each frame's main data (see mainDataSpans),
+// XORed with a keystream seeded by a per-frame nonce taken from bits the
+// watermark leaves alone. Every surviving frame holds complete copies, so
+// detection is a majority vote per bit and the tag rejects wrong keys.
+const (
+	WatermarkBits = 64
+
+	watermarkTagBits  = 32
+	watermarkCodeBits = WatermarkBits + watermarkTagBits
+
+	// WatermarkThreshold is the vote confidence a detection needs in
+	// addition to a valid tag. It is low enough that a watermarked track
+	// joined with unmarked audio of the same length is still reported.
+	WatermarkThreshold = 0.25
+
+	// minWatermarkVotes is the number of copies every bit needs before a
+	// detection is trusted, so a handful of bytes cannot look unanimous.
+	minWatermarkVotes = 5
+
+	// watermarkNonceUnits is kept small so that scattered bit errors rarely
+	// change a frame's nonce and cost its votes.
+	watermarkNonceUnits    = 4
+	watermarkNonceSideInfo = 4
+	watermarkNonceSize     = 16
+)
+
+// WatermarkDetection is the result of majority voting over every copy of
+// the codeword. BitConfidence[i] is the vote margin of codeword bit i, from
+// 0 (tie) to 1 (unanimous), and Confidence is their mean.
+type WatermarkDetection struct {
+	Detected      bool      `json:"detected"`
+	TagValid      bool      `json:"tag_valid"`
+	ID            uint64    `json:"-"`
+	IDHex         string    `json:"id"`
+	Confidence    float64   `json:"confidence"`
+	Frames        int       `json:"frames"`
+	Votes         int       `json:"votes"`
+	MinVotes      int       `json:"min_votes_per_bit"`
+	BitErrorRate  float64   `json:"bit_error_rate"`
+	BitConfidence []float64 `json:"bit_confidence"`
+}
+
+func FormatWatermarkID(id uint64) string {
+	return fmt.Sprintf("%016x", id)
+}
+
+// watermarkKeystream returns one whitening bit per unit of span, seeded by
+// the frame's nonce: the last side-information bytes and the upper seven bits
+// of the first watermarkNonceUnits units.
+func watermarkKeystream(key string, carrier []byte, span ByteRange) []byte {
+	seed := sha256.New()
+	seed.Write(carrier[span.Start-watermarkNonceSideInfo : span.Start])
+	for _, b := range carrier[span.Start:min(span.End, span.Start+watermarkNonceUnits)] {
+		seed.Write([]byte{b >> 1})
+	}
+	nonce := seed.Sum(nil)[:watermarkNonceSize]
+
+	length := span.End - span.Start
+	stream := crypto.KeystreamXOR(make([]byte, (length+7)/8), key, "stego-watermark", nonce)
+	bits := make([]byte, length)
+	for u := range bits {
+		bits[u] = (stream[u/8] >> (7 - u%8)) & 1
+	}
+	return bits
+}
+
+func watermarkTag(id uint64, key string) uint32 {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write(binary.BigEndian.AppendUint64(nil, id))
+	return binary.BigEndian.Uint32(mac.Sum(nil))
+}
+
+func watermarkCodeword(id uint64, key string) []byte {
+	code := binary.BigEndian.AppendUint64(nil, id)
+	code = binary.BigEndian.AppendUint32(code, watermarkTag(id, key))
+
+	bits := make([]byte, watermarkCodeBits)
+	for i := range bits {
+		bits[i] = (code[i/8] >> (7 - i%8)) & 1
+	}
+	return bits
+}
+
+// EmbedWatermark writes id into every frame of carrier. It overwrites the
+// LSB plane, so it cannot be combined with a hidden file in the same carrier.
+func EmbedWatermark(carrier []byte, id uint64, key string) ([]byte, error) {
+	if key == "" {
+		return nil, ErrKeyRequired
+	}
+
+	spans, units, err := mainDataSpans(carrier)
+	if err != nil {
+		return nil, err
+	}
+	if units < watermarkCodeBits {
+		return nil, ErrInsufficientCapacity
+	}
+
+	code := watermarkCodeword(id, key)
+	result := make([]byte, len(carrier))
+	copy(result, carrier)
+	for _, span := range spans {
+		whitening := watermarkKeystream(key, carrier, span)
+		for u := 0; u < span.End-span.Start; u++ {
+			i := span.Start + u
+			result[i] = result[i]&^1 | (code[u%watermarkCodeBits] ^ whitening[u])
+		}
+	}
+
+	return result, nil
+}
+
+// DetectWatermark recovers the identifier from whatever frames remain in
+// carrier. Without a watermark for this key the tag does not match, and the
+// identifier it reports is meaningless.
+func DetectWatermark(carrier []byte, key string) (*WatermarkDetection, error) {
+	if key == "" {
+		return nil, ErrKeyRequired
+	}
+
+	spans, _, err := mainDataSpans(carrier)
+	if err != nil {
+		return nil, err
+	}
+
+	var ones, votes [watermarkCodeBits]int
+	for _, span := range spans {
+		whitening := watermarkKeystream(key, carrier, span)
+		for u := 0; u < span.End-span.Start; u++ {
+			bit := carrier[span.Start+u]&1 ^ whitening[u]
+			ones[u%watermarkCodeBits] += int(bit)
+			votes[u%watermarkCodeBits]++
+		}
+	}
+
+	detection := &WatermarkDetection{
+		Frames:        len(spans),
+		MinVotes:      math.MaxInt,
+		BitConfidence: make([]float64, watermarkCodeBits),
+	}
+	var code uint64
+	var tag uint32
+	minority := 0
+	for i := 0; i < watermarkCodeBits; i++ {
+		zeros := votes[i] - ones[i]
+		if ones[i] > zeros {
+			if i < WatermarkBits {
+				code |= 1 << (WatermarkBits - 1 - i)
+			} else {
+				tag |= 1 << (watermarkCodeBits - 1 - i)
+			}
+		}
+		minority += min(ones[i], zeros)
+		detection.Votes += votes[i]
+		detection.MinVotes = min(detection.MinVotes, votes[i])
+		if votes[i] > 0 {
+			margin := float64(ones[i]-zeros) / float64(votes[i])
+			detection.BitConfidence[i] = math.Abs(margin)
+			detection.Confidence += detection.BitConfidence[i]
+		}
+	}
+
+	detection.Confidence /= watermarkCodeBits
+	detection.ID = code
+	detection.TagValid = tag == watermarkTag(code, key)
+	detection.IDHex = FormatWatermarkID(detection.ID)
+	if detection.Votes > 0 {
+		detection.BitErrorRate = float64(minority) / float64(detection.Votes)
+	}
+	detection.Detected = detection.TagValid && detection.MinVotes >= minWatermarkVotes &&
+		detection.Confidence >= WatermarkThreshold
+
+	return detection, nil
+}
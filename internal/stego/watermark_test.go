@@ -0,0 +1,175 @@
+package stego
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+func TestWatermarkRoundTrip(t *testing.T) {
+	carrier := testMP3(80, 30)
+	tests := []struct {
+		name string
+		id   uint64
+		key  string
+	}{
+		{"zero", 0, "k"},
+		{"one", 1, "k"},
+		{"all ones", ^uint64(0), "another key"},
+		{"arbitrary", 0x0123456789abcdef, "k"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			marked, err := EmbedWatermark(carrier, tt.id, tt.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if comparison := CompareFrames(carrier, marked); !comparison.Match {
+				t.Fatalf("watermarking changed the %s of frame %d", comparison.MismatchField, comparison.FirstMismatch)
+			}
+
+			detection, err := DetectWatermark(marked, tt.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !detection.Detected || detection.ID != tt.id || detection.IDHex != FormatWatermarkID(tt.id) {
+				t.Errorf("detected %v with ID %s, want %s", detection.Detected, detection.IDHex, FormatWatermarkID(tt.id))
+			}
+			if detection.Confidence != 1 || detection.BitErrorRate != 0 || detection.Frames != 80 {
+				t.Errorf("confidence %v, bit error rate %v over %d frames", detection.Confidence, detection.BitErrorRate, detection.Frames)
+			}
+
+			for _, key := range []string{"wrong", tt.key + " "} {
+				detection, err := DetectWatermark(marked, key)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if detection.Detected || detection.TagValid {
+					t.Errorf("key %q: detected %s", key, detection.IDHex)
+				}
+			}
+		})
+	}
+}
+
+func TestWatermarkRobustness(t *testing.T) {
+	const id = 0xfeedface
+	carrier := testMP3(80, 31)
+	marked, err := EmbedWatermark(carrier, id, "k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, offsets, err := ScanMP3Frames(marked)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tag := marked[:offsets[0]]
+	flipped := func(rate float64) []byte {
+		rng := rand.New(rand.NewSource(32))
+		data := append([]byte(nil), marked...)
+		for i := offsets[0]; i < len(data); i++ {
+			if rng.Float64() < rate {
+				data[i] ^= 1
+			}
+		}
+		return data
+	}
+
+	tests := []struct {
+		name     string
+		carrier  []byte
+		detected bool
+	}{
+		{"trim start", append(append([]byte(nil), tag...), marked[offsets[30]:]...), true},
+		{"trim to five frames", append(append([]byte(nil), tag...), marked[offsets[40]:offsets[45]]...), true},
+		{"unmarked audio appended", append(append([]byte(nil), marked...), carrier[offsets[0]:]...), true},
+		{"tag removed", marked[offsets[0]:], true},
+		{"bit errors", flipped(0.01), true},
+		{"single frame", append(append([]byte(nil), tag...), marked[offsets[40]:offsets[41]]...), false},
+		{"lsb plane randomised", flipped(0.5), false},
+		{"cover", carrier, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detection, err := DetectWatermark(tt.carrier, "k")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if detection.Detected != tt.detected || (tt.detected && detection.ID != id) {
+				t.Errorf("detected %v with ID %s (confidence %.3f), want %v",
+					detection.Detected, detection.IDHex, detection.Confidence, tt.detected)
+			}
+		})
+	}
+}
+
+// TestWatermarkFrameNonce checks that equal-sized frames are whitened with
+// unrelated keystreams, so their LSBs differ in about half the units instead
+// of cancelling out to the codeword pattern.
+func TestWatermarkFrameNonce(t *testing.T) {
+	carrier := testMP3(30, 33)
+	marked, err := EmbedWatermark(carrier, 42, "k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	spans, _, err := mainDataSpans(marked)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i < len(spans); i++ {
+		a, b := spans[i-1], spans[i]
+		if a.Length() != b.Length() {
+			continue
+		}
+		differ := 0
+		for u := 0; u < a.Length(); u++ {
+			differ += int((marked[a.Start+u] ^ marked[b.Start+u]) & 1)
+		}
+		if fraction := float64(differ) / float64(a.Length()); fraction < 0.4 || fraction > 0.6 {
+			t.Errorf("frames %d and %d differ in %.2f of their LSBs", i-1, i, fraction)
+		}
+	}
+}
+
+// TestWatermarkFrameLayout checks that the watermark leaves frame headers,
+// CRCs and side information alone and skips CRC-protected frames.
+func TestWatermarkFrameLayout(t *testing.T) {
+	carrier := protectEveryOtherFrame(t, testMP3(60, 36))
+	marked, err := EmbedWatermark(carrier, 7, "k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMainDataOnly(t, carrier, marked)
+
+	detection, err := DetectWatermark(marked, "k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !detection.Detected || detection.ID != 7 || detection.Frames != 30 {
+		t.Errorf("detected %v with ID %s over %d frames, want 7 over 30", detection.Detected, detection.IDHex, detection.Frames)
+	}
+}
+
+func TestWatermarkErrors(t *testing.T) {
+	carrier := testMP3(10, 34)
+	tests := []struct {
+		name    string
+		carrier []byte
+		key     string
+		want    error
+	}{
+		{"no key", carrier, "", ErrKeyRequired},
+		{"not mp3", testMessage(4000, 35), "k", ErrNoValidFrames},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := EmbedWatermark(tt.carrier, 1, tt.key); !errors.Is(err, tt.want) {
+				t.Errorf("embed: err = %v, want %v", err, tt.want)
+			}
+			if _, err := DetectWatermark(tt.carrier, tt.key); !errors.Is(err, tt.want) {
+				t.Errorf("detect: err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
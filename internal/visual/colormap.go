@@ -0,0 +1,44 @@
+package visual
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// Perceptually ordered dark-to-bright stops (black, purple, red, orange,
+// pale yellow), similar to the common "inferno" map.
+var colorStops = []color.RGBA{
+	{0, 0, 4, 255},
+	{87, 16, 110, 255},
+	{188, 55, 84, 255},
+	{249, 142, 9, 255},
+	{252, 255, 164, 255},
+}
+
+func colormap(t float64) color.RGBA {
+	if t != t || t <= 0 {
+		return colorStops[0]
+	}
+	if t >= 1 {
+		return colorStops[len(colorStops)-1]
+	}
+
+	pos := t * float64(len(colorStops)-1)
+	i := int(pos)
+	frac := pos - float64(i)
+	a, b := colorStops[i], colorStops[i+1]
+	mix := func(x, y uint8) uint8 {
+		return uint8(float64(x) + (float64(y)-float64(x))*frac + 0.5)
+	}
+	return color.RGBA{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B), 255}
+}
+
+func EncodePNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
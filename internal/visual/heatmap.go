@@ -0,0 +1,81 @@
+package visual
+
+import (
+	"image"
+	"image/color"
+	"math/bits"
+)
+
+const (
+	DefaultHeatmapColumns = 64
+	DefaultHeatmapCell    = 8
+
+	// A range is drawn at full intensity once a quarter of its bits changed,
+	// which is what 4-bit LSB replacement does to random data. Keeping the
+	// scale absolute makes heatmaps of different settings comparable.
+	heatmapSaturation = 0.25
+)
+
+var heatmapUnchanged = color.RGBA{24, 24, 32, 255}
+
+// FrameChange is the number of bits that differ inside one carrier range,
+// typically an MP3 frame.
+type FrameChange struct {
+	Start       int     `json:"start"`
+	End         int     `json:"end"`
+	BitsFlipped int     `json:"bits_flipped"`
+	Fraction    float64 `json:"fraction"`
+}
+
+func MeasureChanges(cover, stego []byte, ranges [][2]int) []FrameChange {
+	changes := make([]FrameChange, len(ranges))
+	for i, r := range ranges {
+		start, end := r[0], min(r[1], len(cover))
+		change := FrameChange{Start: r[0], End: r[1]}
+		for j := start; j < end; j++ {
+			if j < len(stego) {
+				change.BitsFlipped += bits.OnesCount8(cover[j] ^ stego[j])
+			} else {
+				change.BitsFlipped += 8
+			}
+		}
+		if end > start {
+			change.Fraction = float64(change.BitsFlipped) / float64(8*(end-start))
+		}
+		changes[i] = change
+	}
+	return changes
+}
+
+// HeatmapImage lays ranges out row by row, columns cells per row, each cell
+// cell pixels square. Untouched ranges keep a neutral dark colour so they
+// stand apart from lightly modified ones.
+func HeatmapImage(changes []FrameChange, columns, cell int) *image.RGBA {
+	if columns <= 0 {
+		columns = DefaultHeatmapColumns
+	}
+	if cell <= 0 {
+		cell = DefaultHeatmapCell
+	}
+	rows := max((len(changes)+columns-1)/columns, 1)
+	img := image.NewRGBA(image.Rect(0, 0, columns*cell, rows*cell))
+
+	for i := 0; i < rows*columns; i++ {
+		c := color.RGBA{0, 0, 0, 255}
+		if i < len(changes) {
+			c = heatmapUnchanged
+			if changes[i].BitsFlipped > 0 {
+				c = colormap(0.15 + 0.85*changes[i].Fraction/heatmapSaturation)
+			}
+		}
+
+		x0, y0 := (i%columns)*cell, (i/columns)*cell
+		for y := y0; y < y0+cell; y++ {
+			for x := x0; x < x0+cell; x++ {
+				img.SetRGBA(x, y, c)
+			}
+		}
+	}
+
+	return img
+}
@@ -0,0 +1,89 @@
+package visual
+
+import (
+	"image"
+	"math"
+
+	"github.com/AlbertGhazaly/Steganography-on-Audio-Files-with-Multiple-LSB-Method/internal/audio"
+)
+
+const (
+	DefaultFrameSize = 1024
+	DefaultMinDB     = -120.0
+	DefaultMaxDB     = 0.0
+)
+
+// Spectrogram holds one power spectrum per column in dB relative to a
+// full-scale sine, so images of different files share the same scale.
+type Spectrogram struct {
+	SampleRate int
+	FrameSize  int
+	Hop        int
+	Columns    [][]float64
+}
+
+// NewSpectrogram computes at most columns Hann-windowed spectra spread evenly
+// over samples. frameSize must be a power of two.
+func NewSpectrogram(samples []float64, sampleRate, frameSize, columns int) *Spectrogram {
+	if frameSize <= 0 {
+		frameSize = DefaultFrameSize
+	}
+	if columns <= 0 {
+		columns = 1
+	}
+
+	hop := len(samples) / columns
+	if hop < 1 {
+		hop = 1
+	}
+
+	window := audio.HannWindow(frameSize)
+	reference := float64(frameSize) * float64(frameSize) / 16
+	frame := make([]float64, frameSize)
+
+	spec := &Spectrogram{SampleRate: sampleRate, FrameSize: frameSize, Hop: hop}
+	for start := 0; start < len(samples) && len(spec.Columns) < columns; start += hop {
+		for i := range frame {
+			frame[i] = 0
+			if start+i < len(samples) {
+				frame[i] = samples[start+i]
+			}
+		}
+
+		power := audio.PowerSpectrum(frame, window)
+		column := make([]float64, len(power))
+		for k, p := range power {
+			column[k] = 10 * math.Log10(p/reference+1e-30)
+		}
+		spec.Columns = append(spec.Columns, column)
+	}
+
+	return spec
+}
+
+// Image renders the spectrogram with time left to right and frequency
+// increasing upwards. Each pixel shows the loudest bin it covers.
+func (s *Spectrogram) Image(width, height int, minDB, maxDB float64) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	if len(s.Columns) == 0 {
+		return img
+	}
+
+	bins := len(s.Columns[0])
+	for x := 0; x < width; x++ {
+		column := s.Columns[x*len(s.Columns)/width]
+		for y := 0; y < height; y++ {
+			row := height - 1 - y
+			lo := row * bins / height
+			hi := max((row+1)*bins/height, lo+1)
+
+			level := math.Inf(-1)
+			for k := lo; k < hi && k < bins; k++ {
+				level = math.Max(level, column[k])
+			}
+			img.SetRGBA(x, y, colormap((level-minDB)/(maxDB-minDB)))
+		}
+	}
+
+	return img
+}
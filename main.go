@@ -16,8 +16,18 @@ func main() {
 	http.HandleFunc("/api/health", middleware.CorsMiddleware(handlers.HealthCheck))
 	http.HandleFunc("/api/embed", middleware.CorsMiddleware(handlers.EmbedHandler))
 	http.HandleFunc("/api/extract", middleware.CorsMiddleware(handlers.ExtractHandler))
+	http.HandleFunc("/api/probe", middleware.CorsMiddleware(handlers.ProbeHandler))
 	http.HandleFunc("/api/capacity", middleware.CorsMiddleware(handlers.CapacityHandler))
+	http.HandleFunc("/api/plan", middleware.CorsMiddleware(handlers.PlanHandler))
 	http.HandleFunc("/api/psnr", middleware.CorsMiddleware(handlers.PSNRHandler))
+	http.HandleFunc("/api/compare", middleware.CorsMiddleware(handlers.CompareHandler))
+	http.HandleFunc("/api/analyze", middleware.CorsMiddleware(handlers.AnalyzeHandler))
+	http.HandleFunc("/api/analyze/chisquare", middleware.CorsMiddleware(handlers.ChiSquareHandler))
+	http.HandleFunc("/api/analyze/rs-spa", middleware.CorsMiddleware(handlers.PCMAnalysisHandler))
+	http.HandleFunc("/api/analyze/headers", middleware.CorsMiddleware(handlers.HeaderAnomalyHandler))
+	http.HandleFunc("/api/spectrogram", middleware.CorsMiddleware(handlers.SpectrogramHandler))
+	http.HandleFunc("/api/watermark/embed", middleware.CorsMiddleware(handlers.WatermarkEmbedHandler))
+	http.HandleFunc("/api/watermark/detect", middleware.CorsMiddleware(handlers.WatermarkDetectHandler))
 
 	fs := http.FileServer(http.Dir("./static/"))
 	http.Handle("/", fs)
@@ -27,8 +37,18 @@ func main() {
 	fmt.Println("  GET    /api/health")
 	fmt.Println("  POST   /api/embed    - Embed secret file into MP3")
 	fmt.Println("  POST   /api/extract  - Extract secret file from MP3")
+	fmt.Println("  POST   /api/probe    - Detect embedded configuration without extracting")
 	fmt.Println("  POST   /api/capacity - Calculate MP3 embedding capacity")
+	fmt.Println("  POST   /api/plan     - Recommend the least destructive embedding settings")
 	fmt.Println("  POST   /api/psnr     - Calculate PSNR between original and modified MP3")
+	fmt.Println("  POST   /api/compare  - Benchmark every method and bit depth on one carrier")
+	fmt.Println("  POST   /api/analyze  - Analyze MP3 tags, frames, bitrate and structure")
+	fmt.Println("  POST   /api/analyze/chisquare - Chi-square steganalysis over sliding windows")
+	fmt.Println("  POST   /api/analyze/rs-spa    - RS and sample pair analysis for WAV carriers")
+	fmt.Println("  POST   /api/analyze/headers   - Detect varying MP3 frame-header flags")
+	fmt.Println("  POST   /api/spectrogram       - Render cover/stego/difference spectrograms and change heatmap")
+	fmt.Println("  POST   /api/watermark/embed   - Embed a keyed 64-bit recipient ID into every frame")
+	fmt.Println("  POST   /api/watermark/detect  - Recover a watermark ID with majority-vote confidence")
 	fmt.Println("Frontend available at: http://localhost:8080")
 
 	log.Fatal(http.ListenAndServe(":8080", nil))
@@ -46,6 +46,7 @@ export class ApiService {
             }
 
             const metadata = {
+                method: response.headers.get('X-Method'),
                 originalFilename: response.headers.get('X-Original-Filename'),
                 fileType: response.headers.get('X-File-Type'),
                 secretSize: response.headers.get('X-Secret-Size'),
@@ -20,7 +20,7 @@ class SteganographyApp {
         this.setupFormHandlers();
 
         this.handleMethodChange('embed', 'lsb');
-        this.handleMethodChange('extract', 'lsb');
+        this.handleMethodChange('extract', 'auto');
     }
 
     async checkConnection() {
@@ -196,7 +196,7 @@ class SteganographyApp {
 
         const formData = new FormData(form);
         
-        if (method === 'lsb') {
+        if (method !== 'header') {
             formData.delete('use_encryption');
             formData.delete('use_key_for_position');
             formData.delete('lsb_bits');
@@ -291,7 +291,7 @@ class SteganographyApp {
         const keyInput = document.getElementById(`${section}-key`);
         const keyRequiredIndicator = document.getElementById(`${section}-key-required-indicator`);
         
-        if (method === 'lsb') {
+        if (method === 'lsb' || method === 'auto') {
             if (section === 'embed') {
                 lsbSection?.classList.remove('hidden');
                 lsbOptions?.classList.remove('hidden');
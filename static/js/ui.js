@@ -152,6 +152,7 @@ export class UIManager {
                 <div class="mt-4 p-4 bg-blue-50 border border-blue-200 rounded-lg">
                     <h4 class="font-semibold text-blue-800 mb-2">Embedded Metadata:</h4>
                     <div class="grid grid-cols-2 gap-2 text-sm">
+                        <div><span class="font-medium">Method:</span> ${metadata.method || 'N/A'}</div>
                         <div><span class="font-medium">Original Filename:</span> ${metadata.originalFilename}</div>
                         <div><span class="font-medium">File Type:</span> ${metadata.fileType || 'Auto-detected'}</div>
                         <div><span class="font-medium">Secret Size:</span> ${metadata.secretSize ? (metadata.secretSize / 1024).toFixed(2) + ' KB' : 'Unknown'}</div>